@@ -0,0 +1,180 @@
+package automotiveSim
+
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	gasConstant = 8.314 //J/(mol K)
+	agingReferenceTemperature = 298.15 //kelvin
+	daysPerYear = 365
+	secondsPerYear = daysPerYear * 24 * 3600
+)
+
+//Aging is an empirical calendar plus cycle aging model for the pack. Calendar fade grows
+//with the square root of time, cycle fade with charge throughput, and both speed up with
+//temperature following the Arrhenius equation
+type Aging struct {
+	CalendarFade float64 //fraction of capacity lost after one year parked at 25C
+	CycleFade float64 //fraction of capacity lost per full equivalent cycle at 100% depth of discharge and 25C
+	DoDExponent float64 //optional, wear per cycle grows as depth of discharge to this power. Defaults to 1.5
+	ActivationEnergy float64 //optional, J/mol. Defaults to 30000
+	ResistanceGrowth float64 //optional, fractional resistance rise per fraction of capacity lost. Defaults to 2
+}
+
+func (a *Aging)Init() error {
+	if a.CalendarFade < 0 || a.CalendarFade >= 1 {
+		return fmt.Errorf("Calendar fade must be on the range [0,1)")
+	}
+	if a.CycleFade < 0 || a.CycleFade >= 1 {
+		return fmt.Errorf("Cycle fade must be on the range [0,1)")
+	}
+	if a.DoDExponent < 0 || a.ActivationEnergy < 0 || a.ResistanceGrowth < 0 {
+		return fmt.Errorf("Aging parameters must not be negative")
+	}
+	if a.DoDExponent == 0 {
+		a.DoDExponent = 1.5
+	}
+	if a.ActivationEnergy == 0 {
+		a.ActivationEnergy = 30000
+	}
+	if a.ResistanceGrowth == 0 {
+		a.ResistanceGrowth = 2
+	}
+	return nil
+}
+
+//acceleration is how much faster the pack ages at temperature than at 25C
+func (a *Aging)acceleration(temperature float64) float64 {
+	return math.Exp(a.ActivationEnergy/gasConstant * (1/agingReferenceTemperature - 1/temperature))
+}
+
+//calendar is the calendar fade after parking for seconds at temperature
+func (a *Aging)calendar(seconds, temperature float64) float64 {
+	return a.CalendarFade * a.acceleration(temperature) * math.Sqrt(seconds/secondsPerYear)
+}
+
+//cycle is the fade from cycles full equivalent cycles of depth dod at temperature
+func (a *Aging)cycle(cycles, dod, temperature float64) float64 {
+	if dod <= 0 {
+		return 0
+	}
+	return a.CycleFade * a.acceleration(temperature) * cycles * math.Pow(dod, a.DoDExponent - 1)
+}
+
+//AgingYear is the state of the pack at the end of a simulated year
+type AgingYear struct {
+	Year int
+	CapacityFade float64 //fraction of the original capacity lost
+	CalendarFade float64 //the part of CapacityFade from time
+	CycleFade float64 //the part of CapacityFade from use
+	ResistanceGrowth float64 //fraction the internal resistance has risen
+	Cycles float64 //full equivalent cycles during the year
+	Range float64 //km on the commute schedule from a full charge
+}
+
+//commuteDay is what a day of commuting does to the pack
+type commuteDay struct {
+	cycles float64 //full equivalent cycles
+	dod float64
+	temperature float64 //kelvin, of the pack at the end of the day
+	consumption float64 //J/m
+}
+
+//aged returns a copy of the vehicle with the pack faded by fade
+func (vehicle *Vehicle)aged(fade float64) (*Vehicle, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil, err
+	}
+	b := &v.Battery
+	//faded cells no longer match their datasheet
+	b.Cell = nil
+	b.Coulomb *= 1 - fade
+	b.Resistance *= 1 + b.Aging.ResistanceGrowth * fade
+	b.InitialSOC = b.MaxSOC
+	err = b.Init()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//commute drives trips repetitions of the schedule from a full charge
+func (v *Vehicle)commute(schedule *Schedule, trips int) (commuteDay, error) {
+	sim, err := initSimulation(v)
+	if err != nil {
+		return commuteDay{}, err
+	}
+	b := &v.Battery
+	for i := 0; i < trips; i++ {
+		_, err := sim.Run(schedule)
+		if errors.Is(err, errDepleted) {
+			return commuteDay{}, fmt.Errorf("%s: %d trips need more than one charge", schedule.Name, trips)
+		}
+		if err != nil {
+			return commuteDay{}, fmt.Errorf("%s: %v", schedule.Name, err)
+		}
+		sim.Time = 0
+	}
+	if sim.Distance <= 0 {
+		return commuteDay{}, fmt.Errorf("%s: schedule does not cover any distance", schedule.Name)
+	}
+	day := commuteDay{
+		//everything discharged is charged again, so a full equivalent cycle is one
+		//pack's worth of discharge
+		cycles: (b.EnergyUsed() + b.EnergyRecovered()) / b.Energy(),
+		dod: b.MaxSOC - b.StateOfCharge(),
+		temperature: v.Ambient.Temperature,
+		consumption: b.EnergyUsed() / sim.Distance,
+	}
+	if b.Thermal != nil && b.Thermal.Temperature() != 0 {
+		day.temperature = b.Thermal.Temperature()
+	}
+	return day, nil
+}
+
+//Degradation ages the pack over years of driving the schedule trips times a day and
+//charging back to MaxSOC each night, parked at ambient temperature the rest of the time.
+//The first entry is the new pack; each day's wear is taken from a simulated day on the
+//pack as it was at the start of that year
+func (vehicle *Vehicle)Degradation(schedule *Schedule, trips, years int) ([]AgingYear, error) {
+	aging := vehicle.Battery.Aging
+	if aging == nil {
+		return nil, fmt.Errorf("Battery has no aging model")
+	}
+	if trips <= 0 || years <= 0 {
+		return nil, fmt.Errorf("Trips per day and years must be positive")
+	}
+	ambient := vehicle.Ambient.Temperature
+
+	result := []AgingYear{{}}
+	for year := 0; ; year++ {
+		v, err := vehicle.aged(result[year].CapacityFade)
+		if err != nil {
+			return result, err
+		}
+		day, err := v.commute(schedule, trips)
+		if err != nil {
+			return result, fmt.Errorf("Year %d: %v", year, err)
+		}
+		result[year].Range = v.Battery.UsableEnergy() / day.consumption / 1000
+		if year == years {
+			return result, nil
+		}
+
+		//the next year's wear, at this year's daily usage
+		next := AgingYear{Year: year + 1, Cycles: day.cycles * daysPerYear}
+		next.CalendarFade = aging.calendar(float64(year + 1) * secondsPerYear, ambient)
+		next.CycleFade = result[year].CycleFade + aging.cycle(next.Cycles, day.dod, day.temperature)
+		next.CapacityFade = next.CalendarFade + next.CycleFade
+		next.ResistanceGrowth = aging.ResistanceGrowth * next.CapacityFade
+		if next.CapacityFade >= 1 {
+			return result, fmt.Errorf("Pack has no capacity left in year %d", next.Year)
+		}
+		result = append(result, next)
+	}
+}
@@ -3,19 +3,61 @@ package automotiveSim
 
 import (
 	"fmt"
+	"math"
+)
+
+const (
+	seaLevelPressure = 101325 //Pa
+	maxAltitude = 11000 //m, top of the troposphere where the barometric formula holds
+)
+
+//typical Ambient.RoadGrip for road surfaces
+const (
+	SurfaceDry = 1.0
+	SurfaceWet = 0.7
+	SurfaceSnow = 0.3
+	SurfaceIce = 0.1
 )
 
 type Ambient struct {
 	Temperature float64
-	Pressure float64
+	Pressure float64 //Pa, optional. Derived from Altitude when zero
+	Altitude float64 //meters above sea level
+	Solar float64 //W/m^2, optional. Sunlight falling on the vehicle
+	RoadGrip float64 //optional, the fraction of tire grip the road allows. Defaults to SurfaceDry
 }
 
 func (a *Ambient)Init() error {
 	if a.Temperature <= 0 {
 		return fmt.Errorf("Temperature must be above absolute zero")
 	}
+	if a.Solar < 0 {
+		return fmt.Errorf("Solar irradiance can not be negative")
+	}
 	if a.Pressure < 0 {
 		return fmt.Errorf("Pressure can not be negative")
 	}
+	if a.Altitude < -500 || a.Altitude > maxAltitude {
+		return fmt.Errorf("Altitude must be on the range [-500,%d]m", maxAltitude)
+	}
+	if a.Pressure == 0 {
+		a.Pressure = pressureAtAltitude(a.Altitude)
+	}
+	if a.RoadGrip < 0 {
+		return fmt.Errorf("Road grip can not be negative")
+	}
+	if a.RoadGrip == 0 {
+		a.RoadGrip = SurfaceDry
+	}
 	return nil
 }
+
+//AirDensity in kg/m^3 for the ambient temperature and pressure
+func (a *Ambient)AirDensity() float64 {
+	return airDensity(a.Temperature, a.Pressure)
+}
+
+//pressureAtAltitude is the standard atmosphere pressure (Pa) at altitude meters
+func pressureAtAltitude(altitude float64) float64 {
+	return seaLevelPressure * math.Pow(1 - 2.25577e-5 * altitude, 5.25588)
+}
@@ -0,0 +1,142 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+//BatchVehicle is one vehicle of a batch, named for the results
+type BatchVehicle struct {
+	Name string
+	Vehicle *Vehicle
+}
+
+//Batch runs every schedule on every vehicle. Each run gets its own clone of the vehicle,
+//which is dropped once its result is in, so memory is bounded by Workers rather than by
+//the size of the batch
+type Batch struct {
+	Vehicles []BatchVehicle
+	Schedules []*Schedule
+	Workers int //defaults to one per CPU
+	Options SimulationOptions //Driver must be left nil, every run gets its own default one
+}
+
+//BatchResult is one vehicle on one schedule. Err is set instead of Result when the run failed
+type BatchResult struct {
+	Vehicle string
+	Schedule string
+	Result ScheduleResult
+	Err error
+}
+
+//BatchTable holds every run of a batch, schedules varying fastest
+type BatchTable struct {
+	Rows []BatchResult
+}
+
+func (b *Batch)Init() error {
+	if len(b.Vehicles) == 0 || len(b.Schedules) == 0 {
+		return fmt.Errorf("Batch requires at least one vehicle and one schedule")
+	}
+	for _,v := range b.Vehicles {
+		if v.Vehicle == nil {
+			return fmt.Errorf("%s: no vehicle", v.Name)
+		}
+	}
+	for i,s := range b.Schedules {
+		if s == nil {
+			return fmt.Errorf("Schedule %d is missing", i)
+		}
+	}
+	if b.Workers < 0 {
+		return fmt.Errorf("Workers must not be negative")
+	}
+	if b.Options.Driver != nil {
+		return fmt.Errorf("Batch runs can not share a Driver")
+	}
+	opts := b.Options
+	return opts.Init()
+}
+
+func (b *Batch)Run() (BatchTable, error) {
+	return b.RunContext(context.Background())
+}
+
+//RunContext is Run, giving up once ctx is done. Runs already going stop with ctx's error,
+//as do the ones not started, which is also returned. A failed run only fails its own row
+func (b *Batch)RunContext(ctx context.Context) (BatchTable, error) {
+	err := b.Init()
+	if err != nil {
+		return BatchTable{}, err
+	}
+	table := BatchTable{Rows: make([]BatchResult, len(b.Vehicles) * len(b.Schedules))}
+	workerPool(b.Workers, len(table.Rows), func(i int) {
+		v, s := b.Vehicles[i / len(b.Schedules)], b.Schedules[i % len(b.Schedules)]
+		row := &table.Rows[i]
+		row.Vehicle, row.Schedule = v.Name, s.Name
+		if ctx.Err() != nil {
+			row.Err = ctx.Err()
+			return
+		}
+		row.Result, row.Err = b.run(ctx, v.Vehicle, s)
+	})
+	return table, ctx.Err()
+}
+
+func (b *Batch)run(ctx context.Context, vehicle *Vehicle, schedule *Schedule) (ScheduleResult, error) {
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return ScheduleResult{}, err
+	}
+	sim.Options = b.Options
+	err = sim.Options.Init()
+	if err != nil {
+		return ScheduleResult{}, err
+	}
+	return sim.RunContext(ctx, schedule)
+}
+
+//Failed is every row whose run failed
+func (t BatchTable)Failed() []BatchResult {
+	var failed []BatchResult
+	for _,r := range t.Rows {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+//WriteCSV writes one row per run with a header, in SI units. Failed runs leave the
+//results empty and give the error
+func (t BatchTable)WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{"Vehicle", "Schedule", "Duration (s)", "Distance (m)", "Energy (J)",
+		"Recovered (J)", "Friction (J)", "Fuel (L)", "Start SOC", "End SOC", "Error"})
+	if err != nil {
+		return err
+	}
+	format := func(x float64) string {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	for _,row := range t.Rows {
+		record := []string{row.Vehicle, row.Schedule, "", "", "", "", "", "", "", "", ""}
+		if row.Err != nil {
+			record[10] = row.Err.Error()
+		} else {
+			r := row.Result
+			copy(record[2:10], []string{format(r.Duration.Seconds()), format(r.Distance), format(r.Energy),
+				format(r.RecoveredEnergy), format(r.FrictionEnergy), format(r.Fuel), format(r.StartSOC), format(r.EndSOC)})
+		}
+		err = out.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -2,24 +2,67 @@ package automotiveSim
 
 
 import (
-	"time"
 	"math"
 	"fmt"
 )
 
+//errDepleted is returned once the pack reaches MinSOC
+var errDepleted = LimitReason{Kind: LimitBatteryDepleted}
+
 type Battery struct {
-	Component
     NominalVoltage float64
     Resistance float64
     Coulomb float64
 	MaxCurrent float64
+	ContinuousCurrent float64 //A, optional. What MaxCurrent derates to when hot, defaults to MaxCurrent
+	MaxChargeCurrent float64 //A, optional. Limits regen into the pack, defaults to MaxCurrent
 	ChargerEfficency float64
+	SpecificEnergy float64 //Wh/kg, optional. Used to account for pack mass when resizing
+	HeaterPower float64 //W, optional. Pack heater used for preconditioning, warming Thermal
+	Thermal *Thermal //optional, derates MaxCurrent toward ContinuousCurrent as the pack heats up
+	Aging *Aging //optional, capacity fade and resistance growth for Degradation
+	
+	//optional, builds the pack from Series x Parallel of Cell. NominalVoltage, Resistance,
+	//Coulomb, the currents, SpecificEnergy and OpenCircuitVoltage then come from the cells
+	Cell *Cell
+	Series int
+	Parallel int
+	
+	//optional, open circuit voltage against state of charge (0-1). Defaults to NominalVoltage
+	OpenCircuitVoltage Curve
+	
+	//optional, the most power (W) the pack accepts from a charger against state of charge (X)
+	//and pack temperature in kelvin (Y). Unlimited beyond MaxChargeCurrent when empty
+	ChargeAcceptance Map
+	
+	//optional, cold weather. The fraction of the usable window that can be drawn (X pack
+	//temperature in kelvin) and the multiple of Resistance (X kelvin). The pack is at
+	//ambient without a Thermal model
+	CapacityTemperature Curve
+	ResistanceTemperature Curve
+	
+	//usable state of charge window, defaults to [0,1]
+	MinSOC float64
+	MaxSOC float64
+	InitialSOC float64 //defaults to MaxSOC
 	
 	//state
 	coulombsUsed float64
+	energyUsed float64
+	energyRecovered float64
+	loss float64 //internal resistance loss on the last tick, W
+	resistanceFactor float64 //ResistanceTemperature at the pack's temperature, zero until known
+	fromCells bool //the pack-level fields were derived from Cell
 }
 
 func (b *Battery)Init() error {
+	if b.Cell != nil {
+		err := b.initFromCells()
+		if err != nil {
+			return err
+		}
+	}
+	
 	if b.Coulomb <= 0 {
 		return fmt.Errorf("Battery must store a positive amount of charge")
 	}
@@ -36,22 +79,164 @@ func (b *Battery)Init() error {
 		return fmt.Errorf("Battery must have positive maximum current")
 	}
 	
+	if b.ContinuousCurrent < 0 || b.ContinuousCurrent > b.MaxCurrent {
+		return fmt.Errorf("Battery continuous current must be on the range [0,MaxCurrent]")
+	}
+	if b.ContinuousCurrent == 0 {
+		b.ContinuousCurrent = b.MaxCurrent
+	}
+	
+	if b.MaxChargeCurrent < 0 {
+		return fmt.Errorf("Battery max charge current can not be negative")
+	}
+	if b.MaxChargeCurrent == 0 {
+		b.MaxChargeCurrent = b.MaxCurrent
+	}
+	
 	if b.ChargerEfficency <= 0 || b.ChargerEfficency > 1 {
 		return fmt.Errorf("Charger efficiency must be on the range (0,1]")
 	}
-	b.Power = make(Power)
+	
+	if b.SpecificEnergy < 0 {
+		return fmt.Errorf("Battery specific energy can not be negative")
+	}
+	
+	if b.HeaterPower < 0 {
+		return fmt.Errorf("Battery heater power can not be negative")
+	}
+	
+	if len(b.ChargeAcceptance.X) != 0 || len(b.ChargeAcceptance.Y) != 0 {
+		err := b.ChargeAcceptance.Init()
+		if err != nil {
+			return fmt.Errorf("Charge acceptance: %v", err)
+		}
+	}
+	
+	if b.Thermal != nil {
+		err := b.Thermal.Init()
+		if err != nil {
+			return fmt.Errorf("Battery thermal: %v", err)
+		}
+	}
+	
+	if b.Aging != nil {
+		err := b.Aging.Init()
+		if err != nil {
+			return fmt.Errorf("Battery aging: %v", err)
+		}
+	}
+	
+	err := b.CapacityTemperature.Init()
+	if err != nil {
+		return fmt.Errorf("Capacity temperature: %v", err)
+	}
+	for _,p := range b.CapacityTemperature {
+		if p.Y <= 0 || p.Y > 1 {
+			return fmt.Errorf("Capacity temperature fractions must be on the range (0,1]")
+		}
+	}
+	err = b.ResistanceTemperature.Init()
+	if err != nil {
+		return fmt.Errorf("Resistance temperature: %v", err)
+	}
+	for _,p := range b.ResistanceTemperature {
+		if p.Y <= 0 {
+			return fmt.Errorf("Resistance temperature multiples must be positive")
+		}
+	}
+	
+	err = b.OpenCircuitVoltage.Init()
+	if err != nil {
+		return fmt.Errorf("Open circuit voltage: %v", err)
+	}
+	for _,p := range b.OpenCircuitVoltage {
+		if p.Y <= 0 {
+			return fmt.Errorf("Open circuit voltage must be positive")
+		}
+	}
+	
+	if b.MaxSOC == 0 {
+		b.MaxSOC = 1
+	}
+	if b.MinSOC < 0 || b.MaxSOC > 1 || b.MinSOC >= b.MaxSOC {
+		return fmt.Errorf("Usable state of charge window must be within [0,1]")
+	}
+	if b.InitialSOC == 0 {
+		b.InitialSOC = b.MaxSOC
+	}
+	if b.InitialSOC < b.MinSOC || b.InitialSOC > b.MaxSOC {
+		return fmt.Errorf("Initial state of charge must be within the usable window")
+	}
+	b.coulombsUsed = (1 - b.InitialSOC) * b.Coulomb
+	b.energyUsed = 0
+	b.energyRecovered = 0
+	b.loss = 0
+	b.resistanceFactor = 0
 	
 	return nil
 }
 
+//temperature is the pack temperature in kelvin
+func (b *Battery)temperature(sim *SimulatorState) float64 {
+	if b.Thermal != nil && b.Thermal.Temperature() != 0 {
+		return b.Thermal.Temperature()
+	}
+	return sim.Vehicle.Ambient.Temperature
+}
+
+//updateTemperature takes up the resistance for the pack's temperature
+func (b *Battery)updateTemperature(sim *SimulatorState) {
+	b.resistanceFactor = 1
+	if len(b.ResistanceTemperature) != 0 {
+		b.resistanceFactor = b.ResistanceTemperature.At(b.temperature(sim))
+	}
+}
+
+//internalResistance is Resistance at the pack's temperature
+func (b *Battery)internalResistance() float64 {
+	if b.resistanceFactor == 0 {
+		return b.Resistance
+	}
+	return b.Resistance * b.resistanceFactor
+}
+
+//minSOC is the lowest the pack can be drawn to at its temperature. A cold pack can't
+//reach the bottom of its usable window
+func (b *Battery)minSOC(sim *SimulatorState) float64 {
+	if len(b.CapacityTemperature) == 0 {
+		return b.MinSOC
+	}
+	available := b.CapacityTemperature.At(b.temperature(sim))
+	return b.MaxSOC - available * (b.MaxSOC - b.MinSOC)
+}
+
 func (b *Battery)CanOperate(sim *SimulatorState, power float64) error {
+	b.updateTemperature(sim)
 	amp := b.AmpsAtPower(power)
-	if math.IsNaN(amp) || math.Abs(amp) > b.MaxCurrent {
-		return fmt.Errorf("Exceeds max pack current")
+	if math.IsNaN(amp) {
+		//more power than the pack can deliver at any current
+		return LimitReason{Kind: LimitPackCurrent, Headroom: -1}
+	}
+	if math.Abs(amp) > b.MaxCurrent {
+		return LimitReason{Kind: LimitPackCurrent, Headroom: headroom(b.MaxCurrent, math.Abs(amp))}
+	}
+	if b.Thermal != nil {
+		available := b.Thermal.available(sim)
+		allowed := derated(b.ContinuousCurrent, b.MaxCurrent, available)
+		if math.Abs(amp) > allowed {
+			return LimitReason{Kind: LimitBatteryTemperature, Headroom: headroom(allowed, math.Abs(amp))}
+		}
+	}
+	if -amp > b.MaxChargeCurrent {
+		return LimitReason{Kind: LimitChargeCurrent, Headroom: headroom(b.MaxChargeCurrent, -amp)}
 	}
 	coulomb := amp * sim.Interval.Seconds()
-	if (coulomb + b.coulombsUsed) > b.Coulomb {
-		return fmt.Errorf("Battery Energy depleted")
+	soc := 1.0 - ((b.coulombsUsed + coulomb)/b.Coulomb)
+	if soc < b.minSOC(sim) {
+		return errDepleted
+	}
+	if soc > b.MaxSOC {
+		return LimitReason{Kind: LimitBatteryFull, Headroom: headroom(b.MaxSOC, soc)}
 	}
 	return nil
 }
@@ -59,16 +244,40 @@ func (b *Battery)CanOperate(sim *SimulatorState, power float64) error {
 func (b *Battery)Operate(sim *SimulatorState, power float64) float64 {
 	amp := b.AmpsAtPower(power)
 	time := sim.Interval.Seconds()
+	totalUsed := (amp*b.OpenCircuit())
 	b.coulombsUsed += amp * time
-	totalUsed := (amp*b.NominalVoltage)
-	b.Power["Internal Resistance"] = totalUsed - power
+	b.energyUsed += totalUsed * time
+	if power < 0 {
+		b.energyRecovered -= power * time
+	}
+	b.loss = totalUsed - power
+	if b.Thermal != nil {
+		b.Thermal.heat(sim, totalUsed - power)
+	}
 	sim.Resources["Electricity"] += (totalUsed * time) / b.ChargerEfficency
 	return power/amp
 }
 
+//OpenCircuit is the pack voltage at no load at the current state of charge
+func (b *Battery)OpenCircuit() float64 {
+	if len(b.OpenCircuitVoltage) == 0 {
+		return b.NominalVoltage
+	}
+	return b.OpenCircuitVoltage.At(b.StateOfCharge())
+}
+
+//VoltageAtPower is the terminal voltage while supplying power, sagging with internal resistance
 func (b *Battery)VoltageAtPower(power float64) float64  {
-	diff := math.Sqrt(b.NominalVoltage*b.NominalVoltage - 4*power*b.Resistance)
-	return (b.NominalVoltage + diff)/2
+	voc := b.OpenCircuit()
+	diff := math.Sqrt(voc*voc - 4*power*b.internalResistance())
+	return (voc + diff)/2
+}
+
+//MaxChargePower is the most power the pack can accept over the next tick without exceeding
+//its max charge current, derated for temperature, its charge acceptance at the current state
+//of charge and temperature, or MaxSOC, in watts
+func (b *Battery)MaxChargePower(sim *SimulatorState) float64 {
+	return b.chargePower(sim, b.MaxSOC)
 }
 
 func (b *Battery)AmpsAtPower(power float64) float64 {
@@ -79,6 +288,31 @@ func (b *Battery)StateOfCharge() float64 {
 	return 1.0 - (b.coulombsUsed/b.Coulomb)
 }
 
+//total energy stored in the pack when full, in joules
+func (b *Battery)Energy() float64 {
+	return b.Coulomb * b.NominalVoltage
+}
+
+//energy inside the usable state of charge window when full, in joules
+func (b *Battery)UsableEnergy() float64 {
+	return (b.MaxSOC - b.MinSOC) * b.Energy()
+}
+
+//usable energy left at the current state of charge, in joules
+func (b *Battery)RemainingEnergy() float64 {
+	return math.Max(0, b.StateOfCharge() - b.MinSOC) * b.Energy()
+}
+
+//energy drawn from the pack since the simulation started, in joules
+func (b *Battery)EnergyUsed() float64 {
+	return b.energyUsed
+}
+
+//energy put back into the pack (by regen) since the simulation started, in joules
+func (b *Battery)EnergyRecovered() float64 {
+	return b.energyRecovered
+}
+
 
 
 
@@ -0,0 +1,99 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegenCappedByChargeCurrent(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.MaxChargeCurrent = 20
+	sim := testSimulation(t, v)
+	sim.Speed = 30
+
+	_, err := sim.Tick(-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current := sim.Battery.AmpsAtPower(sim.Power.Total() - sim.Power.Battery); current < -20 * 1.001 {
+		t.Fatalf("regen charged at %.0f A, more than the pack's 20 A", -current)
+	}
+	if sim.FrictionBrakeEnergy <= 0 {
+		t.Fatal("friction brakes didn't take the regen the pack couldn't")
+	}
+	if sim.RegenLimitedTime != sim.Interval {
+		t.Fatalf("regen limited for %v of a %v tick", sim.RegenLimitedTime, sim.Interval)
+	}
+}
+
+func TestRunReportsRegenLimitedTime(t *testing.T) {
+	limited := testVehicle(t)
+	limited.Battery.MaxChargeCurrent = 20
+	result, err := testSimulation(t, limited).Run(testCycle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RegenLimitedTime <= 0 {
+		t.Fatal("no time reported with regen limited by a 20 A pack")
+	}
+
+	result, err = testSimulation(t, testVehicle(t)).Run(testCycle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RegenLimitedTime != 0 {
+		t.Fatalf("regen limited for %v by a 1000 A pack", result.RegenLimitedTime)
+	}
+}
+
+//thermalPack gives the test vehicle a heated pack in the cold
+func thermalPack(t *testing.T) *Vehicle {
+	v := testVehicle(t)
+	v.Ambient.Temperature = 263
+	v.Battery.HeaterPower = 5000
+	v.Battery.Thermal = &Thermal{HeatCapacity: 300000, Cooling: 20, DerateTemperature: 318, MaxTemperature: 333}
+	return v
+}
+
+func TestPreconditionForChargeMatchesPrecondition(t *testing.T) {
+	v := thermalPack(t)
+	energy, duration, err := v.PreconditionForCharge(263, 298)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//without the cooling it would be 300000*35/5000 = 2100s
+	if duration.Seconds() <= 2100 {
+		t.Fatalf("warming took %v, ignoring the pack's cooling", duration)
+	}
+	if math.Abs(energy - 5000 * duration.Seconds()) > 1 {
+		t.Fatalf("%.0f J isn't the heater running for %v", energy, duration)
+	}
+
+	result, err := testSimulation(t, v).Precondition(Preconditioning{Duration: duration, BatteryTarget: 298, FromGrid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.BatteryTemperature - 298) > 0.5 {
+		t.Fatalf("simulated pack reached %.1fK in %v, not 298K", result.BatteryTemperature, duration)
+	}
+}
+
+func TestPreconditionForChargeOutOfReach(t *testing.T) {
+	v := thermalPack(t)
+	v.Battery.HeaterPower = 500
+	_, _, err := v.PreconditionForCharge(263, 298)
+	if err == nil {
+		t.Fatal("500 W heater held the pack 35K above ambient against 20 W/K of cooling")
+	}
+}
+
+func TestThermalMassMigrated(t *testing.T) {
+	old := strings.Replace(testVehicleJSON, `"ChargerEfficency": 0.9`, `"ChargerEfficency": 0.9, "ThermalMass": 300000`, 1)
+	old = strings.Replace(old, "{", `{"SchemaVersion": 2,`, 1)
+	_, err := ParseVehicle([]byte(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,28 +2,120 @@ package automotiveSim
 
 
 import (
-	"time"
 	"fmt"
 	"math"
 )
 
+const coastIterations = 20 //for the weight transfer at the coast acceleration to settle
+
 type Wheelset struct {
 	Name string
 	Drive *Drive
 	WeightDistribution float64 //percentage of weight supported by this drives tire(s)
 	Tires Tire
+	BrakeBias float64 //share of the friction brake force, optional. Defaults to WeightDistribution
+	Front bool //ahead of the center of gravity, unloaded by acceleration. Used with Body.CGHeight
+	
+	//state
+	limits limitErrors
+	transfer float64 //share of the weight transfer this wheelset takes, negative at the front
 }
 
 type Drive struct {
 	Motor Motor
+	Engine *Engine //optional, drives the wheels instead of Motor
+	Hybrid *Hybrid //optional, an engine working alongside Motor
 	Gearing float64
 	Efficiency float64
+	EfficiencyCurve Curve //optional, vehicle speed (m/s) to efficiency. Overrides Efficiency
+	Gearbox *Gearbox //optional, between the motor and Gearing
+	Stages []GearStage //optional, the meshes making up Gearing. Overrides Efficiency and EfficiencyCurve
+	SpinLoss Curve //optional, motor shaft speed (rad/s) to drag torque (Nm) that turns with the drive loaded or not
+
+	//state
+	power DrivePower //on the last tick
+}
+
+//GearStage is one gear mesh of a drive, e.g. the reduction gear or the differential
+type GearStage struct {
+	Name string
+	Efficiency float64 //mesh efficiency under load
+}
+
+//EfficiencyAt is the mesh efficiency from motor shaft to wheel, spin losses aside
+func (d *Drive)EfficiencyAt(speed float64) float64 {
+	efficiency := d.Efficiency
+	if len(d.Stages) != 0 {
+		efficiency = 1
+		for _,s := range d.Stages {
+			efficiency *= s.Efficiency
+		}
+	} else if len(d.EfficiencyCurve) != 0 {
+		efficiency = d.EfficiencyCurve.At(speed)
+	}
+	if d.Gearbox != nil {
+		efficiency *= d.Gearbox.efficiency(d.Gearbox.gear)
+	}
+	return efficiency
+}
+
+//spinTorque is the drag torque at the motor shaft turning at shaftSpeed (rad/s), against
+//the direction of rotation
+func (d *Drive)spinTorque(shaftSpeed float64) float64 {
+	if len(d.SpinLoss) == 0 || shaftSpeed == 0 {
+		return 0
+	}
+	return math.Copysign(d.SpinLoss.At(math.Abs(shaftSpeed)), shaftSpeed)
+}
+
+//maxTorque is the most torque the powerplant can deliver at shaftSpeed (rad/s)
+func (d *Drive)maxTorque(sim *SimulatorState, shaftSpeed float64) (float64, error) {
+	if d.Engine != nil {
+		return d.Engine.MaxTorque(sim, shaftSpeed)
+	}
+	if d.Hybrid != nil && d.Hybrid.Mode == Parallel {
+		motor, limit := d.Motor.MaxTorque(sim, shaftSpeed)
+		engine, _ := d.Hybrid.Engine.MaxTorque(sim, shaftSpeed)
+		return motor + engine, limit
+	}
+	return d.Motor.MaxTorque(sim, shaftSpeed)
+}
+
+//maxShaftSpeed is the fastest the powerplant can turn, in rad/s
+func (d *Drive)maxShaftSpeed() float64 {
+	if d.Engine != nil {
+		return d.Engine.RedlineRPM * rpmToRadS
+	}
+	return d.Motor.MaxShaftSpeed
+}
+
+//Ratio is the overall reduction from motor shaft to wheel in the current gear
+func (d *Drive)Ratio() float64 {
+	if d.Gearbox == nil {
+		return d.Gearing
+	}
+	return d.Gearing * d.Gearbox.Ratios[d.Gearbox.gear]
+}
+
+//Shifting reports whether the drive is disconnected for a gear change
+func (d *Drive)Shifting() bool {
+	return d.Gearbox != nil && d.Gearbox.Shifting()
 }
 
 type Body struct {
 	Wheelsets []Wheelset
     Weight float64
     CdA float64
+	YawDragFactor float64 //optional, fractional increase in CdA per degree of yaw from a crosswind
+	TorqueSplit TorqueSplit `json:"-"` //optional, shares force between driven wheelsets. Defaults to ProportionalSplit. Set in code, not in a description
+	Trailer *Trailer //optional, towed behind the vehicle
+	TractionControl *TractionControl //optional, how wheelspin is handled. Without it the driven tires are held at peak grip
+	Regen *RegenLevels //optional, separate lift-off and brake pedal regen. Without it all the regen the motors have is used
+	
+	//optional, shift load from the front wheelsets to the rear ones as the vehicle accelerates
+	//or climbs, and back when braking, in meters
+	Wheelbase float64
+	CGHeight float64
 }
 
 func (b *Body)Init() error {
@@ -35,20 +127,99 @@ func (b *Body)Init() error {
 		return fmt.Errorf("Vehicle must not have negative drag area")
 	}
 	
+	if b.YawDragFactor < 0 {
+		return fmt.Errorf("Yaw drag factor must not be negative")
+	}
+	
+	if b.Wheelbase < 0 || b.CGHeight < 0 {
+		return fmt.Errorf("Wheelbase and center of gravity height must not be negative")
+	}
+	if b.CGHeight > 0 && b.Wheelbase == 0 {
+		return fmt.Errorf("Weight transfer requires a wheelbase")
+	}
+	
+	if b.TractionControl != nil {
+		err := b.TractionControl.Init()
+		if err != nil {
+			return err
+		}
+	}
+	if b.Regen != nil {
+		err := b.Regen.Init()
+		if err != nil {
+			return err
+		}
+	}
+	
+	if b.Trailer != nil {
+		err := b.Trailer.Init()
+		if err != nil {
+			return err
+		}
+	}
+	
 	totalWeightDist := 0.0
+	totalBias := 0.0
 	drivenCount := 0
 	for _,w := range b.Wheelsets {
 		if w.Drive != nil {
-			err := w.Drive.Motor.Init()
-			if err != nil {
-				return fmt.Errorf("%s: %v", w.Drive.Motor.Name, err)
+			var err error
+			if w.Drive.Engine != nil {
+				err = w.Drive.Engine.Init()
+				if err != nil {
+					return fmt.Errorf("%s: %v", w.Drive.Engine.Name, err)
+				}
+			} else {
+				err = w.Drive.Motor.Init()
+				if err != nil {
+					return fmt.Errorf("%s: %v", w.Drive.Motor.Name, err)
+				}
+			}
+			if w.Drive.Hybrid != nil {
+				if w.Drive.Engine != nil {
+					return fmt.Errorf("%s: a drive can not have both an engine and a hybrid", w.Name)
+				}
+				err = w.Drive.Hybrid.Init()
+				if err != nil {
+					return fmt.Errorf("%s: hybrid: %v", w.Name, err)
+				}
 			}
 			if w.Drive.Gearing == 0 {
 				return fmt.Errorf("%s: gearing must not be zero", w.Name)
 			}
-			if w.Drive.Efficiency <= 0 || w.Drive.Efficiency > 1 {
+			for _,s := range w.Drive.Stages {
+				if s.Efficiency <= 0 || s.Efficiency > 1 {
+					return fmt.Errorf("%s: %s: mesh efficiency must be on the range (0,1]", w.Name, s.Name)
+				}
+			}
+			if len(w.Drive.Stages) == 0 && len(w.Drive.EfficiencyCurve) == 0 && (w.Drive.Efficiency <= 0 || w.Drive.Efficiency > 1) {
 				return fmt.Errorf("%s: Mechanical drive efficiency must be on the range (0,1]", w.Name)
 			}
+			err = w.Drive.EfficiencyCurve.Init()
+			if err != nil {
+				return fmt.Errorf("%s: drive efficiency: %v", w.Name, err)
+			}
+			for _,p := range w.Drive.EfficiencyCurve {
+				if p.Y <= 0 || p.Y > 1 {
+					return fmt.Errorf("%s: Mechanical drive efficiency must be on the range (0,1]", w.Name)
+				}
+			}
+			err = w.Drive.SpinLoss.Init()
+			if err != nil {
+				return fmt.Errorf("%s: spin loss: %v", w.Name, err)
+			}
+			for _,p := range w.Drive.SpinLoss {
+				if p.Y < 0 {
+					return fmt.Errorf("%s: spin loss must not be negative", w.Name)
+				}
+			}
+			if w.Drive.Gearbox != nil {
+				err = w.Drive.Gearbox.Init()
+				if err != nil {
+					return fmt.Errorf("%s: gearbox: %v", w.Name, err)
+				}
+			}
+			w.Drive.power = DrivePower{}
 			drivenCount++
 		}
 		
@@ -56,7 +227,31 @@ func (b *Body)Init() error {
 		if err != nil {
 			return err
 		}
-		totalWeightDist += drive.WeightDistribution
+		totalWeightDist += w.WeightDistribution
+		if w.BrakeBias < 0 {
+			return fmt.Errorf("%s: brake bias must not be negative", w.Name)
+		}
+		totalBias += w.BrakeBias
+	}
+	for i := range b.Wheelsets {
+		b.Wheelsets[i].limits.init(b.Wheelsets[i].Name)
+	}
+	err := b.initWeightTransfer()
+	if err != nil {
+		return err
+	}
+	if b.TorqueSplit == nil {
+		b.TorqueSplit = ProportionalSplit{}
+	}
+	if split, ok := b.TorqueSplit.(FixedSplit); ok {
+		if len(split.Shares) != len(b.Wheelsets) {
+			return fmt.Errorf("Torque split requires one share per wheelset")
+		}
+		for _,share := range split.Shares {
+			if share < 0 {
+				return fmt.Errorf("Torque split shares must not be negative")
+			}
+		}
 	}
 	if drivenCount == 0 {
 		return fmt.Errorf("Vehicle requires at least one driven wheelset")
@@ -65,54 +260,206 @@ func (b *Body)Init() error {
 	if math.Abs(totalWeightDist - 1.0) >= 0.0001 {
 		return fmt.Errorf("Vehicle weight distribution does not sum to 1.0 (%6.4f)", totalWeightDist)
 	}
+	if totalBias != 0 && math.Abs(totalBias - 1.0) >= 0.0001 {
+		return fmt.Errorf("Vehicle brake bias does not sum to 1.0 (%6.4f)", totalBias)
+	}
 	return nil
 }
 
-func (b *Body)findWheelsetForces(sim *SimulatorState, accel float64) ([]float64, error) {
+//findWheelsetForces splits the force needed for accel between the wheelsets. When the
+//wheelsets can't brake hard enough on their own the friction brakes make up the
+//difference, returned as a positive force
+func (b *Body)findWheelsetForces(sim *SimulatorState, accel float64) ([]float64, float64, error) {
+	sim.accel = accel
+	
 	//first find the total force required by the rest of the car
-	totalForce := s.body.Weight * accel
-	totalForce += s.AeroDrag(sim)
+	totalForce := b.Mass() * accel
+	totalForce += b.AeroDrag(sim)
+	totalForce += b.GradeForce(sim)
+	totalForce += b.trailerDrag(sim)
 		
 	//find the total range of force the wheelsets are collectively able to produce
+	buffers := sim.tickBuffers(len(b.Wheelsets))
 	totalFmax := 0.0
-	FmaxLimits := make([]error, len(b.Wheelsets))
-	Fmax := make([]float64, len(b.Wheelsets))
+	FmaxLimits := buffers.limits
+	Fmax := buffers.fmax
 	totalFmin := 0.0
-	FminLimits := make([]error, len(b.Wheelsets))
-	Fmin := make([]float64, len(b.Wheelsets))
+	Fmin := buffers.fmin
 	for i,w := range b.Wheelsets {
-		Fmax[i], FmaxLimits[i] := w.Fmax(sim)
-		maxAvailableF += Fmax[i]
-		Fmin[i], FminLimits[i] := w.Fmin(sim)
-		minAvailableF += Fmin[i]
+		Fmax[i], FmaxLimits[i] = w.Fmax(sim)
+		totalFmax += Fmax[i]
+		Fmin[i], _ = w.Fmin(sim)
+	}
+	b.limitRegen(sim, Fmin)
+	b.limitRegenLevel(sim, Fmin)
+	for _,f := range Fmin {
+		totalFmin += f
 	}
 	
-	if(totalForce < totalFmin) {
-		errorStr := ""
-		for _,reason := range FminLimits {
-			errorStr += reason + "\n"
-		}
-		return nil, fmt.Errorf("Min wheelset force:\n%s", errorStr)
+	if totalForce < -b.MaxBrakeForce(sim) {
+		return nil, 0, LimitReason{Kind: LimitTireGrip, Headroom: headroom(b.MaxBrakeForce(sim), -totalForce)}
+	} else if(totalForce < totalFmin) {
+		//regen is maxed out, blend in the friction brakes for the rest
+		return Fmin, totalFmin - totalForce, nil
 	} else if (totalForce > totalFmax) {
-		errorStr := ""
-		for _,reason := range FmaxLimits {
-			errorStr += reason + "\n"
+		//report the first driven wheelset's limit, with the headroom of the vehicle as a whole
+		reason := LimitReason{}
+		for _,limit := range FmaxLimits {
+			l := asLimit(limit)
+			if reason.Kind == LimitNone || reason.Kind == LimitFreewheel {
+				reason = l
+			}
 		}
-		return nil, fmt.Errorf("Max wheelset force:\n%s", errorStr)
+		reason.Headroom = headroom(totalFmax, totalForce)
+		return nil, 0, reason
 	}
 	
-	//for now, balance the torque from each wheelset by driving them at the same % of their max
-	throttle := (totalForce - totalFmin)/(totalFmax - totalFmin)
-	
-	//reuse Fmax array for final output torques
+	return b.TorqueSplit.Split(sim, b, totalForce, Fmin, Fmax), 0, nil
+}
+
+//MaxBrakeForce is the most braking force the friction brakes can apply before the first
+//wheelset locks up, given the brake bias
+func (b *Body)MaxBrakeForce(sim *SimulatorState) float64 {
+	max := math.Inf(1)
+	for _,w := range b.Wheelsets {
+		bias := w.BrakeBias
+		if bias == 0 {
+			bias = w.WeightDistribution
+		}
+		if bias > 0 {
+			max = math.Min(max, w.Grip(sim) / bias)
+		}
+	}
+	return max
+}
+
+//limitRegen scales back the regen part of each wheelset's minimum force so the
+//recovered power fits within what the battery can accept. Rolling resistance
+//is left alone, it brakes the car either way
+func (b *Body)limitRegen(sim *SimulatorState, Fmin []float64) {
+	sim.regenLimited = false
+	regen := 0.0
+	for i,w := range b.Wheelsets {
+		regen -= (Fmin[i] + w.RollingDrag(sim)) * sim.Speed
+	}
+	//anything the accessories draw never reaches the pack
+	budget := sim.Vehicle.Battery.MaxChargePower(sim) + sim.Vehicle.auxiliaryPower()
+	if regen <= budget || regen <= 0 {
+		return
+	}
+	sim.regenLimited = true
+	scale := budget/regen
+	for i,w := range b.Wheelsets {
+		rolling := w.RollingDrag(sim)
+		Fmin[i] = (Fmin[i] + rolling) * scale - rolling
+	}
+}
+
+//limitRegenLevel scales back the regen part of the wheelsets' minimum force to the
+//deceleration Regen allows for the pedal in use
+func (b *Body)limitRegenLevel(sim *SimulatorState, Fmin []float64) {
+	if b.Regen == nil {
+		return
+	}
+	regen := 0.0
+	for i,w := range b.Wheelsets {
+		regen -= Fmin[i] + w.RollingDrag(sim)
+	}
+	allowed := b.Regen.level(sim.liftOff) * b.Mass()
+	if regen <= allowed || regen <= 0 {
+		return
+	}
+	scale := allowed/regen
+	for i,w := range b.Wheelsets {
+		rolling := w.RollingDrag(sim)
+		Fmin[i] = (Fmin[i] + rolling) * scale - rolling
+	}
+}
+
+//liftOffAccel is the deceleration with both pedals released, the wheelsets at their
+//lift-off regen. Like coastAccel it is worked out again from its own result for the
+//weight transfer
+func (b *Body)liftOffAccel(sim *SimulatorState) float64 {
+	accel := 0.0
+	for i := 0; i < coastIterations; i++ {
+		next := b.accelLiftedOff(sim, accel)
+		if math.Abs(next - accel) < 1e-6 {
+			accel = next
+			break
+		}
+		accel = next
+	}
+	//stay just inside the limit so rounding doesn't push it over
+	return accel + 1e-9
+}
+
+//accelLiftedOff is the acceleration with every wheelset at its regen limit, their loads
+//taken at accel
+func (b *Body)accelLiftedOff(sim *SimulatorState, accel float64) float64 {
+	sim.accel = accel
+	Fmin := sim.tickBuffers(len(b.Wheelsets)).fmin
+	for i,w := range b.Wheelsets {
+		Fmin[i], _ = w.Fmin(sim)
+	}
+	b.limitRegen(sim, Fmin)
+	b.limitRegenLevel(sim, Fmin)
+	total := 0.0
+	for _,f := range Fmin {
+		total += f
+	}
+	return (total - b.AeroDrag(sim) - b.GradeForce(sim) - b.trailerDrag(sim)) / b.Mass()
+}
+
+//coastAccel is the best the wheelsets can do when that isn't enough to hold speed. Their
+//grip depends on the weight transfer at the acceleration itself, so it is worked out
+//again from its own result until it settles
+func (b *Body)coastAccel(sim *SimulatorState) float64 {
+	accel := 0.0
+	for i := 0; i < coastIterations; i++ {
+		next := b.accelFlatOut(sim, accel)
+		if math.Abs(next - accel) < 1e-6 {
+			accel = next
+			break
+		}
+		accel = next
+	}
+	//stay just inside the limit so rounding doesn't push it over
+	return accel - 1e-9
+}
+
+//accelFlatOut is the acceleration with every wheelset at Fmax, their loads taken at accel
+func (b *Body)accelFlatOut(sim *SimulatorState, accel float64) float64 {
+	sim.accel = accel
+	totalFmax := 0.0
 	for _,w := range b.Wheelsets {
-		Fmax[i] := (throttle * (Fmax[i] - Fmin[i])) + Fmin[i]
+		f, _ := w.Fmax(sim)
+		totalFmax += f
+	}
+	return (totalFmax - b.AeroDrag(sim) - b.GradeForce(sim) - b.trailerDrag(sim)) / b.Mass()
+}
+
+//Shifting reports whether any drive is mid gear change
+func (b *Body)Shifting() bool {
+	for _,w := range b.Wheelsets {
+		if w.Drive != nil && w.Drive.Shifting() {
+			return true
+		}
+	}
+	return false
+}
+
+//shift lets every gearbox pick its gear for the coming tick
+func (b *Body)shift(sim *SimulatorState) {
+	for i := range b.Wheelsets {
+		w := &b.Wheelsets[i]
+		if w.Drive != nil && w.Drive.Gearbox != nil {
+			w.Drive.Gearbox.update(sim, w)
+		}
 	}
-	return Fmax, nil
 }
 
 func (b *Body)CanOperate(sim *SimulatorState, accel float64) (float64, error) {
-	forces, err := b.findWheelsetForces(sim, accel)
+	forces, _, err := b.findWheelsetForces(sim, accel)
 	if err != nil {
 		return 0, err
 	}
@@ -129,29 +476,97 @@ func (b *Body)CanOperate(sim *SimulatorState, accel float64) (float64, error) {
 }
 
 func (b *Body)Operate(sim *SimulatorState, accel float64) float64 {
-	forces, err := b.findWheelsetForces(sim, accel)
+	forces, friction, err := b.findWheelsetForces(sim, accel)
 	if err != nil {
 		panic("Got body error on operate")
 	}
 	
+	sim.Power.FrictionBrakes = friction * sim.Speed
+	sim.FrictionBrakeEnergy += friction * sim.Speed * sim.Interval.Seconds()
+	if sim.regenLimited && friction > 0 {
+		sim.RegenLimitedTime += sim.Interval
+	}
+	
+	if len(sim.Power.Drives) != len(b.Wheelsets) {
+		sim.Power.Drives = make([]DrivePower, len(b.Wheelsets))
+	}
 	totalPower := 0.0
 	for i,w := range b.Wheelsets {
 		totalPower += w.Operate(sim, forces[i])
+		sim.Power.Drives[i] = DrivePower{}
+		if w.Drive != nil {
+			sim.Power.Drives[i] = w.Drive.power
+		}
 	}
+	sim.Power.Traction = totalPower
 	return totalPower
 }
 
 func (b *Body)RollingDrag(sim *SimulatorState) float64 {
 	total := 0.0
-	for _,w := range b.Wheelsets {
-		supportedWeight := w.WeightDistribution * b.Weight
-		total += supportedWeight * gravity * w.Tires.RollingResistance
+	for i := range b.Wheelsets {
+		total += b.Wheelsets[i].RollingDrag(sim)
 	}	
-	return total
+	return total + b.trailerDrag(sim)
 }
 
+//initWeightTransfer shares the weight transfer out between the wheelsets at each end in
+//proportion to the weight they carry
+func (b *Body)initWeightTransfer() error {
+	front, rear := 0.0, 0.0
+	for _,w := range b.Wheelsets {
+		if w.Front {
+			front += w.WeightDistribution
+		} else {
+			rear += w.WeightDistribution
+		}
+	}
+	if b.CGHeight > 0 && (front == 0 || rear == 0) {
+		return fmt.Errorf("Weight transfer requires wheelsets both front and rear")
+	}
+	for i := range b.Wheelsets {
+		w := &b.Wheelsets[i]
+		w.transfer = 0
+		if b.CGHeight == 0 {
+			continue
+		}
+		if w.Front {
+			w.transfer = -w.WeightDistribution / front
+		} else {
+			w.transfer = w.WeightDistribution / rear
+		}
+	}
+	return nil
+}
+
+//weightTransfer is the load (N) moved onto the rear wheelsets at accel
+func (b *Body)weightTransfer(sim *SimulatorState, accel float64) float64 {
+	if b.CGHeight == 0 {
+		return 0
+	}
+	force := b.Weight * (accel + gravity * math.Sin(math.Atan(sim.Grade)))
+	return force * b.CGHeight / b.Wheelbase
+}
+
+//GradeForce is the component of gravity pulling the vehicle back down the slope
+func (b *Body)GradeForce(sim *SimulatorState) float64 {
+	return b.Mass() * gravity * math.Sin(math.Atan(sim.Grade))
+}
+
+//AeroDrag is the drag along the direction of travel from the air speed relative to
+//the vehicle, wind included. A crosswind yaws the flow, which raises CdA
 func (b *Body)AeroDrag(sim *SimulatorState) float64 {
-    return 0.5 * b.CdA * sim.Speed * sim.Speed * airDensity(sim.Vehicle.Ambient.Temperature, sim.Vehicle.Ambient.Pressure)
+	along := sim.Speed + sim.Headwind
+	apparent := math.Hypot(along, sim.Crosswind)
+	yaw := math.Abs(math.Atan2(sim.Crosswind, along)) * 180 / math.Pi
+	cda := b.CdA * (1 + b.YawDragFactor * yaw)
+	if b.Trailer != nil {
+		cda += b.Trailer.CdA
+	}
+	
+	//signed so a tailwind faster than the vehicle pushes it along
+    drag := 0.5 * cda * along * apparent * sim.Vehicle.Ambient.AirDensity()
+	return drag * (1 - sim.DragReduction)
 }
 
 
@@ -0,0 +1,45 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+)
+
+//tractionLimited is the test vehicle on slippery tires with weight transfer, so its grip
+//depends on how hard it accelerates
+func tractionLimited(t *testing.T) *SimulatorState {
+	v := testVehicle(t)
+	v.Body.CGHeight, v.Body.Wheelbase = 0.6, 2.8
+	v.Body.Wheelsets[0].Front = true
+	for i := range v.Body.Wheelsets {
+		v.Body.Wheelsets[i].Tires.Grip = 0.4
+	}
+	sim := testSimulation(t, v)
+	sim.Speed = 10
+	return sim
+}
+
+func TestCoastAccelIgnoresStaleAccel(t *testing.T) {
+	sim := tractionLimited(t)
+	b := sim.Body
+
+	sim.accel = -8
+	after := b.coastAccel(sim)
+	sim.accel = 8
+	if other := b.coastAccel(sim); math.Abs(other - after) > 1e-6 {
+		t.Fatalf("coast acceleration %.4f after braking, %.4f after accelerating", after, other)
+	}
+	if settled := b.accelFlatOut(sim, after); math.Abs(settled - after) > 1e-5 {
+		t.Fatalf("coast acceleration %.4f, but the wheelsets give %.4f with the weight transfer at it", after, settled)
+	}
+}
+
+func TestGradeForce(t *testing.T) {
+	sim := testSimulation(t, testVehicle(t))
+	sim.Grade = 0.1
+	want := sim.Body.Mass() * gravity * 0.1/math.Sqrt(1.01)
+	if got := sim.Body.GradeForce(sim); math.Abs(got - want) > 1e-6 {
+		t.Fatalf("got %.3f N, want %.3f N", got, want)
+	}
+}
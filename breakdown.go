@@ -0,0 +1,67 @@
+package automotiveSim
+
+
+import (
+)
+
+//EnergyBreakdown is where the energy of a run went, integrated tick by tick, in joules
+type EnergyBreakdown struct {
+	Aero float64
+	Rolling float64 //tires, the trailer's included
+	Grade float64 //net work lifting the vehicle, negative downhill
+	Kinetic float64 //net change in kinetic energy, negative when the vehicle ends slower
+	Accessory float64
+	Climate float64
+	LowVoltage float64 //12V loads
+	DCDC float64 //DC-DC converter losses
+	Drivetrain float64 //gear mesh and spin losses between motor and wheels
+	Motor float64 //motor losses, driving and regenerating
+	Inverter float64
+	Battery float64 //internal resistance
+	FrictionBrakes float64
+	Regen float64 //put back into the battery
+}
+
+//account adds one tick at accel to the running breakdown. It is called by Operate before
+//the speed is updated, with the climate load it drew
+func (state *SimulatorState)account(accel, climate float64) {
+	b := &state.Vehicle.Body
+	dt := state.Interval.Seconds()
+	travel := state.Speed * dt
+	e := &state.Energy
+	e.Aero += b.AeroDrag(state) * travel
+	e.Rolling += b.RollingDrag(state) * travel
+	e.Grade += b.GradeForce(state) * travel
+	e.Kinetic += b.Mass() * accel * travel
+	e.Accessory += state.Vehicle.Accessory * dt
+	e.Climate += climate * dt
+	e.LowVoltage += state.Power.LowVoltage * dt
+	e.DCDC += state.Power.DCDC * dt
+	for _,d := range state.Power.Drives {
+		e.Drivetrain += (d.GearFriction + d.SpinLoss) * dt
+		e.Motor += d.MotorLosses * dt
+		e.Inverter += d.InverterLosses * dt
+	}
+	e.Battery += state.Power.Battery * dt
+	e.FrictionBrakes += state.Power.FrictionBrakes * dt
+}
+
+//since is the breakdown accumulated after start
+func (e EnergyBreakdown)since(start EnergyBreakdown) EnergyBreakdown {
+	return EnergyBreakdown{
+		Aero: e.Aero - start.Aero,
+		Rolling: e.Rolling - start.Rolling,
+		Grade: e.Grade - start.Grade,
+		Kinetic: e.Kinetic - start.Kinetic,
+		Accessory: e.Accessory - start.Accessory,
+		Climate: e.Climate - start.Climate,
+		LowVoltage: e.LowVoltage - start.LowVoltage,
+		DCDC: e.DCDC - start.DCDC,
+		Drivetrain: e.Drivetrain - start.Drivetrain,
+		Motor: e.Motor - start.Motor,
+		Inverter: e.Inverter - start.Inverter,
+		Battery: e.Battery - start.Battery,
+		FrictionBrakes: e.FrictionBrakes - start.FrictionBrakes,
+		Regen: e.Regen - start.Regen,
+	}
+}
@@ -0,0 +1,202 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultPassengerMass = 70.0 //kg, a passenger and what they carry
+	dwellInterval = time.Second
+)
+
+//BusStop is one stop of a bus route and the leg driven to it
+type BusStop struct {
+	Name string
+	Distance float64 //m from the previous stop, zero for the first
+	Grade float64 //optional, of the leg from the previous stop (rise/run)
+	Dwell time.Duration //stopped with the doors open
+	Alighting int
+	Boarding int
+	Charger *Charger //optional, opportunity charging while dwelling, usually at the termini
+}
+
+//BusRoute is a transit bus scenario: a line of stops with passengers getting on and off,
+//changing the mass carried, and the doors letting the cabin air out at every stop
+type BusRoute struct {
+	Name string
+	Stops []BusStop //the first and last are the termini
+	SpeedLimit float64 //m/s between stops
+	InitialPassengers int //on board leaving the first stop, before its boardings
+	PassengerMass float64 //kg, defaults to 70
+	DoorOpenLoad float64 //W of extra heating or cooling while the doors are open
+}
+
+//EvenStops is count stops spacing meters apart, each with the same dwell and no passengers,
+//to fill in with boardings and alightings
+func EvenStops(count int, spacing float64, dwell time.Duration) []BusStop {
+	stops := make([]BusStop, count)
+	for i := range stops {
+		stops[i] = BusStop{Name: fmt.Sprintf("Stop %d", i+1), Dwell: dwell}
+		if i > 0 {
+			stops[i].Distance = spacing
+		}
+	}
+	return stops
+}
+
+func (r *BusRoute)Init() error {
+	if len(r.Stops) < 2 {
+		return fmt.Errorf("Bus route requires at least two stops")
+	}
+	if r.SpeedLimit <= 0 {
+		return fmt.Errorf("Bus route speed limit must be positive")
+	}
+	if r.InitialPassengers < 0 {
+		return fmt.Errorf("Passengers on board must not be negative")
+	}
+	if r.PassengerMass < 0 {
+		return fmt.Errorf("Passenger mass must not be negative")
+	}
+	if r.PassengerMass == 0 {
+		r.PassengerMass = defaultPassengerMass
+	}
+	if r.DoorOpenLoad < 0 {
+		return fmt.Errorf("Door open load must not be negative")
+	}
+	passengers := r.InitialPassengers
+	for i,s := range r.Stops {
+		if s.Distance < 0 || (i > 0 && s.Distance == 0) {
+			return fmt.Errorf("%s: stops after the first must be a positive distance from the one before", s.Name)
+		}
+		if s.Dwell < 0 || s.Alighting < 0 || s.Boarding < 0 {
+			return fmt.Errorf("%s: dwell and passenger counts must not be negative", s.Name)
+		}
+		if s.Alighting > passengers {
+			return fmt.Errorf("%s: %d passengers alight from a bus carrying %d", s.Name, s.Alighting, passengers)
+		}
+		passengers += s.Boarding - s.Alighting
+		if s.Charger != nil {
+			err := s.Charger.Init()
+			if err != nil {
+				return fmt.Errorf("%s: %v", s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+//BusStopResult is the leg to a stop and the dwell there
+type BusStopResult struct {
+	Name string
+	Passengers int //on board leaving the stop
+	LegEnergy float64 //J drawn driving from the previous stop
+	DwellEnergy float64 //J drawn while stopped, net of any charging
+	Charged float64 //J put into the pack by an opportunity charger
+	SOC float64 //leaving the stop
+}
+
+//BusResult is one trip along a bus route
+type BusResult struct {
+	Duration time.Duration
+	Distance float64
+	Energy float64 //J net drawn from the pack, charging included
+	Charged float64 //J from opportunity chargers
+	PassengerKm float64
+	Stops []BusStopResult
+	EndSOC float64
+}
+
+//RunBusRoute drives a copy of the vehicle along route, stop to stop, from its current
+//state of charge
+func (vehicle *Vehicle)RunBusRoute(route BusRoute) (BusResult, error) {
+	err := route.Init()
+	if err != nil {
+		return BusResult{}, err
+	}
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return BusResult{}, err
+	}
+	v := sim.Vehicle
+	b := &v.Battery
+	empty := v.Body.Weight
+	startEnergy := b.EnergyUsed()
+
+	result := BusResult{}
+	passengers := route.InitialPassengers
+	for i,stop := range route.Stops {
+		s := BusStopResult{Name: stop.Name}
+		if i > 0 {
+			leg := &Schedule{
+				Name: fmt.Sprintf("%s to %s", route.Stops[i-1].Name, stop.Name),
+				Distances: []float64{0, stop.Distance},
+				Speeds: []float64{route.SpeedLimit, 0},
+			}
+			sim.Grade = stop.Grade
+			r, err := sim.Run(leg)
+			if err != nil {
+				return result, err
+			}
+			s.LegEnergy = r.Energy
+			result.PassengerKm += float64(passengers) * r.Distance / 1000
+		}
+
+		passengers += stop.Boarding - stop.Alighting
+		v.Body.Weight = empty + float64(passengers) * route.PassengerMass
+		s.DwellEnergy, s.Charged, err = sim.dwell(stop, route.DoorOpenLoad)
+		if err != nil {
+			return result, fmt.Errorf("%s: %v", stop.Name, err)
+		}
+		s.Passengers = passengers
+		s.SOC = b.StateOfCharge()
+		result.Charged += s.Charged
+		result.Stops = append(result.Stops, s)
+	}
+	result.Duration = sim.Time
+	result.Distance = sim.Distance
+	result.Energy = b.EnergyUsed() - startEnergy
+	result.EndSOC = b.StateOfCharge()
+	return result, nil
+}
+
+//dwell holds the bus at a stop with its doors open, the cabin losing its air to the
+//outside and any charger topping up the pack
+func (sim *SimulatorState)dwell(stop BusStop, doorOpenLoad float64) (energy, charged float64, err error) {
+	v := sim.Vehicle
+	b := &v.Battery
+	saved := sim.Interval
+	defer func() { sim.Interval = saved }()
+	sim.Speed = 0
+
+	for elapsed := time.Duration(0); elapsed < stop.Dwell; elapsed += sim.Interval {
+		sim.Interval = dwellInterval
+		if stop.Dwell - elapsed < sim.Interval {
+			sim.Interval = stop.Dwell - elapsed
+		}
+		load := v.auxiliaryPower() + doorOpenLoad
+		if v.Climate != nil {
+			load += v.Climate.Operate(sim)
+		}
+		charge := 0.0
+		if stop.Charger != nil {
+			//the charger carries the loads, and the pack takes what's left
+			charge = math.Min(stop.Charger.MaxPower, b.chargePower(sim, b.MaxSOC) + load)
+		}
+		net := load - charge
+		err := b.CanOperate(sim, net)
+		if err != nil {
+			return energy, charged, err
+		}
+		b.Operate(sim, net)
+		dt := sim.Interval.Seconds()
+		energy += net * dt
+		if net < 0 {
+			charged -= net * dt
+		}
+		sim.Time += sim.Interval
+	}
+	return energy, charged, nil
+}
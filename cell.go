@@ -0,0 +1,123 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+//Cell is the datasheet of a single cell, for building a Battery out of Series x Parallel of them
+type Cell struct {
+	Chemistry string //optional, e.g. "NMC" or "LFP"
+	NominalVoltage float64
+	Capacity float64 //Ah
+	Resistance float64 //ohm, DC internal resistance
+	MaxDischargeCurrent float64 //A
+	ContinuousDischargeCurrent float64 //A, optional. Defaults to MaxDischargeCurrent
+	MaxChargeCurrent float64 //A, optional. Defaults to MaxDischargeCurrent
+	Mass float64 //kg, optional. Gives the pack's SpecificEnergy, enclosure and cooling left out
+	OpenCircuitVoltage Curve //optional, cell voltage against state of charge (0-1)
+}
+
+func (c *Cell)Init() error {
+	if c.NominalVoltage <= 0 {
+		return fmt.Errorf("Cell must have positive nominal voltage")
+	}
+	if c.Capacity <= 0 {
+		return fmt.Errorf("Cell must have positive capacity")
+	}
+	if c.Resistance < 0 {
+		return fmt.Errorf("Cell can not have negative resistance")
+	}
+	if c.MaxDischargeCurrent <= 0 {
+		return fmt.Errorf("Cell must have positive maximum discharge current")
+	}
+	if c.ContinuousDischargeCurrent < 0 || c.ContinuousDischargeCurrent > c.MaxDischargeCurrent {
+		return fmt.Errorf("Cell continuous current must be on the range [0,MaxDischargeCurrent]")
+	}
+	if c.MaxChargeCurrent < 0 {
+		return fmt.Errorf("Cell max charge current can not be negative")
+	}
+	if c.Mass < 0 {
+		return fmt.Errorf("Cell mass can not be negative")
+	}
+	err := c.OpenCircuitVoltage.Init()
+	if err != nil {
+		return fmt.Errorf("Cell open circuit voltage: %v", err)
+	}
+	for _,p := range c.OpenCircuitVoltage {
+		if p.Y <= 0 {
+			return fmt.Errorf("Cell open circuit voltage must be positive")
+		}
+	}
+	return nil
+}
+
+//initFromCells fills in the pack-level fields from Cell, Series and Parallel. A pack-level
+//field may also be given, but only if it agrees with the cells. Once derived they are
+//derived again on every Init, so a clone picks up changes to the cells
+func (b *Battery)initFromCells() error {
+	if b.Series <= 0 || b.Parallel <= 0 {
+		return fmt.Errorf("Battery built from cells must have positive Series and Parallel counts")
+	}
+	c := b.Cell
+	err := c.Init()
+	if err != nil {
+		return err
+	}
+	series, parallel := float64(b.Series), float64(b.Parallel)
+	continuous := c.ContinuousDischargeCurrent
+	if continuous == 0 {
+		continuous = c.MaxDischargeCurrent
+	}
+	charge := c.MaxChargeCurrent
+	if charge == 0 {
+		charge = c.MaxDischargeCurrent
+	}
+	type derivedField struct {
+		name string
+		field *float64
+		value float64
+	}
+	derived := []derivedField{
+		{"NominalVoltage", &b.NominalVoltage, c.NominalVoltage * series},
+		{"Resistance", &b.Resistance, c.Resistance * series / parallel},
+		{"Coulomb", &b.Coulomb, c.Capacity * 3600 * parallel},
+		{"MaxCurrent", &b.MaxCurrent, c.MaxDischargeCurrent * parallel},
+		{"ContinuousCurrent", &b.ContinuousCurrent, continuous * parallel},
+		{"MaxChargeCurrent", &b.MaxChargeCurrent, charge * parallel},
+	}
+	if c.Mass > 0 {
+		derived = append(derived, derivedField{"SpecificEnergy", &b.SpecificEnergy, c.NominalVoltage * c.Capacity / c.Mass})
+	}
+	for _,d := range derived {
+		if !b.fromCells && *d.field != 0 && *d.field != d.value {
+			return fmt.Errorf("Battery %s of %g disagrees with the %dS%dP cells' %g", d.name, *d.field, b.Series, b.Parallel, d.value)
+		}
+		*d.field = d.value
+	}
+
+	if len(c.OpenCircuitVoltage) != 0 {
+		ocv := make(Curve, len(c.OpenCircuitVoltage))
+		for i,p := range c.OpenCircuitVoltage {
+			ocv[i] = CurvePoint{X: p.X, Y: p.Y * series}
+		}
+		if !b.fromCells && len(b.OpenCircuitVoltage) != 0 && !sameCurve(b.OpenCircuitVoltage, ocv) {
+			return fmt.Errorf("Battery OpenCircuitVoltage disagrees with the %dS%dP cells'", b.Series, b.Parallel)
+		}
+		b.OpenCircuitVoltage = ocv
+	}
+	b.fromCells = true
+	return nil
+}
+
+func sameCurve(a, b Curve) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
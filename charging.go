@@ -0,0 +1,170 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	chargeInterval = 10 * time.Second
+	maxChargeTime = 48 * time.Hour //gives up on a charger too weak to ever finish
+)
+
+//Charger is an external charger, AC or DC
+type Charger struct {
+	Name string
+	MaxPower float64 //W, at the pack
+	Efficiency float64 //optional, wall to pack. Defaults to Battery.ChargerEfficency
+}
+
+func (c *Charger)Init() error {
+	if c.MaxPower <= 0 {
+		return fmt.Errorf("%s: charger must have positive max power", c.Name)
+	}
+	if c.Efficiency < 0 || c.Efficiency > 1 {
+		return fmt.Errorf("%s: charger efficiency must be on the range (0,1]", c.Name)
+	}
+	return nil
+}
+
+type ChargeResult struct {
+	Duration time.Duration
+	Energy float64 //drawn from the wall, joules
+	SOC Curve //state of charge against time (s)
+	Power Curve //power into the pack (W) against time (s)
+	Time10to80 time.Duration //zero unless the session covered 10% to 80%
+}
+
+//Between is how long the session took to go from one state of charge to another, zero if
+//it didn't cover both
+func (r ChargeResult)Between(from, to float64) time.Duration {
+	if len(r.SOC) == 0 || from < r.SOC[0].Y || to > r.SOC[len(r.SOC)-1].Y || to < from {
+		return 0
+	}
+	seconds := r.timeAt(to) - r.timeAt(from)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+//timeAt is when the session reached soc, which only ever rises
+func (r ChargeResult)timeAt(soc float64) float64 {
+	for i := 1; i < len(r.SOC); i++ {
+		a, b := r.SOC[i-1], r.SOC[i]
+		if soc <= b.Y {
+			if b.Y == a.Y {
+				return a.X
+			}
+			return a.X + (soc - a.Y)*(b.X - a.X)/(b.Y - a.Y)
+		}
+	}
+	return r.SOC[len(r.SOC)-1].X
+}
+
+//Charge simulates a copy of the vehicle on charger from fromSOC up to toSOC. Power into
+//the pack is the least of the charger's limit, the battery's charge acceptance at its
+//current state of charge and temperature, and its max charge current
+func (vehicle *Vehicle)Charge(charger Charger, fromSOC, toSOC float64) (ChargeResult, error) {
+	err := charger.Init()
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	v, err := vehicle.Clone()
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	b := &v.Battery
+	if fromSOC < b.MinSOC || toSOC > b.MaxSOC || fromSOC >= toSOC {
+		return ChargeResult{}, fmt.Errorf("Charge must rise within the usable state of charge window")
+	}
+	b.InitialSOC = fromSOC
+	err = b.Init()
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	sim.Interval = chargeInterval
+	efficiency := charger.Efficiency
+	if efficiency == 0 {
+		efficiency = b.ChargerEfficency
+	}
+
+	var result ChargeResult
+	record := func(power float64) {
+		seconds := sim.Time.Seconds()
+		result.SOC = append(result.SOC, CurvePoint{X: seconds, Y: b.StateOfCharge()})
+		result.Power = append(result.Power, CurvePoint{X: seconds, Y: power})
+	}
+	for b.StateOfCharge() < toSOC - 1e-9 {
+		if sim.Time > maxChargeTime {
+			return result, fmt.Errorf("%s: charge did not finish", charger.Name)
+		}
+		power := math.Min(charger.MaxPower, b.chargePower(sim, toSOC))
+		if power <= 0 {
+			return result, fmt.Errorf("Battery stopped accepting charge at %.0f%%", b.StateOfCharge()*100)
+		}
+		record(power)
+		b.Operate(sim, -power)
+		result.Energy += power * sim.Interval.Seconds() / efficiency
+		sim.Time += sim.Interval
+	}
+	record(0)
+	result.Duration = sim.Time
+	result.Time10to80 = result.Between(0.1, 0.8)
+	return result, nil
+}
+
+//chargePower is the most the pack accepts from a charger over the next interval without
+//passing toSOC
+func (b *Battery)chargePower(sim *SimulatorState, toSOC float64) float64 {
+	b.updateTemperature(sim)
+	temperature := b.temperature(sim)
+	current := b.MaxChargeCurrent
+	if b.Thermal != nil {
+		current = math.Min(current, derated(b.ContinuousCurrent, b.MaxCurrent, b.Thermal.available(sim)))
+	}
+	current = math.Min(current, (toSOC - b.StateOfCharge()) * b.Coulomb / sim.Interval.Seconds())
+	if current <= 0 {
+		return 0
+	}
+	power := current * (b.OpenCircuit() + current * b.internalResistance())
+	if len(b.ChargeAcceptance.X) != 0 {
+		power = math.Min(power, b.ChargeAcceptance.At(b.StateOfCharge(), temperature))
+	}
+	return power
+}
+
+//PreconditionForCharge returns the energy (J) and time needed for the pack heater to warm
+//Battery.Thermal from fromTemp to toTemp (kelvin) ahead of a fast charge, losing heat to
+//the ambient through Thermal.Cooling as Precondition does. Vehicles without a battery
+//thermal model return zero
+func (vehicle *Vehicle)PreconditionForCharge(fromTemp, toTemp float64) (float64, time.Duration, error) {
+	b := &vehicle.Battery
+	if b.Thermal == nil || toTemp <= fromTemp {
+		return 0, 0, nil
+	}
+	if fromTemp <= 0 {
+		return 0, 0, fmt.Errorf("Temperature must be above absolute zero")
+	}
+	if b.HeaterPower == 0 {
+		return 0, 0, fmt.Errorf("Battery has no heater to precondition with")
+	}
+	
+	t := b.Thermal
+	ambient := vehicle.Ambient.Temperature
+	//net heating is HeaterPower - Cooling*(T - ambient), so the pack closes on its
+	//equilibrium exponentially and never gets past it
+	if b.HeaterPower <= t.Cooling * (toTemp - ambient) {
+		return 0, 0, fmt.Errorf("Battery heater can't hold the pack at %.1fK against the cooling", toTemp)
+	}
+	seconds := t.HeatCapacity * (toTemp - fromTemp) / b.HeaterPower
+	if t.Cooling > 0 {
+		seconds = t.HeatCapacity / t.Cooling * math.Log((b.HeaterPower - t.Cooling * (fromTemp - ambient)) / (b.HeaterPower - t.Cooling * (toTemp - ambient)))
+	}
+	energy := b.HeaterPower * seconds
+	duration := time.Duration(seconds * float64(time.Second))
+	return energy, duration, nil
+}
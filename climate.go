@@ -0,0 +1,213 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultCarnotFraction = 0.4
+	maxHeatPumpCOP = 5 //small temperature lifts don't get the heat pump past this
+)
+
+//Heater is how the climate system heats the cabin
+type Heater int
+
+const (
+	HeaterDefault Heater = iota //heats at Efficiency, like it cools
+	HeaterResistive //one joule of heat per joule of electricity
+	HeaterHeatPump //a fraction of the Carnot COP, resistive below HeatPumpCutoff
+)
+
+//SetpointStep changes the cabin setpoint at a point in the trip
+type SetpointStep struct {
+	Start time.Duration
+	Setpoint float64 //kelvin, zero turns the system off
+}
+
+//Climate models the cabin as a single thermal mass exchanging heat with the ambient air
+type Climate struct {
+	HeatCapacity float64 //J/K of the cabin air and interior
+	Insulation float64 //W/K conducted between cabin and ambient
+	MaxPower float64 //maximum heat moved into or out of the cabin, in watts
+	Efficiency float64 //heat moved per unit of electrical energy (1 for resistive heat)
+	InitialTemperature float64 //kelvin, defaults to ambient
+	Schedule []SetpointStep
+	
+	//optional, heating that differs from Efficiency. A heat pump reaches CarnotFraction
+	//(default 0.4) of the ideal COP between ambient and cabin, and switches to resistive
+	//heat below HeatPumpCutoff (kelvin)
+	Heater Heater
+	CarnotFraction float64
+	HeatPumpCutoff float64
+	
+	//optional, m^2 of glazing letting Ambient.Solar into the cabin
+	SolarArea float64
+	
+	//state
+	cabinTemperature float64
+}
+
+func (c *Climate)Init() error {
+	if c.HeatCapacity <= 0 {
+		return fmt.Errorf("Cabin heat capacity must be positive")
+	}
+	if c.Insulation < 0 {
+		return fmt.Errorf("Cabin insulation must not be negative")
+	}
+	if c.MaxPower < 0 {
+		return fmt.Errorf("Climate power must not be negative")
+	}
+	if c.Efficiency <= 0 {
+		return fmt.Errorf("Climate efficiency must be positive")
+	}
+	if c.InitialTemperature < 0 {
+		return fmt.Errorf("Cabin temperature must be above absolute zero")
+	}
+	if c.Heater < HeaterDefault || c.Heater > HeaterHeatPump {
+		return fmt.Errorf("Unknown heater type %d", c.Heater)
+	}
+	if c.CarnotFraction < 0 || c.CarnotFraction > 1 {
+		return fmt.Errorf("Carnot fraction must be on the range [0,1]")
+	}
+	if c.CarnotFraction == 0 {
+		c.CarnotFraction = defaultCarnotFraction
+	}
+	if c.HeatPumpCutoff < 0 {
+		return fmt.Errorf("Heat pump cutoff must not be negative")
+	}
+	if c.SolarArea < 0 {
+		return fmt.Errorf("Solar area must not be negative")
+	}
+	for i := 1; i < len(c.Schedule); i++ {
+		if c.Schedule[i].Start < c.Schedule[i-1].Start {
+			return fmt.Errorf("Climate schedule must be in time order")
+		}
+	}
+	c.cabinTemperature = c.InitialTemperature
+	return nil
+}
+
+func (c *Climate)setpoint(sim *SimulatorState) float64 {
+	setpoint := 0.0
+	for _,step := range c.Schedule {
+		if step.Start > sim.Time {
+			break
+		}
+		setpoint = step.Setpoint
+	}
+	return setpoint
+}
+
+//heat flow into the cabin (negative when cooling) needed to reach the setpoint this tick
+func (c *Climate)heatFlow(sim *SimulatorState) float64 {
+	return c.heatFlowTo(sim, c.setpoint(sim))
+}
+
+func (c *Climate)heatFlowTo(sim *SimulatorState, setpoint float64) float64 {
+	if c.cabinTemperature == 0 {
+		c.cabinTemperature = sim.Vehicle.Ambient.Temperature
+	}
+	if setpoint == 0 {
+		return 0
+	}
+	needed := c.leak(sim) + c.HeatCapacity * (setpoint - c.cabinTemperature) / sim.Interval.Seconds()
+	return math.Copysign(math.Min(math.Abs(needed), c.MaxPower), needed)
+}
+
+//leak is the heat lost from the cabin through its walls, less the sun coming in
+func (c *Climate)leak(sim *SimulatorState) float64 {
+	ambient := sim.Vehicle.Ambient
+	return c.Insulation * (c.cabinTemperature - ambient.Temperature) - c.SolarArea * ambient.Solar
+}
+
+//cop is the heat moved per unit of electrical energy moving heat (W) into the cabin at
+//cabin against outside temperatures
+func (c *Climate)cop(heat, cabin, outside float64) float64 {
+	if heat <= 0 || c.Heater == HeaterDefault {
+		return c.Efficiency
+	}
+	if c.Heater == HeaterResistive || outside < c.HeatPumpCutoff || cabin <= outside {
+		return 1
+	}
+	ideal := cabin / (cabin - outside)
+	return math.Max(1, math.Min(maxHeatPumpCOP, c.CarnotFraction * ideal))
+}
+
+//Load returns the electrical power the climate system draws this tick
+func (c *Climate)Load(sim *SimulatorState) float64 {
+	heat := c.heatFlow(sim)
+	return math.Abs(heat) / c.cop(heat, c.setpoint(sim), sim.Vehicle.Ambient.Temperature)
+}
+
+func (c *Climate)Operate(sim *SimulatorState) float64 {
+	return c.condition(sim, c.setpoint(sim))
+}
+
+//condition runs the system toward setpoint (kelvin) for one tick, returning its load
+func (c *Climate)condition(sim *SimulatorState, setpoint float64) float64 {
+	heat := c.heatFlowTo(sim, setpoint)
+	load := math.Abs(heat) / c.cop(heat, setpoint, sim.Vehicle.Ambient.Temperature)
+	c.cabinTemperature += (heat - c.leak(sim)) * sim.Interval.Seconds() / c.HeatCapacity
+	return load
+}
+
+//SteadyLoad is the electrical power (W) needed to hold the cabin at setpoint (kelvin)
+//once it has got there, in the given weather
+func (c *Climate)SteadyLoad(ambient Ambient, setpoint float64) float64 {
+	heat := c.Insulation * (setpoint - ambient.Temperature) - c.SolarArea * ambient.Solar
+	heat = math.Copysign(math.Min(math.Abs(heat), c.MaxPower), heat)
+	return math.Abs(heat) / c.cop(heat, setpoint, ambient.Temperature)
+}
+
+func (c *Climate)CabinTemperature() float64 {
+	return c.cabinTemperature
+}
+
+//ConsumptionWithClimate returns the energy drawn per meter (J/m) over the cycle when
+//the vehicle's climate system follows the given setpoint schedule. Vehicles without a
+//climate model use their constant accessory load
+func (vehicle *Vehicle)ConsumptionWithClimate(cycle *Schedule, setpoints []SetpointStep) (float64, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return 0, err
+	}
+	if v.Climate != nil {
+		v.Climate.Schedule = setpoints
+	}
+	return v.cycleConsumption(cycle)
+}
+
+//RangeVsAmbient returns the range (meters) from the current state of charge repeating the
+//cycle, see RangeOnCycle, at each of the given ambient temperatures (kelvin). The vehicle
+//starts soaked to ambient, the cabin held at setpoint (kelvin) if it has a climate model,
+//and a pack with CapacityTemperature or ResistanceTemperature loses range to the cold
+func (vehicle *Vehicle)RangeVsAmbient(temperatures []float64, setpoint float64, cycle *Schedule) ([]float64, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if v.Climate != nil {
+		v.Climate.Schedule = []SetpointStep{{Setpoint: setpoint}}
+	}
+	
+	ranges := make([]float64, len(temperatures))
+	for i,temperature := range temperatures {
+		v.Ambient.Temperature = temperature
+		//a car parked outside starts at ambient
+		if v.Climate != nil {
+			v.Climate.InitialTemperature = temperature
+		}
+		if v.Battery.Thermal != nil {
+			v.Battery.Thermal.InitialTemperature = temperature
+		}
+		r, err := v.RangeOnCycle(cycle)
+		if err != nil {
+			return nil, fmt.Errorf("%5.1fK: %v", temperature, err)
+		}
+		ranges[i] = r.Range * 1000
+	}
+	return ranges, nil
+}
@@ -0,0 +1,127 @@
+//go:build js && wasm
+
+//Command automotivesim-wasm runs the simulator in the browser. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o automotivesim.wasm ./cmd/automotivesim-wasm
+//
+//and load it with the wasm_exec.js of the same Go release. It sets a global automotiveSim
+//object whose functions take vehicle JSON, in the format ParseVehicle reads, and return a
+//Promise of the result:
+//
+//	automotiveSim.runAccelerationProfile(vehicleJSON)
+//	automotiveSim.runSchedule(vehicleJSON, scheduleJSONOrCycleName)
+//
+//A failed simulation rejects the Promise with an Error
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"syscall/js"
+
+	"github.com/evantandersen/automotiveSim"
+	"github.com/evantandersen/automotiveSim/cycles"
+)
+
+//accelResult is AccelProfile without the NaN it uses for times never reached
+type accelResult struct {
+	TopSpeed float64
+	Accel100 *float64 //null if 100km/h is never reached
+	QuarterMile float64
+	SixtyFoot float64
+	PeakAccel float64
+	TractionLimited float64
+	Limits []automotiveSim.LimitingReason
+	Warnings []automotiveSim.Warning
+}
+
+func main() {
+	js.Global().Set("automotiveSim", js.ValueOf(map[string]interface{}{
+		"runAccelerationProfile": promised(1, accel),
+		"runSchedule": promised(2, runSchedule),
+	}))
+	//the Go runtime has to keep running for the functions to be called
+	select {}
+}
+
+//promised wraps run as a JS function returning a Promise. Simulations run on their own
+//goroutine so the caller isn't blocked while they do
+func promised(args int, run func(args []string) (interface{}, error)) js.Func {
+	return js.FuncOf(func(this js.Value, values []js.Value) interface{} {
+		strs := make([]string, args)
+		for i := range strs {
+			if i < len(values) && values[i].Type() == js.TypeString {
+				strs[i] = values[i].String()
+			}
+		}
+		executor := js.FuncOf(func(this js.Value, callbacks []js.Value) interface{} {
+			resolve, reject := callbacks[0], callbacks[1]
+			go func() {
+				result, err := run(strs)
+				var body []byte
+				if err == nil {
+					body, err = json.Marshal(result)
+				}
+				if err != nil {
+					reject.Invoke(js.Global().Get("Error").New(err.Error()))
+					return
+				}
+				resolve.Invoke(js.Global().Get("JSON").Call("parse", string(body)))
+			}()
+			return nil
+		})
+		defer executor.Release()
+		return js.Global().Get("Promise").New(executor)
+	})
+}
+
+func accel(args []string) (interface{}, error) {
+	vehicle, err := automotiveSim.ParseVehicle([]byte(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	p, err := vehicle.RunAccelerationProfile()
+	if err != nil {
+		return nil, err
+	}
+	result := accelResult{
+		TopSpeed: p.TopSpeed,
+		QuarterMile: p.QuarterMile,
+		SixtyFoot: p.SixtyFoot,
+		PeakAccel: p.PeakAccel,
+		TractionLimited: p.TractionLimited,
+		Limits: p.Limits,
+		Warnings: p.Warnings,
+	}
+	if !math.IsNaN(p.Accel100) {
+		result.Accel100 = &p.Accel100
+	}
+	return result, nil
+}
+
+func runSchedule(args []string) (interface{}, error) {
+	vehicle, err := automotiveSim.ParseVehicle([]byte(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	var schedule *automotiveSim.Schedule
+	if strings.HasPrefix(strings.TrimSpace(args[1]), "{") {
+		schedule = &automotiveSim.Schedule{}
+		err = json.Unmarshal([]byte(args[1]), schedule)
+		if err != nil {
+			return nil, fmt.Errorf("Schedule: %v", err)
+		}
+	} else {
+		schedule, err = cycles.ByName(args[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	sim, err := automotiveSim.InitSimulation(vehicle)
+	if err != nil {
+		return nil, err
+	}
+	return sim.Run(schedule)
+}
@@ -0,0 +1,283 @@
+//Command automotivesim runs the simulator on a vehicle description from the command line.
+//
+//	automotivesim [flags] accel|cycle|efficiency|range vehicle.json
+//	automotivesim [-addr host:port] serve
+//
+//Results print as a table, or as JSON with -json. serve runs the HTTP API of package server
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/evantandersen/automotiveSim"
+	"github.com/evantandersen/automotiveSim/cycles"
+	"github.com/evantandersen/automotiveSim/server"
+)
+
+var (
+	jsonOutput = flag.Bool("json", false, "print results as JSON instead of a table")
+	cycleName = flag.String("cycle", "nedc", "drive cycle for cycle and range: nedc, ece15, eudc, a published .txt speed trace or a .gpx/.csv GPS trace")
+	speedList = flag.String("speeds", "50,80,100,120", "comma separated speeds in km/h for efficiency")
+	srtmDir = flag.String("srtm", "", "directory of SRTM .hgt tiles to take a GPS trace's elevation from")
+	addr = flag.String("addr", "localhost:8080", "address for serve to listen on")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: automotivesim [flags] accel|cycle|efficiency|range vehicle.json\n")
+	fmt.Fprintf(os.Stderr, "       automotivesim [-addr host:port] serve\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 1 && flag.Arg(0) == "serve" {
+		fmt.Fprintln(os.Stderr, http.ListenAndServe(*addr, server.Handler()))
+		os.Exit(1)
+	}
+	if flag.NArg() != 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	vehicle, err := automotiveSim.LoadVehicle(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_, warnings := vehicle.Validate()
+	for _,w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+
+	var result interface{}
+	var table [][]string
+	switch flag.Arg(0) {
+	case "accel":
+		result, table, err = accel(vehicle)
+	case "cycle":
+		result, table, err = cycle(vehicle)
+	case "efficiency":
+		result, table, err = efficiency(vehicle)
+	case "range":
+		result, table, err = vehicleRange(vehicle)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	} else {
+		err = printTable(table)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func printTable(rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _,row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func selectedCycle() (*automotiveSim.Schedule, error) {
+	ext := strings.ToLower(filepath.Ext(*cycleName))
+	if ext == ".gpx" || ext == ".csv" {
+		if *srtmDir != "" {
+			return cycles.LoadGPSWithElevation(*cycleName, cycles.NewSRTM(*srtmDir))
+		}
+		return cycles.LoadGPS(*cycleName)
+	}
+	if ext == ".txt" {
+		return cycles.LoadTrace(*cycleName)
+	}
+	return cycles.ByName(*cycleName)
+}
+
+func formatFloat(x float64, decimals int) string {
+	return strconv.FormatFloat(x, 'f', decimals, 64)
+}
+
+type accelResult struct {
+	TopSpeed float64 //km/h
+	Governed bool
+	DragTopSpeed float64 //km/h
+	Accel100 *float64 //seconds, null if 100km/h is never reached
+	QuarterMile float64
+	SixtyFoot float64
+	PeakAccel float64
+	TractionLimited float64 //seconds
+	Limits []automotiveSim.LimitingReason
+}
+
+func accel(vehicle *automotiveSim.Vehicle) (interface{}, [][]string, error) {
+	p, err := vehicle.RunAccelerationProfile()
+	if err != nil {
+		return nil, nil, err
+	}
+	result := accelResult{
+		TopSpeed: p.TopSpeed * 3.6,
+		Governed: p.Governed,
+		DragTopSpeed: p.DragTopSpeed * 3.6,
+		QuarterMile: p.QuarterMile,
+		SixtyFoot: p.SixtyFoot,
+		TractionLimited: p.TractionLimited,
+		PeakAccel: p.PeakAccel,
+		Limits: p.Limits,
+	}
+	accel100 := "-"
+	if !math.IsNaN(p.Accel100) {
+		result.Accel100 = &p.Accel100
+		accel100 = formatFloat(p.Accel100, 2)
+	}
+
+	topSpeed := formatFloat(result.TopSpeed, 1)
+	if p.Governed {
+		topSpeed += " (governed, " + formatFloat(result.DragTopSpeed, 1) + " without)"
+	}
+	table := [][]string{
+		{"Top speed (km/h)", topSpeed},
+		{"0-100 km/h (s)", accel100},
+		{"60 ft (s)", formatFloat(p.SixtyFoot, 2)},
+		{"Quarter mile (s)", formatFloat(p.QuarterMile, 2)},
+		{"Peak accel (m/s^2)", formatFloat(p.PeakAccel, 2)},
+		{"Traction limited (s)", formatFloat(p.TractionLimited, 2)},
+		{},
+		{"Limit", "From (s)", "From (km/h)", "To (km/h)"},
+	}
+	for _,l := range p.Limits {
+		table = append(table, []string{
+			l.Reason.Error(),
+			formatFloat(l.Start.Seconds(), 2),
+			formatFloat(l.StartSpeed * 3.6, 1),
+			formatFloat(l.EndSpeed * 3.6, 1),
+		})
+	}
+	return result, table, nil
+}
+
+func runCycle(vehicle *automotiveSim.Vehicle) (automotiveSim.ScheduleResult, error) {
+	schedule, err := selectedCycle()
+	if err != nil {
+		return automotiveSim.ScheduleResult{}, err
+	}
+	sim, err := automotiveSim.InitSimulation(vehicle)
+	if err != nil {
+		return automotiveSim.ScheduleResult{}, err
+	}
+	return sim.Run(schedule)
+}
+
+func cycle(vehicle *automotiveSim.Vehicle) (interface{}, [][]string, error) {
+	r, err := runCycle(vehicle)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := r.Consumption()
+	table := [][]string{
+		{"Cycle", r.Name},
+		{"Duration", r.Duration.Round(time.Second).String()},
+		{"Distance (km)", formatFloat(r.Distance/1000, 2)},
+		{"Energy (kWh)", formatFloat(r.Energy/3.6e6, 3)},
+		{"Consumption (Wh/km)", formatFloat(c.WhPerKm, 1)},
+		{"Consumption (Wh/mi)", formatFloat(c.WhPerMile, 1)},
+		{"MPGe", formatFloat(c.MPGe, 1)},
+		{"Recovered (kWh)", formatFloat(r.RecoveredEnergy/3.6e6, 3)},
+		{"Friction brakes (kWh)", formatFloat(r.FrictionEnergy/3.6e6, 3)},
+		{"Regen limited by battery", r.RegenLimitedTime.Round(time.Second).String()},
+		{"SOC", formatFloat(r.StartSOC*100, 1) + "% -> " + formatFloat(r.EndSOC*100, 1) + "%"},
+		{"Peak battery power (kW)", formatFloat(r.Stats.BatteryPower.Max/1000, 1)},
+		{"Peak battery current (A)", formatFloat(r.Stats.BatteryCurrent.Max, 0)},
+	}
+	return r, table, nil
+}
+
+func efficiency(vehicle *automotiveSim.Vehicle) (interface{}, [][]string, error) {
+	var speeds []float64
+	for _,field := range strings.Split(*speedList, ",") {
+		kph, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || kph <= 0 {
+			return nil, nil, fmt.Errorf("Bad speed %q", field)
+		}
+		speeds = append(speeds, kph / 3.6)
+	}
+	eff, err := vehicle.EfficiencyAtSpeeds(speeds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	causes := make([]string, 0, len(eff))
+	for cause := range eff {
+		causes = append(causes, cause)
+	}
+	sort.Strings(causes)
+
+	header := []string{"Speed (km/h)"}
+	for _,cause := range causes {
+		header = append(header, cause + " (N)")
+	}
+	table := [][]string{header}
+	for i,speed := range speeds {
+		row := []string{formatFloat(speed * 3.6, 0)}
+		for _,cause := range causes {
+			row = append(row, formatFloat(eff[cause][i], 1))
+		}
+		table = append(table, row)
+	}
+	return eff, table, nil
+}
+
+type rangeResult struct {
+	Cycle string
+	Consumption float64 //Wh/km
+	Range float64 //km
+}
+
+func vehicleRange(vehicle *automotiveSim.Vehicle) (interface{}, [][]string, error) {
+	schedule, err := selectedCycle()
+	if err != nil {
+		return nil, nil, err
+	}
+	//RangeOnCycle works on a copy, so run it before runCycle drains the vehicle
+	full, err := vehicle.RangeOnCycle(schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := runCycle(vehicle)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := rangeResult{
+		Cycle: r.Name,
+		Consumption: r.Consumption().WhPerKm,
+		Range: full.Range,
+	}
+	table := [][]string{
+		{"Cycle", result.Cycle},
+		{"Consumption (Wh/km)", formatFloat(result.Consumption, 1)},
+		{"Range (km)", formatFloat(result.Range, 0)},
+	}
+	return result, table, nil
+}
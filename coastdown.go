@@ -0,0 +1,84 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	coastdownSampleInterval = time.Second / 10
+)
+
+//Coastdown lets a copy of the vehicle roll in neutral from fromSpeed down to toSpeed (m/s)
+//on a flat road, slowed only by aero drag and rolling resistance. The speed is
+//sampled every tenth of a second
+func (vehicle *Vehicle)Coastdown(fromSpeed, toSpeed float64) ([]TelemetrySample, error) {
+	if toSpeed < 0 || fromSpeed <= toSpeed {
+		return nil, fmt.Errorf("Coastdown must start above its end speed")
+	}
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return nil, err
+	}
+
+	sim.Speed = fromSpeed
+	samples := []TelemetrySample{{Speed: sim.Speed}}
+	next := coastdownSampleInterval
+	interval := sim.Interval.Seconds()
+	for sim.Speed > toSpeed {
+		drag := v.Body.AeroDrag(sim) + v.Body.RollingDrag(sim)
+		accel := -drag / v.Body.Mass()
+		sim.Distance += sim.Speed * interval
+		sim.Speed += accel * interval
+		sim.Time += sim.Interval
+		if sim.Time >= next {
+			samples = append(samples, TelemetrySample{Time: sim.Time, Speed: sim.Speed, Accel: accel, Distance: sim.Distance})
+			next += coastdownSampleInterval
+		}
+	}
+	return samples, nil
+}
+
+//FitCoastdown estimates CdA (m^2) and a single rolling resistance coefficient from
+//measured coastdown samples, using the vehicle's mass and ambient conditions.
+//The road load F = F0 + F2*v^2 is fitted by least squares, where F0 is rolling
+//resistance and F2 the aero term
+func (vehicle *Vehicle)FitCoastdown(samples []TelemetrySample) (cda, crr float64, err error) {
+	if len(samples) < 3 {
+		return 0, 0, fmt.Errorf("Coastdown fit needs at least 3 samples")
+	}
+
+	//sums for the normal equations, on the midpoint of each pair of samples
+	var n, sx, sy, sxx, sxy float64
+	for i := 1; i < len(samples); i++ {
+		a, b := samples[i-1], samples[i]
+		dt := (b.Time - a.Time).Seconds()
+		if dt <= 0 {
+			return 0, 0, fmt.Errorf("Coastdown samples must be in time order")
+		}
+		speed := (a.Speed + b.Speed)/2
+		x := speed * speed
+		y := -vehicle.Body.Weight * (b.Speed - a.Speed)/dt
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	det := n*sxx - sx*sx
+	if det == 0 {
+		return 0, 0, fmt.Errorf("Coastdown needs a range of speeds to fit")
+	}
+	f2 := (n*sxy - sx*sy)/det
+	f0 := (sy - f2*sx)/n
+
+	density := vehicle.Ambient.AirDensity()
+	cda = 2 * f2 / density
+	crr = f0 / (vehicle.Body.Weight * gravity)
+	return cda, crr, nil
+}
@@ -1,6 +1,8 @@
 package automotiveSim
 
 import (
+	"context"
+	"errors"
 	"math"
 	"time"
 	"fmt"
@@ -9,94 +11,449 @@ import (
 const (
 	kph100 = 100 / 3.6
 	quarterMile = 402.33600 //quarter mile in meters
+	sixtyFeet = 18.288 //the drag strip's first timing light, in meters
 	causeFilter = 100 //number of simulation intervals
+	profileInterval = time.Millisecond * 10 //spacing of AccelProfile.Profile samples
+	scheduleProfileInterval = time.Second //spacing of ScheduleResult.Profile samples
+	mph60 = 60 * 0.44704
 )
 
 type Schedule struct {
 	Name string	
     Interval time.Duration
     Speeds []float64
+	Times []time.Duration //optional, when each speed is reached for unevenly spaced samples. Overrides Interval
+	
+	//optional, makes this a route: each speed is the limit from that distance (m) to the next,
+	//and the last is the speed to finish at. A repeated distance is a point limit, such as a
+	//stop sign with a speed of zero. See followRoute
+	Distances []float64
+	DragReduction []float64 //optional, fraction of aero drag removed at each sample (drafting)
+	
+	//optional road grade (rise/run), either one entry per speed or as an
+	//elevation profile (meters) keyed by distance travelled
+	Grades []float64
+	Elevation Curve
+	
+	//optional wind (m/s), one entry per speed. Headwind is negative for a tailwind
+	Headwind []float64
+	Crosswind []float64
+}
+
+//ScheduleResult summarizes a single run through a schedule
+type ScheduleResult struct {
+	Name string
+	Duration time.Duration
+	Distance float64
+	Energy float64 //drawn from the battery, in joules
+	RecoveredEnergy float64 //put back into the battery by regen, in joules
+	FrictionEnergy float64 //dissipated in the friction brakes, in joules
+	RegenLimitedTime time.Duration //braking with regen held back by the battery's charge rate, the friction brakes taking the rest
+	Fuel float64 //liquid fuel burned by engines, in liters
+	CO2 float64 //grams from burning the fuel
+	GridEnergy float64 //from the wall to charge back what was drawn, through the charger, in joules
+	EngineTimeline []EngineEvent //hybrid engine starts and stops during the run
+	Tracking TrackingError //how closely the driver followed the speed trace, zero for routes
+	Stats RunStatistics //tick by tick
+	Profile []ScheduleSample //once a second from the start of the run, for plotting
+	Breakdown EnergyBreakdown
+	StartSOC float64
+	EndSOC float64
+}
+
+//ScheduleSample is the vehicle during a run at one point of ScheduleResult.Profile
+type ScheduleSample struct {
+	Time time.Duration //from the start of the run
+	Speed float64
+	Power PowerBreakdown //on the tick ending at Time, without the per drive detail
+}
+
+func (sim *SimulatorState)Run(input *Schedule) (ScheduleResult, error) {
+	return sim.RunContext(context.Background(), input)
 }
 
-func (sim *SimulatorState)Run(input *Schedule) (error) {	
+//RunContext is Run, stopping with ctx's error once ctx is done
+func (sim *SimulatorState)RunContext(ctx context.Context, input *Schedule) (ScheduleResult, error) {	
+	battery := &sim.Vehicle.Battery
+	result := ScheduleResult{Name: input.Name, StartSOC: battery.StateOfCharge()}
+	startTime, startDistance, startEnergy := sim.Time, sim.Distance, battery.EnergyUsed()
+	startRecovered, startFriction := battery.EnergyRecovered(), sim.FrictionBrakeEnergy
+	startRegenLimited := sim.RegenLimitedTime
+	startFuel, startEvents := sim.fuelUsed(), len(sim.EngineEvents)
+	startCO2, startGrid := sim.tailpipeCO2(), sim.Resources["Electricity"]
+	startBreakdown := sim.Energy
+	
+	if len(input.DragReduction) != 0 && len(input.DragReduction) != len(input.Speeds) {
+		return result, fmt.Errorf("%s: drag reduction must have one entry per speed", input.Name)
+	}
+	for _,r := range input.DragReduction {
+		if r < 0 || r > 1 {
+			return result, fmt.Errorf("%s: drag reduction must be on the range [0,1]", input.Name)
+		}
+	}
+	if len(input.Times) != 0 && len(input.Times) != len(input.Speeds) {
+		return result, fmt.Errorf("%s: times must have one entry per speed", input.Name)
+	}
+	for i := 1; i < len(input.Times); i++ {
+		if input.Times[i] <= input.Times[i-1] {
+			return result, fmt.Errorf("%s: times must be increasing", input.Name)
+		}
+	}
+	if len(input.Distances) != 0 && len(input.Distances) != len(input.Speeds) {
+		return result, fmt.Errorf("%s: distances must have one entry per speed", input.Name)
+	}
+	if len(input.Distances) != 0 && len(input.Times) != 0 {
+		return result, fmt.Errorf("%s: specify either times or distances, not both", input.Name)
+	}
+	for i,d := range input.Distances {
+		if d < 0 || (i > 0 && d < input.Distances[i-1]) {
+			return result, fmt.Errorf("%s: distances must not be negative or decreasing", input.Name)
+		}
+		if input.Speeds[i] < 0 {
+			return result, fmt.Errorf("%s: speed limits must not be negative", input.Name)
+		}
+	}
+	if len(input.Grades) != 0 && len(input.Grades) != len(input.Speeds) {
+		return result, fmt.Errorf("%s: grades must have one entry per speed", input.Name)
+	}
+	if len(input.Headwind) != 0 && len(input.Headwind) != len(input.Speeds) {
+		return result, fmt.Errorf("%s: headwind must have one entry per speed", input.Name)
+	}
+	if len(input.Crosswind) != 0 && len(input.Crosswind) != len(input.Speeds) {
+		return result, fmt.Errorf("%s: crosswind must have one entry per speed", input.Name)
+	}
+	if len(input.Grades) != 0 && len(input.Elevation) != 0 {
+		return result, fmt.Errorf("%s: specify either grades or an elevation profile, not both", input.Name)
+	}
+	err := input.Elevation.Init()
+	if err != nil {
+		return result, fmt.Errorf("%s: elevation: %v", input.Name, err)
+	}
+	err = sim.Options.Init()
+	if err != nil {
+		return result, err
+	}
+	squaredError, ticks := 0.0, 0
+	stats := &runStats{start: sim.Time}
+	stats.sample(sim)
+	outer := sim.stats
+	sim.stats = stats
+	defer func() { sim.stats = outer }()
+	summarize := func() {
+		result.Duration = sim.Time - startTime
+		result.Distance = sim.Distance - startDistance
+		result.Energy = battery.EnergyUsed() - startEnergy
+		result.RecoveredEnergy = battery.EnergyRecovered() - startRecovered
+		result.FrictionEnergy = sim.FrictionBrakeEnergy - startFriction
+		result.RegenLimitedTime = sim.RegenLimitedTime - startRegenLimited
+		result.Fuel = sim.fuelUsed() - startFuel
+		result.CO2 = sim.tailpipeCO2() - startCO2
+		result.GridEnergy = sim.Resources["Electricity"] - startGrid
+		result.EngineTimeline = sim.EngineEvents[startEvents:]
+		result.Breakdown = sim.Energy.since(startBreakdown)
+		result.EndSOC = battery.StateOfCharge()
+		result.Stats = stats.summary()
+		result.Profile = stats.profile
+		if ticks > 0 {
+			result.Tracking.RMS = math.Sqrt(squaredError / float64(ticks))
+		}
+	}
+	if len(input.Distances) != 0 {
+		err = sim.followRoute(ctx, input)
+		summarize()
+		return result, err
+	}
     for i,newSpeed := range input.Speeds {
-        accel := (newSpeed - sim.Speed)/input.Interval.Seconds()
-		target := input.Interval * time.Duration(i)
+		if len(input.DragReduction) != 0 {
+			sim.DragReduction = input.DragReduction[i]
+		}
+		if len(input.Grades) != 0 {
+			sim.Grade = input.Grades[i]
+		}
+		if len(input.Headwind) != 0 {
+			sim.Headwind = input.Headwind[i]
+		}
+		if len(input.Crosswind) != 0 {
+			sim.Crosswind = input.Crosswind[i]
+		}
+		step, target := input.Interval, input.Interval * time.Duration(i)
+		if len(input.Times) != 0 {
+			target, step = input.Times[i], input.Times[i]
+			if i > 0 {
+				step -= input.Times[i-1]
+			}
+		}
+		//the trace runs in a straight line from the previous speed
+		prevSpeed, prevTime := sim.Speed, target - step
+		if i > 0 {
+			prevSpeed = input.Speeds[i-1]
+		}
+		traceAccel := (newSpeed - prevSpeed)/step.Seconds()
         for sim.Time < target {
-            currAccel, err := sim.Tick(accel);
-            if err != nil {
-				return fmt.Errorf("Vehicle failed to accelerate at %5.2fm/s (only %5.2f) (%v)", accel, currAccel, err)
-            }
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if len(input.Elevation) != 0 {
+				sim.Grade = input.Elevation.Slope(sim.Distance)
+			}
+			trace := prevSpeed + traceAccel * (sim.Time - prevTime).Seconds()
+            before := sim.Time
+            _, err := sim.Tick(sim.Options.Driver.Accel(sim, trace, traceAccel))
+			//a flat pack ends the run however close to the trace it still is
+			if errors.Is(err, errDepleted) || sim.Time == before {
+				summarize()
+				return result, fmt.Errorf("Vehicle stopped at %5.2fm/s (%w)", sim.Speed, err)
+			}
+			miss := trace + traceAccel * sim.Interval.Seconds() - sim.Speed
+			squaredError += miss * miss
+			ticks++
+			result.Tracking.Max = math.Max(result.Tracking.Max, math.Abs(miss))
+			if math.Abs(miss) > sim.Options.SpeedTolerance {
+				result.Tracking.Outside += sim.Interval
+				//torque is interrupted during a gear change, the schedule catches up after
+				if err != nil && !sim.Vehicle.Body.Shifting() {
+					return result, fmt.Errorf("Vehicle fell %5.2fm/s off the trace at %5.2fm/s (%w)", miss, trace, err)
+				}
+			}
         }
+		summarize()
     }
-    return nil
+    return result, nil
+}
+
+//Round returns a copy of the result with every value rounded to the precision of its
+//kind, for stable snapshots and display. The original keeps full precision
+func (r ScheduleResult)Round(precision Precision) ScheduleResult {
+	precision.Init()
+	p := &precision
+	c := r
+	c.Duration = p.duration(r.Duration)
+	c.Distance = roundStep(r.Distance, p.Distance)
+	c.Energy = roundStep(r.Energy, p.Energy)
+	c.RecoveredEnergy = roundStep(r.RecoveredEnergy, p.Energy)
+	c.FrictionEnergy = roundStep(r.FrictionEnergy, p.Energy)
+	c.GridEnergy = roundStep(r.GridEnergy, p.Energy)
+	c.RegenLimitedTime = p.duration(r.RegenLimitedTime)
+	c.Fuel = roundStep(r.Fuel, p.Fuel)
+	c.CO2 = roundStep(r.CO2, p.Mass)
+	c.EngineTimeline = make([]EngineEvent, len(r.EngineTimeline))
+	for i,e := range r.EngineTimeline {
+		e.Time = p.duration(e.Time)
+		c.EngineTimeline[i] = e
+	}
+	c.Tracking = TrackingError{
+		Max: roundStep(r.Tracking.Max, p.Speed),
+		RMS: roundStep(r.Tracking.RMS, p.Speed),
+		Outside: p.duration(r.Tracking.Outside),
+	}
+	c.Stats = RunStatistics{
+		Speed: p.stats(r.Stats.Speed, p.Speed),
+		BatteryPower: p.stats(r.Stats.BatteryPower, p.Power),
+		BatteryCurrent: p.stats(r.Stats.BatteryCurrent, p.Current),
+		BatteryTemperature: p.stats(r.Stats.BatteryTemperature, p.Temperature),
+		MotorTemperature: p.stats(r.Stats.MotorTemperature, p.Temperature),
+	}
+	c.Profile = make([]ScheduleSample, len(r.Profile))
+	for i,s := range r.Profile {
+		c.Profile[i] = ScheduleSample{Time: p.duration(s.Time), Speed: roundStep(s.Speed, p.Speed), Power: p.power(s.Power)}
+	}
+	c.Breakdown = p.energy(r.Breakdown)
+	c.StartSOC = roundStep(r.StartSOC, p.SOC)
+	c.EndSOC = roundStep(r.EndSOC, p.SOC)
+	return c
+}
+
+//LitersPer100km is the fuel consumption, zero for an electric vehicle
+func (r ScheduleResult)LitersPer100km() float64 {
+	if r.Distance <= 0 {
+		return 0
+	}
+	return r.Fuel / (r.Distance / 100000)
+}
+
+//MPG is the fuel economy in US miles per gallon, zero if no fuel was burned
+func (r ScheduleResult)MPG() float64 {
+	if r.Fuel <= 0 {
+		return 0
+	}
+	return (r.Distance / Mile) / (r.Fuel / usGallon)
 }
 
 type LimitingReason struct {
-	Reason string
+	Reason LimitReason
 	Start time.Duration
+	StartSpeed float64
+	EndSpeed float64
+}
+
+//PhaseTime is the part of a run spent under one limiting reason
+type PhaseTime struct {
+	Reason LimitReason
+	Seconds float64
+	Percent float64
 }
 
 type AccelProfile struct {
 	TopSpeed float64
+	Governed bool //TopSpeed is the speed limiter's, see Vehicle.SpeedLimiter
+	DragTopSpeed float64 //where drag stops the vehicle without its speed limiter, TopSpeed when it isn't governed
 	Accel100 float64
 	AccelTop float64
 	QuarterMile float64
+	SixtyFoot float64 //seconds to the drag strip's 60ft mark, which launch traction decides
 	PeakAccel float64
+	TractionLimited float64 //seconds the tires rather than the powertrain limited acceleration
 	Limits []LimitingReason
-	Profile []float64
+	Accel100Phases []PhaseTime //how the 0-100 time splits between limiting reasons
+	Profile []float64 //m/s every profileInterval (10ms), the first at the start
+	Warnings []Warning
+}
+
+//AccelOptions moves the start of the clock to match published test conventions.
+//The zero value times from t=0, distance=0
+type AccelOptions struct {
+	Rollout float64 //meters travelled before timing starts (0.3048 for the US drag strip)
+	ReactionTime time.Duration //driver reaction delay added to every time
+	Trace *Trace //optional, records every tick of the run
+	OnTick func(*TickState) //optional, called after every tick of the run
+	Simulation SimulationOptions
+}
+
+//Round returns a copy of the profile with every value rounded to the precision of its
+//kind, for stable snapshots and display. The original keeps full precision
+func (p AccelProfile)Round(precision Precision) AccelProfile {
+	precision.Init()
+	r := p
+	r.TopSpeed = roundStep(p.TopSpeed, precision.Speed)
+	r.DragTopSpeed = roundStep(p.DragTopSpeed, precision.Speed)
+	r.Accel100 = roundStep(p.Accel100, precision.Time)
+	r.AccelTop = roundStep(p.AccelTop, precision.Time)
+	r.QuarterMile = roundStep(p.QuarterMile, precision.Time)
+	r.SixtyFoot = roundStep(p.SixtyFoot, precision.Time)
+	r.PeakAccel = roundStep(p.PeakAccel, precision.Accel)
+	r.TractionLimited = roundStep(p.TractionLimited, precision.Time)
+	
+	r.Limits = make([]LimitingReason, len(p.Limits))
+	for i,l := range p.Limits {
+		l.Start = precision.duration(l.Start)
+		l.StartSpeed = roundStep(l.StartSpeed, precision.Speed)
+		l.EndSpeed = roundStep(l.EndSpeed, precision.Speed)
+		r.Limits[i] = l
+	}
+	r.Accel100Phases = make([]PhaseTime, len(p.Accel100Phases))
+	for i,phase := range p.Accel100Phases {
+		phase.Seconds = roundStep(phase.Seconds, precision.Time)
+		r.Accel100Phases[i] = phase
+	}
+	r.Profile = make([]float64, len(p.Profile))
+	for i,speed := range p.Profile {
+		r.Profile[i] = roundStep(speed, precision.Speed)
+	}
+	return r
 }
 
 func (vehicle *Vehicle)RunAccelerationProfile() (AccelProfile, error) {
+	return vehicle.RunAccelerationProfileWithOptions(AccelOptions{})
+}
+
+func (vehicle *Vehicle)RunAccelerationProfileWithOptions(opts AccelOptions) (AccelProfile, error) {
+	return vehicle.RunAccelerationProfileContext(context.Background(), opts)
+}
+
+//RunAccelerationProfileContext is RunAccelerationProfileWithOptions, stopping with ctx's
+//error once ctx is done. A vehicle that never settles at a top speed otherwise runs forever
+func (vehicle *Vehicle)RunAccelerationProfileContext(ctx context.Context, opts AccelOptions) (AccelProfile, error) {
+	if opts.Rollout < 0 || opts.ReactionTime < 0 {
+		return AccelProfile{}, fmt.Errorf("Rollout and reaction time must not be negative")
+	}
+	
 	sim, err := InitSimulation(vehicle)
     if err != nil {
     	return AccelProfile{}, err
     }
+	err = opts.Simulation.Init()
+	if err != nil {
+		return AccelProfile{}, err
+	}
+	sim.Options = opts.Simulation
+	sim.Trace = opts.Trace
+	if opts.OnTick != nil {
+		sim.OnTick(opts.OnTick)
+	}
 	
 	var result AccelProfile
+	var rolloutTime time.Duration
+	var time100 time.Duration
+	rolledOut := opts.Rollout == 0
 
-	speedInterval := time.Millisecond * 10
-	var currTime time.Duration
-	lastReason := ""
+	var lastReason LimitReason
+	result.Profile = []float64{sim.Speed}
 	for result.TopSpeed == 0 || result.QuarterMile == 0 {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		prevTime, prevSpeed := sim.Time, sim.Speed
 		//attempt to accelerate at 1,000 m/s^2
 		//it's a binary search, so it only slows things down log(n)
 		//so start with a huge n. This gurantees we are always
 		//accelerating at maximum speed
 		currAccel, err := sim.Tick(1000)
-		currReason := err.Error()
+		currReason := asLimit(err)
 		
-		if currReason != lastReason {
-			result.Limits = append(result.Limits, LimitingReason{Reason:currReason, Start:sim.Time})
+		if len(result.Limits) == 0 || !currReason.same(lastReason) {
+			result.Limits = append(result.Limits, LimitingReason{Reason:currReason, Start:sim.Time, StartSpeed:sim.Speed})
 		}
 		lastReason = currReason
+		if currReason.Kind == LimitTraction || currReason.Kind == LimitTireGrip {
+			result.TractionLimited += sim.Interval.Seconds()
+		}
 		
 		if currAccel > result.PeakAccel {
 			result.PeakAccel = currAccel
 		}
 		
+		if !rolledOut && sim.Distance >= opts.Rollout {
+			rolloutTime = sim.Time
+			rolledOut = true
+		}
+		
 		if sim.Speed > kph100 && result.Accel100 == 0 {
 			result.Accel100 = sim.Time.Seconds()
+			time100 = sim.Time
+		}
+		
+		if sim.Distance > (sixtyFeet + opts.Rollout) && result.SixtyFoot == 0 {
+			result.SixtyFoot = sim.Time.Seconds()
 		}
 		
-		if sim.Distance > quarterMile && result.QuarterMile == 0 {
+		if sim.Distance > (quarterMile + opts.Rollout) && result.QuarterMile == 0 {
 			result.QuarterMile = sim.Time.Seconds()
 		}
 		
-		//have we hit topspeed
-		if currAccel < 0.05  && result.TopSpeed == 0 {
+		//have we hit topspeed (a gear change only pauses the acceleration)
+		if currAccel < 0.05  && result.TopSpeed == 0 && !sim.Vehicle.Body.Shifting() {
 			result.TopSpeed = sim.Speed
+			result.Governed = currReason.Kind == LimitSpeedLimiter
 			result.AccelTop = sim.Time.Seconds()
 			if sim.Speed < kph100 {
 				result.Accel100 = math.NaN()
 			}
 		}
-		currTime += sim.Interval
-		if currTime > speedInterval {
-			result.Profile = append(result.Profile, sim.Speed)
-			currTime -= speedInterval
+		//a sample every profileInterval from the start, interpolated within the tick
+		for next := profileInterval * time.Duration(len(result.Profile)); next <= sim.Time && sim.Time > prevTime; next += profileInterval {
+			f := float64(next - prevTime) / float64(sim.Time - prevTime)
+			result.Profile = append(result.Profile, prevSpeed + f*(sim.Speed - prevSpeed))
 		}
 	}
+	result.Warnings = sim.Warnings
+	
+	//shift the reported times onto the requested clock
+	shift := (opts.ReactionTime - rolloutTime).Seconds()
+	result.Accel100 += shift
+	result.AccelTop += shift
+	result.QuarterMile += shift
+	result.SixtyFoot += shift
+	
 	//clean up transistions
 	pos := len(result.Limits) - 1
 	lastStart := time.Hour * 1000000
@@ -111,9 +468,163 @@ func (vehicle *Vehicle)RunAccelerationProfile() (AccelProfile, error) {
 	pos++
 	copy(result.Limits[:len(result.Limits) - pos], result.Limits[pos:])
 	result.Limits = result.Limits[:len(result.Limits) - pos]
+	
+	//each reason holds until the next one takes over
+	for i := range result.Limits {
+		if i + 1 < len(result.Limits) {
+			result.Limits[i].EndSpeed = result.Limits[i+1].StartSpeed
+		} else {
+			result.Limits[i].EndSpeed = sim.Speed
+		}
+	}
+	result.Accel100Phases = limitPhases(result.Limits, time100)
+
+	//run again with the governor off to find how fast the vehicle could go
+	result.DragTopSpeed = result.TopSpeed
+	if result.Governed {
+		free := *vehicle
+		free.SpeedLimiter = 0
+		freeOpts := opts
+		freeOpts.Trace, freeOpts.OnTick = nil, nil
+		p, err := free.RunAccelerationProfileContext(ctx, freeOpts)
+		if err != nil {
+			return result, err
+		}
+		result.DragTopSpeed = p.TopSpeed
+	}
+	return result, nil
+}
+
+//limitPhases splits the time from a standstill to end between the limiting reasons
+func limitPhases(limits []LimitingReason, end time.Duration) []PhaseTime {
+	if end <= 0 {
+		return nil
+	}
+	var phases []PhaseTime
+	for i,l := range limits {
+		start := l.Start
+		if i == 0 {
+			start = 0
+		}
+		if start >= end {
+			break
+		}
+		stop := end
+		if i + 1 < len(limits) && limits[i+1].Start < end {
+			stop = limits[i+1].Start
+		}
+		phases = append(phases, PhaseTime{
+			Reason: l.Reason,
+			Seconds: (stop - start).Seconds(),
+			Percent: 100 * float64(stop - start) / float64(end),
+		})
+	}
+	return phases
+}
+
+//SpeedAtDistance returns the speed when the vehicle first reaches distance d (meters)
+//accelerating flat out from a standstill
+func (vehicle *Vehicle)SpeedAtDistance(d float64) (float64, error) {
+	if d <= 0 {
+		return 0, fmt.Errorf("Distance must be positive")
+	}
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return 0, err
+	}
+	
+	prev := sim.Sample()
+	for sim.Distance < d {
+		sim.Tick(1000)
+		if sim.Speed <= 0 {
+			return 0, fmt.Errorf("Vehicle stopped before reaching %5.2fm", d)
+		}
+		if sim.Distance < d {
+			prev = sim.Sample()
+		}
+	}
+	return interpolateAtDistance(prev, sim.Sample(), d).Speed, nil
+}
+
+//BrakingStop is a single stop from Speed (m/s) braking as hard as possible
+type BrakingStop struct {
+	Speed float64
+	Distance float64 //meters
+	Time float64 //seconds
+	PeakDecel float64 //m/s^2, positive
+	Profile []BrakeSample //every profileInterval (10ms), the first at the start
+}
+
+//BrakeSample is the vehicle at one point of a braking stop
+type BrakeSample struct {
+	Time time.Duration //from the start of braking
+	Speed float64
+	Power PowerBreakdown //on the tick ending at Time, the drives' regen included
+}
+
+//Regen is the mechanical power the drives recovered, positive
+func (s *BrakeSample)Regen() float64 {
+	regen := 0.0
+	for _,d := range s.Power.Drives {
+		regen -= math.Min(0, d.Mechanical)
+	}
+	return regen
+}
+
+type BrakeProfile struct {
+	From100 BrakingStop //100-0 km/h
+	From60mph BrakingStop //60-0 mph
+}
+
+func (vehicle *Vehicle)RunBrakingProfile() (BrakeProfile, error) {
+	var result BrakeProfile
+	var err error
+	result.From100, err = vehicle.brakingStop(kph100)
+	if err != nil {
+		return result, err
+	}
+	result.From60mph, err = vehicle.brakingStop(mph60)
+	return result, err
+}
+
+//brakingStop brakes a copy of the vehicle to a standstill from speed on a flat road
+func (vehicle *Vehicle)brakingStop(speed float64) (BrakingStop, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return BrakingStop{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return BrakingStop{}, err
+	}
+	
+	result := BrakingStop{Speed: speed}
+	sim.Speed = speed
+	sim.Interval = profileInterval
+	result.Profile = append(result.Profile, BrakeSample{Speed: speed})
+	for sim.Speed > 0 {
+		lastSpeed, lastDistance, lastTime := sim.Speed, sim.Distance, sim.Time
+		//like the acceleration profile, ask for far more than is possible
+		accel, _ := sim.Tick(-1000)
+		if accel >= 0 {
+			return result, fmt.Errorf("Vehicle can not brake at %5.2fm/s", lastSpeed)
+		}
+		if -accel > result.PeakDecel {
+			result.PeakDecel = -accel
+		}
+		result.Profile = append(result.Profile, BrakeSample{Time: sim.Time, Speed: math.Max(0, sim.Speed), Power: sim.Power.Copy()})
+		if sim.Speed <= 0 {
+			//only part of the last tick was needed to stop
+			fraction := lastSpeed / (lastSpeed - sim.Speed)
+			stopTime := lastTime + time.Duration(fraction * float64(sim.Interval))
+			result.Time = stopTime.Seconds()
+			result.Distance = lastDistance + lastSpeed * fraction * sim.Interval.Seconds()
+		}
+	}
 	return result, nil
 }
 
+//EfficiencyAtSpeeds is the consumption (J/m) at each steady speed, split by cause
 func (vehicle *Vehicle)EfficiencyAtSpeeds(speeds []float64) (map[string][]float64, error) {
 	sim, err := InitSimulation(vehicle)
     if err != nil {
@@ -121,7 +632,7 @@ func (vehicle *Vehicle)EfficiencyAtSpeeds(speeds []float64) (map[string][]float6
     }
 	
 	eff := make(map[string][]float64)
-	causes := []string{"Aerodynamics", "Rolling Resistance", "Accessory", "Losses"}
+	causes := []string{"Aerodynamics", "Rolling Resistance", "Accessory", "Low Voltage", "Drivetrain", "Motor", "Inverter", "Battery", "Other"}
 	for _,cause := range causes {
 		eff[cause] = make([]float64, len(speeds))
 	}
@@ -132,15 +643,28 @@ func (vehicle *Vehicle)EfficiencyAtSpeeds(speeds []float64) (map[string][]float6
 		if math.Abs(currAccel) > 0.01 {
 			return nil, fmt.Errorf("Vehicle can not maintain speed %5.2f: %v", speed, err)
 		}
-		//copy the map
 		total := sim.Power.Total()/speed
 		aero := sim.Body.AeroDrag(sim)
 		tire := sim.Body.RollingDrag(sim)
-		accessory := sim.Power["Accessory"].(float64)/speed
+		accessory := sim.Power.Accessory/speed
 		eff["Accessory"][i] = accessory
+		lowVoltage := (sim.Power.LowVoltage + sim.Power.DCDC)/speed
+		eff["Low Voltage"][i] = lowVoltage
 		eff["Aerodynamics"][i] = aero
 		eff["Rolling Resistance"][i] = tire
-		eff["Losses"][i] = total - (accessory + aero + tire)
+		drivetrain, motor, inverter := 0.0, 0.0, 0.0
+		for _,d := range sim.Power.Drives {
+			drivetrain += (d.GearFriction + d.SpinLoss)/speed
+			motor += d.MotorLosses/speed
+			inverter += d.InverterLosses/speed
+		}
+		battery := sim.Power.Battery/speed
+		eff["Drivetrain"][i] = drivetrain
+		eff["Motor"][i] = motor
+		eff["Inverter"][i] = inverter
+		eff["Battery"][i] = battery
+		//climate, and anything a hybrid's engine makes up
+		eff["Other"][i] = total - (accessory + lowVoltage + aero + tire + drivetrain + motor + inverter + battery)
 	}
 	return eff, nil
 }
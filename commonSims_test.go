@@ -0,0 +1,81 @@
+package automotiveSim
+
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunReportsDepletionWithinTolerance(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.Coulomb = 8000 //under 1kWh
+	v.Battery.MinSOC = 0.1
+	sim := testSimulation(t, v)
+	//so loose that only the pack can end the run
+	sim.Options.SpeedTolerance = 100
+
+	_, err := sim.Run(testCycle())
+	if !errors.Is(err, errDepleted) {
+		t.Fatalf("got %v, want the pack depleted", err)
+	}
+	if soc := sim.Battery.StateOfCharge(); soc < v.Battery.MinSOC - 1e-9 {
+		t.Fatalf("ran on to state of charge %.3f", soc)
+	}
+}
+
+func TestRangeOnCycleStopsAtDepletion(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.Coulomb = 40000
+	err := v.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := v.RangeOnCycle(testCycle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Range <= 0 {
+		t.Fatalf("got a range of %.1f km", result.Range)
+	}
+	if end := result.SOC[len(result.SOC)-1].Y; end < v.Battery.MinSOC - 1e-9 {
+		t.Fatalf("ended at state of charge %.3f", end)
+	}
+}
+
+func TestBrakingProfileSamplesPower(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.InitialSOC = 0.8 //room for the regen
+	profile, err := v.RunBrakingProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := profile.From100
+	samples := stop.Profile
+	if len(samples) < 2 || samples[0].Speed != stop.Speed || samples[len(samples)-1].Speed != 0 {
+		t.Fatalf("%d samples don't run from %.1f m/s to a standstill", len(samples), stop.Speed)
+	}
+	regen, friction := 0.0, 0.0
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Time - samples[i-1].Time != 10 * time.Millisecond {
+			t.Fatalf("samples %d and %d are %v apart", i-1, i, samples[i].Time - samples[i-1].Time)
+		}
+		if len(samples[i].Power.Drives) != len(v.Body.Wheelsets) {
+			t.Fatalf("sample %d has %d drives", i, len(samples[i].Power.Drives))
+		}
+		regen += samples[i].Regen() * 0.01
+		friction += samples[i].Power.FrictionBrakes * 0.01
+	}
+	if regen <= 0 || friction <= 0 {
+		t.Fatalf("a hard stop split %.0f J regen and %.0f J friction", regen, friction)
+	}
+	//the rest of the kinetic energy goes to drag
+	kinetic := 0.5 * v.Body.Mass() * stop.Speed * stop.Speed
+	if regen + friction > kinetic || regen + friction < 0.9 * kinetic {
+		t.Fatalf("regen and friction took %.0f J of %.0f J kinetic energy", regen + friction, kinetic)
+	}
+	if plot := stop.PlotData(); len(plot.Series) != 2 || len(plot.Series[0].Y) != len(samples) {
+		t.Fatalf("plot has %d series for %d samples", len(plot.Series), len(samples))
+	}
+}
@@ -0,0 +1,180 @@
+package automotiveSim
+
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+//CompareOptions sets up the simulations Compare runs
+type CompareOptions struct {
+	Names []string //optional, one per vehicle. Defaults to "Vehicle 1", "Vehicle 2"...
+	Cycle *Schedule //for cycle energy and range, which are left out without one
+	GradeSpeed float64 //m/s the steepest grade is found at, zero for a hill start
+}
+
+//ComparisonMetric is one result for every vehicle compared. A value is NaN where its
+//simulation failed, with the reason in Errs, or where it was never reached (0-100km/h)
+type ComparisonMetric struct {
+	Name string
+	Unit string
+	HigherIsBetter bool
+	Values []float64
+	Deltas []float64 //against the first vehicle
+	Relative []float64 //Deltas as a fraction of the first vehicle's value
+	Errs []error
+	Best int //index of the best vehicle, -1 if none has a value
+}
+
+//Comparison is the result of Compare
+type Comparison struct {
+	Names []string
+	Metrics []ComparisonMetric
+}
+
+//comparedMetric is one row of the comparison and how to measure it
+type comparedMetric struct {
+	name string
+	unit string
+	higherIsBetter bool
+	fromProfile bool //taken from the acceleration profile, so fails with it
+	measure func(v *Vehicle, profile *AccelProfile) (float64, error)
+}
+
+func (opts *CompareOptions)metrics() []comparedMetric {
+	metrics := []comparedMetric{
+		{"0-100 km/h", "s", false, true, func(v *Vehicle, p *AccelProfile) (float64, error) {
+			return p.Accel100, nil
+		}},
+		{"Quarter mile", "s", false, true, func(v *Vehicle, p *AccelProfile) (float64, error) {
+			return p.QuarterMile, nil
+		}},
+		{"Top speed", "m/s", true, true, func(v *Vehicle, p *AccelProfile) (float64, error) {
+			return p.TopSpeed, nil
+		}},
+		{"Max grade", "rise/run", true, false, func(v *Vehicle, p *AccelProfile) (float64, error) {
+			g, err := v.MaxGrade(opts.GradeSpeed)
+			return g.Grade, err
+		}},
+	}
+	if opts.Cycle != nil {
+		metrics = append(metrics, comparedMetric{"Cycle consumption", "J/m", false, false, func(v *Vehicle, p *AccelProfile) (float64, error) {
+			return v.cycleConsumption(opts.Cycle)
+		}}, comparedMetric{"Range", "km", true, false, func(v *Vehicle, p *AccelProfile) (float64, error) {
+			r, err := v.RangeOnCycle(opts.Cycle)
+			return r.Range, err
+		}})
+	}
+	return metrics
+}
+
+//Compare runs the acceleration profile, top speed, gradeability and, given a cycle, cycle
+//consumption and range on every vehicle, one vehicle per CPU. The first vehicle is the
+//baseline the others are compared against. A failed simulation only leaves its own value out
+func Compare(vehicles []*Vehicle, opts CompareOptions) (Comparison, error) {
+	if len(vehicles) == 0 {
+		return Comparison{}, fmt.Errorf("Compare requires at least one vehicle")
+	}
+	if len(opts.Names) != 0 && len(opts.Names) != len(vehicles) {
+		return Comparison{}, fmt.Errorf("Compare requires one name per vehicle")
+	}
+	if opts.GradeSpeed < 0 {
+		return Comparison{}, fmt.Errorf("Grade speed must not be negative")
+	}
+	result := Comparison{Names: opts.Names}
+	if len(result.Names) == 0 {
+		for i := range vehicles {
+			result.Names = append(result.Names, "Vehicle " + strconv.Itoa(i + 1))
+		}
+	}
+	metrics := opts.metrics()
+	result.Metrics = make([]ComparisonMetric, len(metrics))
+	for i,m := range metrics {
+		result.Metrics[i] = ComparisonMetric{
+			Name: m.name,
+			Unit: m.unit,
+			HigherIsBetter: m.higherIsBetter,
+			Values: make([]float64, len(vehicles)),
+			Deltas: make([]float64, len(vehicles)),
+			Relative: make([]float64, len(vehicles)),
+			Errs: make([]error, len(vehicles)),
+		}
+	}
+
+	parallel(len(vehicles), func(j int) {
+		v := vehicles[j]
+		profile, profileErr := v.RunAccelerationProfile()
+		for i,m := range metrics {
+			value, err := math.NaN(), profileErr
+			if !m.fromProfile || err == nil {
+				value, err = m.measure(v, &profile)
+			}
+			if err != nil {
+				value = math.NaN()
+			}
+			result.Metrics[i].Values[j], result.Metrics[i].Errs[j] = value, err
+		}
+	})
+
+	for i := range result.Metrics {
+		m := &result.Metrics[i]
+		m.Best = -1
+		for j,value := range m.Values {
+			m.Deltas[j] = value - m.Values[0]
+			m.Relative[j] = m.Deltas[j] / m.Values[0]
+			if math.IsNaN(value) {
+				continue
+			}
+			if m.Best < 0 || (m.HigherIsBetter && value > m.Values[m.Best]) || (!m.HigherIsBetter && value < m.Values[m.Best]) {
+				m.Best = j
+			}
+		}
+	}
+	return result, nil
+}
+
+//WriteCSV writes one row per metric, with a value column and a delta column per vehicle
+func (c Comparison)WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := []string{"Metric", "Unit"}
+	for _,name := range c.Names {
+		header = append(header, name)
+	}
+	for _,name := range c.Names[1:] {
+		header = append(header, name + " delta")
+	}
+	header = append(header, "Best")
+	err := out.Write(header)
+	if err != nil {
+		return err
+	}
+	format := func(x float64) string {
+		if math.IsNaN(x) {
+			return ""
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	for _,m := range c.Metrics {
+		row := []string{m.Name, m.Unit}
+		for _,value := range m.Values {
+			row = append(row, format(value))
+		}
+		for _,delta := range m.Deltas[1:] {
+			row = append(row, format(delta))
+		}
+		best := ""
+		if m.Best >= 0 {
+			best = c.Names[m.Best]
+		}
+		row = append(row, best)
+		err = out.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
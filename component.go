@@ -1,7 +0,0 @@
-package automotiveSim
-
-
-type Component struct {
-    Power Power
-}
-
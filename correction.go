@@ -0,0 +1,51 @@
+package automotiveSim
+
+
+import (
+)
+
+//CorrectionFactors scale simulated lab-cycle consumption to a real-world estimate.
+//Override them per market where better data is available
+type CorrectionFactors struct {
+	City float64 //consumption multiplier for city cycles
+	Highway float64 //consumption multiplier for highway cycles
+	ReferenceTemperature float64 //kelvin, no temperature adjustment at this ambient
+	ColdCoefficient float64 //fractional consumption increase per kelvin below reference
+	HotCoefficient float64 //fractional consumption increase per kelvin above reference
+}
+
+//DefaultCorrection uses the EPA's derived 0.7 label adjustment on both cycles (1/0.7),
+//referenced to a 20C lab, with roughly 1%/K extra consumption in the cold (cabin and
+//battery heating) and 0.5%/K in the heat (air conditioning)
+var DefaultCorrection = CorrectionFactors{
+	City: 1/0.7,
+	Highway: 1/0.7,
+	ReferenceTemperature: 293.15,
+	ColdCoefficient: 0.01,
+	HotCoefficient: 0.005,
+}
+
+//Apply corrects a lab consumption figure (any unit) for the cycle type and ambient temperature (K)
+func (c CorrectionFactors)Apply(consumption float64, highway bool, temperature float64) float64 {
+	factor := c.City
+	if highway {
+		factor = c.Highway
+	}
+	
+	if temperature < c.ReferenceTemperature {
+		factor *= 1 + c.ColdCoefficient * (c.ReferenceTemperature - temperature)
+	} else {
+		factor *= 1 + c.HotCoefficient * (temperature - c.ReferenceTemperature)
+	}
+	return consumption * factor
+}
+
+//RealWorldConsumption returns the corrected consumption (J/m) for the cycle at the
+//vehicle's ambient temperature
+func (vehicle *Vehicle)RealWorldConsumption(cycle *Schedule, highway bool, c CorrectionFactors) (float64, error) {
+	consumption, err := vehicle.cycleConsumption(cycle)
+	if err != nil {
+		return 0, err
+	}
+	return c.Apply(consumption, highway, vehicle.Ambient.Temperature), nil
+}
@@ -0,0 +1,156 @@
+package automotiveSim
+
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	cruiseAccel = 1.0 //m/s^2, the most cruise control asks for getting back up to speed
+	cruiseDecel = 1.0 //m/s^2, the most it brakes to hold speed downhill
+	defaultCruiseTolerance = 0.5 //m/s
+)
+
+//CruiseSegment is one stretch of road under cruise control
+type CruiseSegment struct {
+	Distance float64 //m
+	Grade float64 //rise/run
+	Headwind float64 //m/s, negative for a tailwind
+	Crosswind float64 //m/s
+}
+
+//Cruise holds a set speed over a grade and wind profile, e.g. to see whether a vehicle
+//towing a trailer can keep highway speed up a long climb
+type Cruise struct {
+	SetSpeed float64 //m/s, the vehicle starts at it
+	Segments []CruiseSegment
+	Tolerance float64 //m/s below SetSpeed still counted as holding it, defaults to 0.5
+}
+
+func (c *Cruise)Init() error {
+	if c.SetSpeed <= 0 {
+		return fmt.Errorf("Cruise set speed must be positive")
+	}
+	if len(c.Segments) == 0 {
+		return fmt.Errorf("Cruise requires at least one segment")
+	}
+	for i,s := range c.Segments {
+		if s.Distance <= 0 {
+			return fmt.Errorf("Cruise segment %d must have a positive distance", i)
+		}
+	}
+	if c.Tolerance < 0 {
+		return fmt.Errorf("Cruise tolerance must not be negative")
+	}
+	if c.Tolerance == 0 {
+		c.Tolerance = defaultCruiseTolerance
+	}
+	return nil
+}
+
+//CruiseSegmentResult is one segment of a cruise
+type CruiseSegmentResult struct {
+	Duration time.Duration
+	MeanDemand float64 //W at the wheels to hold the speed driven, against drag, rolling resistance and grade
+	PeakDemand float64
+	Energy float64 //J drawn from the battery
+	Fuel float64 //liters
+	MinSpeed float64 //m/s
+	Held bool //never fell more than Tolerance below SetSpeed
+}
+
+//CruiseShortfall is a stretch where the vehicle couldn't hold the set speed
+type CruiseShortfall struct {
+	Start float64 //m from the start of the profile
+	End float64
+	MinSpeed float64 //m/s
+	Reason LimitReason //what held the vehicle back as it fell below the set speed
+}
+
+//CruiseResult is a run under cruise control
+type CruiseResult struct {
+	Duration time.Duration
+	Distance float64
+	Energy float64 //J drawn from the battery
+	Fuel float64 //liters
+	PeakDemand float64 //W at the wheels
+	Segments []CruiseSegmentResult
+	Shortfalls []CruiseShortfall //empty if the set speed was held throughout
+	EndSOC float64
+}
+
+//RunCruise drives a copy of the vehicle over c's profile, cruise control holding the set
+//speed as well as the powertrain allows
+func (vehicle *Vehicle)RunCruise(c Cruise) (CruiseResult, error) {
+	err := c.Init()
+	if err != nil {
+		return CruiseResult{}, err
+	}
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return CruiseResult{}, err
+	}
+	v := sim.Vehicle
+	b := &v.Body
+	startEnergy := v.Battery.EnergyUsed()
+	interval := sim.Interval.Seconds()
+	sim.Speed = c.SetSpeed
+
+	var result CruiseResult
+	var shortfall *CruiseShortfall
+	for i,segment := range c.Segments {
+		sim.Grade, sim.Headwind, sim.Crosswind = segment.Grade, segment.Headwind, segment.Crosswind
+		s := CruiseSegmentResult{MinSpeed: sim.Speed, Held: true}
+		start, startTime := sim.Distance, sim.Time
+		energy, fuel := v.Battery.EnergyUsed(), sim.fuelUsed()
+		demand := 0.0
+		for sim.Distance - start < segment.Distance {
+			//what the road takes at this speed, before the tick changes it
+			load := (b.AeroDrag(sim) + b.RollingDrag(sim) + b.GradeForce(sim)) * sim.Speed
+			demand += load * interval
+			s.PeakDemand = math.Max(s.PeakDemand, load)
+
+			target := math.Max(-cruiseDecel, math.Min(cruiseAccel, (c.SetSpeed - sim.Speed)/interval))
+			before := sim.Time
+			_, err := sim.Tick(target)
+			if errors.Is(err, errDepleted) || sim.Time == before {
+				return result, fmt.Errorf("Vehicle stopped %.0fm into cruise segment %d: %w", sim.Distance - start, i, err)
+			}
+			if sim.Speed <= 0 {
+				return result, fmt.Errorf("Vehicle stalled %.0fm into cruise segment %d: %v", sim.Distance - start, i, err)
+			}
+			s.MinSpeed = math.Min(s.MinSpeed, sim.Speed)
+
+			if sim.Speed < c.SetSpeed - c.Tolerance {
+				s.Held = false
+				if shortfall == nil {
+					shortfall = &CruiseShortfall{Start: sim.Distance, MinSpeed: sim.Speed, Reason: asLimit(err)}
+				}
+				shortfall.MinSpeed = math.Min(shortfall.MinSpeed, sim.Speed)
+			} else if shortfall != nil {
+				shortfall.End = sim.Distance
+				result.Shortfalls = append(result.Shortfalls, *shortfall)
+				shortfall = nil
+			}
+		}
+		s.Duration = sim.Time - startTime
+		s.MeanDemand = demand / s.Duration.Seconds()
+		s.Energy = v.Battery.EnergyUsed() - energy
+		s.Fuel = sim.fuelUsed() - fuel
+		result.PeakDemand = math.Max(result.PeakDemand, s.PeakDemand)
+		result.Segments = append(result.Segments, s)
+	}
+	if shortfall != nil {
+		shortfall.End = sim.Distance
+		result.Shortfalls = append(result.Shortfalls, *shortfall)
+	}
+	result.Duration = sim.Time
+	result.Distance = sim.Distance
+	result.Energy = v.Battery.EnergyUsed() - startEnergy
+	result.Fuel = sim.fuelUsed()
+	result.EndSOC = v.Battery.StateOfCharge()
+	return result, nil
+}
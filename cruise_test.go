@@ -0,0 +1,45 @@
+package automotiveSim
+
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCruiseCountsTrailerDragOnce(t *testing.T) {
+	cruise := Cruise{SetSpeed: 25, Segments: []CruiseSegment{{Distance: 2000}}}
+	trailer := &Trailer{Mass: 1000, RollingResistance: 0.02}
+	demand := func(load LoadConfig) float64 {
+		v, err := testVehicle(t).WithLoad(load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := v.RunCruise(cruise)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Segments[0].MeanDemand
+	}
+
+	got := demand(LoadConfig{Trailer: trailer}) - demand(LoadConfig{})
+	want := trailer.Mass * gravity * trailer.RollingResistance * cruise.SetSpeed
+	if math.Abs(got - want) > want*0.01 {
+		t.Fatalf("trailer added %.0f W of demand, want %.0f W", got, want)
+	}
+}
+
+func TestCruiseReportsDepletion(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.Coulomb = 2000
+	v.Battery.MinSOC = 0.1
+	err := v.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.RunCruise(Cruise{SetSpeed: 25, Segments: []CruiseSegment{{Distance: 50000}}})
+	if !errors.Is(err, errDepleted) {
+		t.Fatalf("got %v, want the pack depleted", err)
+	}
+}
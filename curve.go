@@ -0,0 +1,115 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+type CurvePoint struct {
+	X float64
+	Y float64
+}
+
+//Curve is a piecewise linear lookup table, sorted by X
+type Curve []CurvePoint
+
+func (c Curve)Init() error {
+	for i := 1; i < len(c); i++ {
+		if c[i].X <= c[i-1].X {
+			return fmt.Errorf("Curve points must be in increasing order")
+		}
+	}
+	return nil
+}
+
+//Slope returns the gradient of the segment containing x, zero beyond the end points
+func (c Curve)Slope(x float64) float64 {
+	for i := 1; i < len(c); i++ {
+		if x >= c[i-1].X && x < c[i].X {
+			return (c[i].Y - c[i-1].Y)/(c[i].X - c[i-1].X)
+		}
+	}
+	return 0
+}
+
+//At interpolates the curve at x, clamping to the end points
+func (c Curve)At(x float64) float64 {
+	if len(c) == 0 {
+		return 0
+	}
+	if x <= c[0].X {
+		return c[0].Y
+	}
+	for i := 1; i < len(c); i++ {
+		if x <= c[i].X {
+			frac := (x - c[i-1].X)/(c[i].X - c[i-1].X)
+			return c[i-1].Y + frac * (c[i].Y - c[i-1].Y)
+		}
+	}
+	return c[len(c)-1].Y
+}
+
+//Map is a two dimensional lookup table, Z[i][j] being the value at X[i], Y[j].
+//It is bilinearly interpolated and clamped at the edges
+type Map struct {
+	X []float64
+	Y []float64
+	Z [][]float64
+}
+
+func (m *Map)Init() error {
+	if len(m.X) == 0 || len(m.Y) == 0 {
+		return fmt.Errorf("Map requires at least one point on each axis")
+	}
+	for i := 1; i < len(m.X); i++ {
+		if m.X[i] <= m.X[i-1] {
+			return fmt.Errorf("Map X must be in increasing order")
+		}
+	}
+	for j := 1; j < len(m.Y); j++ {
+		if m.Y[j] <= m.Y[j-1] {
+			return fmt.Errorf("Map Y must be in increasing order")
+		}
+	}
+	if len(m.Z) != len(m.X) {
+		return fmt.Errorf("Map requires one row of Z per X")
+	}
+	for _,row := range m.Z {
+		if len(row) != len(m.Y) {
+			return fmt.Errorf("Map requires one Z per Y in every row")
+		}
+	}
+	return nil
+}
+
+//mapIndex finds the cell containing v along axis and how far across it v is
+func mapIndex(axis []float64, v float64) (int, float64) {
+	if len(axis) == 1 || v <= axis[0] {
+		return 0, 0
+	}
+	for i := 1; i < len(axis); i++ {
+		if v <= axis[i] {
+			return i-1, (v - axis[i-1])/(axis[i] - axis[i-1])
+		}
+	}
+	return len(axis)-2, 1
+}
+
+//At interpolates the map at (x, y)
+func (m *Map)At(x, y float64) float64 {
+	if len(m.X) == 0 || len(m.Y) == 0 {
+		return 0
+	}
+	i, fx := mapIndex(m.X, x)
+	j, fy := mapIndex(m.Y, y)
+	i2, j2 := i, j
+	if len(m.X) > 1 {
+		i2 = i + 1
+	}
+	if len(m.Y) > 1 {
+		j2 = j + 1
+	}
+	low := m.Z[i][j] + fy * (m.Z[i][j2] - m.Z[i][j])
+	high := m.Z[i2][j] + fy * (m.Z[i2][j2] - m.Z[i2][j])
+	return low + fx * (high - low)
+}
@@ -0,0 +1,102 @@
+//Package cycles builds standard drive cycles as automotiveSim schedules
+package cycles
+
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	
+	"github.com/evantandersen/automotiveSim"
+)
+
+const (
+	kph = 1 / 3.6
+)
+
+//Segment ramps linearly from the previous segment's speed to Speed (km/h) over Duration seconds.
+//Modal cycles such as the NEDC are defined this way
+type Segment struct {
+	Duration int
+	Speed float64
+}
+
+//FromSegments builds a 1Hz schedule from a standstill through the given segments
+func FromSegments(name string, segments []Segment) *automotiveSim.Schedule {
+	speeds := []float64{0}
+	for _,s := range segments {
+		start := speeds[len(speeds)-1]
+		end := s.Speed * kph
+		for t := 1; t <= s.Duration; t++ {
+			speeds = append(speeds, start + (end - start) * float64(t) / float64(s.Duration))
+		}
+	}
+	return &automotiveSim.Schedule{Name: name, Interval: time.Second, Speeds: speeds}
+}
+
+//FromTrace builds a schedule from a speed trace in km/h, one sample per interval. The
+//official second-by-second traces (UDDS, HWFET, US06, SC03, WLTC, JC08...) are read from
+//their published files by LoadTrace
+func FromTrace(name string, interval time.Duration, speedsKph []float64) *automotiveSim.Schedule {
+	speeds := make([]float64, len(speedsKph))
+	for i,s := range speedsKph {
+		speeds[i] = s * kph
+	}
+	return &automotiveSim.Schedule{Name: name, Interval: interval, Speeds: speeds}
+}
+
+//elementary urban cycle (ECE-15), 195s, UNECE R83 Annex 4
+var ece15 = []Segment{
+	{11, 0},
+	{4, 15}, {8, 15}, {2, 10}, {3, 0},
+	{21, 0},
+	{5, 15}, {2, 15}, {5, 32}, {24, 32}, {8, 10}, {3, 0},
+	{21, 0},
+	{5, 15}, {2, 15}, {9, 35}, {2, 35}, {8, 50}, {12, 50}, {8, 35}, {13, 35}, {2, 32}, {7, 10}, {3, 0},
+	{7, 0},
+}
+
+//extra-urban driving cycle, 400s, UNECE R83 Annex 4
+var eudc = []Segment{
+	{20, 0},
+	{5, 15}, {2, 15}, {9, 35}, {2, 35}, {8, 50}, {2, 50}, {13, 70},
+	{50, 70}, {8, 50}, {69, 50}, {13, 70}, {50, 70},
+	{35, 100}, {30, 100}, {20, 120}, {10, 120},
+	{16, 80}, {8, 50}, {10, 0},
+	{20, 0},
+}
+
+//ECE15 is a single elementary urban cycle
+func ECE15() *automotiveSim.Schedule {
+	return FromSegments("ECE-15", ece15)
+}
+
+//EUDC is the extra-urban driving cycle
+func EUDC() *automotiveSim.Schedule {
+	return FromSegments("EUDC", eudc)
+}
+
+//NEDC is four urban cycles followed by the extra-urban cycle, 1180s
+func NEDC() *automotiveSim.Schedule {
+	var segments []Segment
+	for i := 0; i < 4; i++ {
+		segments = append(segments, ece15...)
+	}
+	segments = append(segments, eudc...)
+	return FromSegments("NEDC", segments)
+}
+
+var byName = map[string]func() *automotiveSim.Schedule{
+	"nedc": NEDC,
+	"ece15": ECE15,
+	"eudc": EUDC,
+}
+
+//ByName builds a standard cycle from its name, ignoring case: nedc, ece15 or eudc
+func ByName(name string) (*automotiveSim.Schedule, error) {
+	build, ok := byName[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("Unknown cycle %q", name)
+	}
+	return build(), nil
+}
@@ -0,0 +1,134 @@
+package cycles
+
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const srtmVoid = -32768 //no data, over water or in radar shadow
+
+//ElevationProvider looks up the ground elevation (m above sea level) at a point, so a
+//route can be given grades without the trace recording them. See LoadGPSWithElevation
+type ElevationProvider interface {
+	Elevation(lat, lon float64) (float64, error)
+}
+
+//SRTM is an ElevationProvider reading Shuttle Radar Topography Mission .hgt tiles from Dir,
+//named for the south-west corner of the one degree square each covers (N37W123.hgt).
+//Both 3 arc-second (1201x1201) and 1 arc-second (3601x3601) tiles work. Tiles are read on
+//first use and kept
+type SRTM struct {
+	Dir string
+
+	//state
+	mu sync.Mutex
+	tiles map[string]*srtmTile
+}
+
+//srtmTile is one tile's samples, north row first
+type srtmTile struct {
+	size int
+	samples []int16
+}
+
+func NewSRTM(dir string) *SRTM {
+	return &SRTM{Dir: dir, tiles: make(map[string]*srtmTile)}
+}
+
+//tileName is the .hgt file covering the point
+func tileName(lat, lon float64) string {
+	ns, ew := 'N', 'E'
+	south, west := int(math.Floor(lat)), int(math.Floor(lon))
+	if south < 0 {
+		ns, south = 'S', -south
+	}
+	if west < 0 {
+		ew, west = 'W', -west
+	}
+	return fmt.Sprintf("%c%02d%c%03d.hgt", ns, south, ew, west)
+}
+
+func (s *SRTM)tile(name string) (*srtmTile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tiles == nil {
+		s.tiles = make(map[string]*srtmTile)
+	}
+	if t,ok := s.tiles[name]; ok {
+		return t, nil
+	}
+	file, err := os.Open(filepath.Join(s.Dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("SRTM: %v", err)
+	}
+	defer file.Close()
+	t, err := readSRTMTile(file)
+	if err != nil {
+		return nil, fmt.Errorf("SRTM %s: %v", name, err)
+	}
+	s.tiles[name] = t
+	return t, nil
+}
+
+//readSRTMTile reads a square of big-endian 16 bit samples, its size from the length
+func readSRTMTile(r io.Reader) (*srtmTile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	size := int(math.Round(math.Sqrt(float64(len(data) / 2))))
+	if size < 2 || size*size*2 != len(data) {
+		return nil, fmt.Errorf("not a square tile of 16 bit samples")
+	}
+	t := &srtmTile{size: size, samples: make([]int16, size*size)}
+	for i := range t.samples {
+		t.samples[i] = int16(binary.BigEndian.Uint16(data[2*i:]))
+	}
+	return t, nil
+}
+
+//Elevation interpolates between the four samples around the point. Void samples are left
+//out, and only a point surrounded by voids has no elevation
+func (s *SRTM)Elevation(lat, lon float64) (float64, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, fmt.Errorf("Latitude %g, longitude %g is not on the earth", lat, lon)
+	}
+	t, err := s.tile(tileName(lat, lon))
+	if err != nil {
+		return 0, err
+	}
+	last := float64(t.size - 1)
+	//rows run north to south, columns west to east
+	y := (math.Ceil(lat) - lat) * last
+	x := (lon - math.Floor(lon)) * last
+	if lat == math.Ceil(lat) {
+		y = last
+	}
+	row, col := math.Min(math.Floor(y), last - 1), math.Min(math.Floor(x), last - 1)
+	fy, fx := y - row, x - col
+
+	total, weights := 0.0, 0.0
+	for _,c := range [4][3]float64{
+		{row, col, (1 - fy) * (1 - fx)},
+		{row, col + 1, (1 - fy) * fx},
+		{row + 1, col, fy * (1 - fx)},
+		{row + 1, col + 1, fy * fx},
+	} {
+		sample := t.samples[int(c[0])*t.size + int(c[1])]
+		if sample == srtmVoid {
+			continue
+		}
+		total += float64(sample) * c[2]
+		weights += c[2]
+	}
+	if weights == 0 {
+		return 0, fmt.Errorf("SRTM has no elevation at %g, %g", lat, lon)
+	}
+	return total / weights, nil
+}
@@ -0,0 +1,230 @@
+package cycles
+
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evantandersen/automotiveSim"
+)
+
+const (
+	earthRadius = 6371000 //meters, mean
+	elevationSpacing = 10 //meters of travel between elevation points, GPS altitude is noisy
+)
+
+//gpsPoint is one fix from a logged trace
+type gpsPoint struct {
+	Lat float64
+	Lon float64
+	Ele float64
+	HasEle bool
+	Time time.Time
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Lat float64 `xml:"lat,attr"`
+				Lon float64 `xml:"lon,attr"`
+				Ele *float64 `xml:"ele"`
+				Time string `xml:"time"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+//LoadGPS reads a .gpx or .csv trace, see FromGPX and FromGPSCSV
+func LoadGPS(path string) (*automotiveSim.Schedule, error) {
+	return LoadGPSWithElevation(path, nil)
+}
+
+//LoadGPSWithElevation is LoadGPS with the elevation profile looked up from provider, such
+//as SRTM, in place of any the trace recorded. A nil provider keeps the recorded elevations
+func LoadGPSWithElevation(path string, provider ElevationProvider) (*automotiveSim.Schedule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var points []gpsPoint
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpx":
+		points, err = readGPX(file)
+	case ".csv":
+		points, err = readGPSCSV(file)
+	default:
+		return nil, fmt.Errorf("%s: unknown trace format, expected .gpx or .csv", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = addElevation(points, provider)
+	if err != nil {
+		return nil, err
+	}
+	return fromGPS(name, points)
+}
+
+//addElevation replaces each point's elevation with provider's, GPS altitude being the
+//least accurate part of a fix
+func addElevation(points []gpsPoint, provider ElevationProvider) error {
+	if provider == nil {
+		return nil
+	}
+	for i := range points {
+		ele, err := provider.Elevation(points[i].Lat, points[i].Lon)
+		if err != nil {
+			return fmt.Errorf("Trace point %d: %v", i+1, err)
+		}
+		points[i].Ele, points[i].HasEle = ele, true
+	}
+	return nil
+}
+
+//FromGPX builds a schedule from every track point in a GPX file, in order. Points need
+//a time; elevations, where present, become the schedule's elevation profile
+func FromGPX(name string, r io.Reader) (*automotiveSim.Schedule, error) {
+	points, err := readGPX(r)
+	if err != nil {
+		return nil, err
+	}
+	return fromGPS(name, points)
+}
+
+func readGPX(r io.Reader) ([]gpsPoint, error) {
+	var gpx gpxFile
+	err := xml.NewDecoder(r).Decode(&gpx)
+	if err != nil {
+		return nil, fmt.Errorf("GPX: %v", err)
+	}
+	var points []gpsPoint
+	for _,track := range gpx.Tracks {
+		for _,segment := range track.Segments {
+			for _,p := range segment.Points {
+				t, err := time.Parse(time.RFC3339, strings.TrimSpace(p.Time))
+				if err != nil {
+					return nil, fmt.Errorf("GPX: track point without a valid time: %v", err)
+				}
+				point := gpsPoint{Lat: p.Lat, Lon: p.Lon, Time: t}
+				if p.Ele != nil {
+					point.Ele, point.HasEle = *p.Ele, true
+				}
+				points = append(points, point)
+			}
+		}
+	}
+	return points, nil
+}
+
+//FromGPSCSV builds a schedule from a CSV trace with a header row naming lat, lon and time
+//columns, and optionally ele. Times are RFC3339 or seconds from the start
+func FromGPSCSV(name string, r io.Reader) (*automotiveSim.Schedule, error) {
+	points, err := readGPSCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return fromGPS(name, points)
+}
+
+func readGPSCSV(r io.Reader) ([]gpsPoint, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV: no header row")
+	}
+	column := map[string]int{"lat": -1, "lon": -1, "time": -1, "ele": -1}
+	for i,heading := range rows[0] {
+		heading = strings.ToLower(strings.TrimSpace(heading))
+		if _,ok := column[heading]; ok {
+			column[heading] = i
+		}
+	}
+	if column["lat"] < 0 || column["lon"] < 0 || column["time"] < 0 {
+		return nil, fmt.Errorf("CSV: header must name lat, lon and time columns")
+	}
+
+	var points []gpsPoint
+	for n,row := range rows[1:] {
+		var p gpsPoint
+		p.Lat, err = strconv.ParseFloat(strings.TrimSpace(row[column["lat"]]), 64)
+		if err == nil {
+			p.Lon, err = strconv.ParseFloat(strings.TrimSpace(row[column["lon"]]), 64)
+		}
+		if err == nil {
+			p.Time, err = parseTraceTime(strings.TrimSpace(row[column["time"]]))
+		}
+		if err == nil && column["ele"] >= 0 && strings.TrimSpace(row[column["ele"]]) != "" {
+			p.Ele, err = strconv.ParseFloat(strings.TrimSpace(row[column["ele"]]), 64)
+			p.HasEle = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV: row %d: %v", n+2, err)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+//parseTraceTime takes an RFC3339 timestamp or seconds from the start of the trace
+func parseTraceTime(s string) (time.Time, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second))), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+//distance is the great circle distance between two fixes in meters
+func distance(a, b gpsPoint) float64 {
+	toRad := math.Pi / 180
+	dLat := (b.Lat - a.Lat) * toRad
+	dLon := (b.Lon - a.Lon) * toRad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(a.Lat*toRad)*math.Cos(b.Lat*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+//fromGPS turns fixes into a schedule with the speed between each pair of fixes, the time
+//of each one, and an elevation profile by distance travelled
+func fromGPS(name string, points []gpsPoint) (*automotiveSim.Schedule, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("Trace needs at least two points")
+	}
+	schedule := &automotiveSim.Schedule{Name: name, Speeds: []float64{0}, Times: []time.Duration{0}}
+	start := points[0].Time
+	travelled := 0.0
+	lastElevation := math.Inf(-1)
+	for i,p := range points {
+		if i > 0 {
+			dt := p.Time.Sub(points[i-1].Time)
+			if dt <= 0 {
+				return nil, fmt.Errorf("Trace point %d is not after the one before it", i+1)
+			}
+			step := distance(points[i-1], p)
+			travelled += step
+			schedule.Speeds = append(schedule.Speeds, step / dt.Seconds())
+			schedule.Times = append(schedule.Times, p.Time.Sub(start))
+		}
+		if p.HasEle && travelled - lastElevation >= elevationSpacing {
+			schedule.Elevation = append(schedule.Elevation, automotiveSim.CurvePoint{X: travelled, Y: p.Ele})
+			lastElevation = travelled
+		}
+	}
+	if len(schedule.Elevation) < 2 {
+		schedule.Elevation = nil
+	}
+	return schedule, nil
+}
@@ -0,0 +1,93 @@
+package cycles
+
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evantandersen/automotiveSim"
+)
+
+//traceUnits are the speed units a published trace's header may name, in m/s
+var traceUnits = []struct {
+	name string
+	scale float64
+}{
+	{"km/h", kph}, {"kph", kph}, {"kmh", kph}, {"mph", 0.44704}, {"m/s", 1},
+}
+
+//LoadTrace reads a published second-by-second speed trace, see FromSpeedTrace
+func LoadTrace(path string) (*automotiveSim.Schedule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return FromSpeedTrace(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), file)
+}
+
+//FromSpeedTrace builds a schedule from a speed trace as the regulators publish them, such
+//as EPA's uddscol.txt, hwycol.txt, us06col.txt and sc03col.txt, or the WLTC and JC08
+//tables: header lines naming the speed unit (mph, km/h or m/s), then one time (s) and
+//speed per line, separated by tabs, spaces or commas
+func FromSpeedTrace(name string, r io.Reader) (*automotiveSim.Schedule, error) {
+	scale := 0.0
+	var times []time.Duration
+	var speeds []float64
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.FieldsFunc(scanner.Text(), func(c rune) bool {
+			return c == ',' || c == ';' || c == ' ' || c == '\t'
+		})
+		if len(fields) < 2 {
+			continue
+		}
+		t, errT := strconv.ParseFloat(fields[0], 64)
+		v, errV := strconv.ParseFloat(fields[1], 64)
+		if errT != nil || errV != nil {
+			if len(speeds) != 0 {
+				return nil, fmt.Errorf("Trace line %d: expected a time and speed", line)
+			}
+			header := strings.ToLower(scanner.Text())
+			for _,u := range traceUnits {
+				if scale == 0 && strings.Contains(header, u.name) {
+					scale = u.scale
+				}
+			}
+			continue
+		}
+		if scale == 0 {
+			return nil, fmt.Errorf("Trace header must name the speed unit, mph, km/h or m/s")
+		}
+		if len(times) != 0 && time.Duration(t * float64(time.Second)) <= times[len(times)-1] {
+			return nil, fmt.Errorf("Trace line %d: times must increase", line)
+		}
+		times = append(times, time.Duration(t * float64(time.Second)))
+		speeds = append(speeds, v * scale)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(speeds) < 2 {
+		return nil, fmt.Errorf("Trace has no speeds")
+	}
+	schedule := &automotiveSim.Schedule{Name: name, Interval: times[1] - times[0], Speeds: speeds}
+	for i := 1; i < len(times); i++ {
+		if times[i] - times[i-1] != schedule.Interval {
+			//unevenly sampled, so every speed keeps its own time from the start
+			start := times[0]
+			for j := range times {
+				times[j] -= start
+			}
+			schedule.Times = times
+			break
+		}
+	}
+	return schedule, nil
+}
@@ -0,0 +1,51 @@
+package cycles
+
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+//epaTrace is the start of a trace in the layout of EPA's published files
+const epaTrace = `EPA Urban Dynamometer Driving Schedule
+Sample Period 1 second
+seconds	mph
+0	0
+1	0
+2	3.0
+3	5.9
+`
+
+func TestFromSpeedTraceEPA(t *testing.T) {
+	s, err := FromSpeedTrace("udds", strings.NewReader(epaTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Interval != time.Second || len(s.Times) != 0 || len(s.Speeds) != 4 {
+		t.Fatalf("got interval %v, %d times and %d speeds", s.Interval, len(s.Times), len(s.Speeds))
+	}
+	if math.Abs(s.Speeds[3] - 5.9 * 0.44704) > 1e-9 {
+		t.Fatalf("5.9 mph read as %.4f m/s", s.Speeds[3])
+	}
+}
+
+func TestFromSpeedTraceUneven(t *testing.T) {
+	s, err := FromSpeedTrace("wltc", strings.NewReader("Time (s),Speed (km/h)\n10,0\n11,3.6\n13,7.2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Times) != 3 || s.Times[0] != 0 || s.Times[2] != 3 * time.Second || s.Speeds[2] != 2 {
+		t.Fatalf("got times %v and speeds %v", s.Times, s.Speeds)
+	}
+}
+
+func TestFromSpeedTraceErrors(t *testing.T) {
+	for _,trace := range []string{"0 0\n1 5\n", "mph\n0 0\n0 5\n", "mph\n0 0\n1 5\nend of trace\n", "mph\n"} {
+		_, err := FromSpeedTrace("bad", strings.NewReader(trace))
+		if err == nil {
+			t.Fatalf("%q read without an error", trace)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cycles
+
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/evantandersen/automotiveSim"
+)
+
+const (
+	defaultStopDuration = 20 //seconds
+	gentleAccel, aggressiveAccel = 0.8, 2.5 //m/s^2
+	gentleDecel, aggressiveDecel = 1.0, 3.0 //m/s^2
+)
+
+//TrafficOptions describes the stop-and-go driving Traffic generates
+type TrafficOptions struct {
+	Distance float64 //km of driving
+	StopsPerKm float64 //on average, stop spacing is random around 1/StopsPerKm
+	CruiseSpeed float64 //km/h, average speed between stops
+	SpeedVariation float64 //optional, standard deviation of each leg's cruise speed as a fraction of CruiseSpeed
+	Aggressiveness float64 //0 for gentle acceleration and braking, up to 1 for aggressive
+	StopDuration float64 //optional, average seconds stopped. Defaults to 20
+	Seed int64 //the same options and seed always give the same schedule
+	Source rand.Source //optional, drawn from in place of Seed
+}
+
+func (o *TrafficOptions)Init() error {
+	if o.Distance <= 0 {
+		return fmt.Errorf("Traffic distance must be positive")
+	}
+	if o.StopsPerKm <= 0 {
+		return fmt.Errorf("Stops per km must be positive")
+	}
+	if o.CruiseSpeed <= 0 {
+		return fmt.Errorf("Cruise speed must be positive")
+	}
+	if o.SpeedVariation < 0 || o.SpeedVariation >= 1 {
+		return fmt.Errorf("Speed variation must be on the range [0,1)")
+	}
+	if o.Aggressiveness < 0 || o.Aggressiveness > 1 {
+		return fmt.Errorf("Aggressiveness must be on the range [0,1]")
+	}
+	if o.StopDuration < 0 {
+		return fmt.Errorf("Stop duration must not be negative")
+	}
+	if o.StopDuration == 0 {
+		o.StopDuration = defaultStopDuration
+	}
+	return nil
+}
+
+//Traffic generates a randomized urban schedule: legs of random length between stops, each
+//accelerating to a random cruise speed and braking to a stop of random duration
+func Traffic(opts TrafficOptions) (*automotiveSim.Schedule, error) {
+	err := opts.Init()
+	if err != nil {
+		return nil, err
+	}
+	source := opts.Source
+	if source == nil {
+		source = rand.NewSource(opts.Seed)
+	}
+	r := rand.New(source)
+	accel := gentleAccel + (aggressiveAccel - gentleAccel) * opts.Aggressiveness
+	decel := gentleDecel + (aggressiveDecel - gentleDecel) * opts.Aggressiveness
+
+	//corners of the speed trace, in seconds and m/s
+	trace := automotiveSim.Curve{{X: 0, Y: 0}}
+	t := 0.0
+	point := func(dt, speed float64) {
+		t += dt
+		trace = append(trace, automotiveSim.CurvePoint{X: t, Y: speed})
+	}
+	point(opts.StopDuration / 2, 0)
+	remaining := opts.Distance * 1000
+	for remaining > 0 {
+		//stops are a Poisson process along the road, so legs are exponentially distributed
+		leg := math.Min(remaining, r.ExpFloat64() * 1000 / opts.StopsPerKm)
+		remaining -= leg
+		cruise := opts.CruiseSpeed * kph * math.Max(0.2, 1 + opts.SpeedVariation * r.NormFloat64())
+
+		//legs too short to reach cruise peak partway
+		peak := math.Min(cruise, math.Sqrt(2 * leg * accel * decel / (accel + decel)))
+		if peak <= 0 {
+			continue
+		}
+		accelDistance := peak * peak / (2 * accel)
+		decelDistance := peak * peak / (2 * decel)
+		point(peak / accel, peak)
+		point((leg - accelDistance - decelDistance) / peak, peak)
+		point(peak / decel, 0)
+		point(r.ExpFloat64() * opts.StopDuration, 0)
+	}
+
+	speeds := make([]float64, int(math.Ceil(t)) + 1)
+	for i := range speeds {
+		speeds[i] = trace.At(float64(i))
+	}
+	name := fmt.Sprintf("Traffic (seed %d)", opts.Seed)
+	if opts.Source != nil {
+		name = "Traffic"
+	}
+	return &automotiveSim.Schedule{Name: name, Interval: time.Second, Speeds: speeds}, nil
+}
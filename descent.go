@@ -0,0 +1,92 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+//DescentSegment is how much of one downhill stretch of a route came back as charge. Ideal
+//regen would recover all of Potential; the friction brakes take what the motors' regen
+//power, a full pack or a hot one won't, and the rest is lost converting it
+type DescentSegment struct {
+	Segment int //index into the route's Distances of the limit the stretch starts at
+	Start float64 //m along the route
+	End float64
+	Drop float64 //m of elevation lost
+	Potential float64 //J the vehicle had to brake away on the way down, regen and friction
+	Regen float64 //J the motors took in at their shafts
+	Recovered float64 //J that reached the battery
+	Friction float64 //J burned in the friction brakes
+	RegenLimited time.Duration //braking on the friction brakes because the pack couldn't take more
+}
+
+//descentDrop is the elevation lost (m) over the route segment from start to end. The
+//elevation profile is keyed by distance travelled, from the start of the route
+func (input *Schedule)descentDrop(segment int, start, end float64) float64 {
+	if len(input.Elevation) != 0 {
+		origin := input.Distances[0]
+		return input.Elevation.At(start - origin) - input.Elevation.At(end - origin)
+	}
+	if len(input.Grades) != 0 {
+		return -input.Grades[segment] * (end - start)
+	}
+	return 0
+}
+
+//DescentRecovery drives the route on a copy of the vehicle and reports, for every segment
+//that loses elevation, the energy that could have been recovered against what was
+func (vehicle *Vehicle)DescentRecovery(route *Schedule) ([]DescentSegment, error) {
+	if len(route.Distances) == 0 {
+		return nil, fmt.Errorf("Descent recovery requires a route")
+	}
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return nil, err
+	}
+	
+	segments := make([]DescentSegment, len(route.Distances))
+	for i := range segments {
+		segments[i].Segment = i
+		segments[i].Start = route.Distances[i]
+		segments[i].End = route.Distances[i]
+		if i + 1 < len(route.Distances) {
+			segments[i].End = route.Distances[i+1]
+		}
+	}
+	origin := route.Distances[0] - sim.Distance
+	position := sim.Distance + origin
+	regen, limited := sim.Energy.Regen, sim.RegenLimitedTime
+	sim.OnTick(func(*TickState) {
+		//the tick counts toward the segment it started in
+		s := &segments[route.segment(position)]
+		dt := sim.Interval.Seconds()
+		for _,d := range sim.Power.Drives {
+			s.Regen -= math.Min(0, d.Mechanical) * dt
+		}
+		s.Friction += sim.Power.FrictionBrakes * dt
+		s.Recovered += sim.Energy.Regen - regen
+		s.RegenLimited += sim.RegenLimitedTime - limited
+		position = sim.Distance + origin
+		regen, limited = sim.Energy.Regen, sim.RegenLimitedTime
+	})
+	_, err = sim.Run(route)
+	if err != nil {
+		return nil, err
+	}
+	
+	var descents []DescentSegment
+	for _,s := range segments {
+		if s.End == s.Start {
+			continue
+		}
+		s.Drop = route.descentDrop(s.Segment, s.Start, s.End)
+		if s.Drop <= 0 {
+			continue
+		}
+		s.Potential = s.Regen + s.Friction
+		descents = append(descents, s)
+	}
+	return descents, nil
+}
@@ -0,0 +1,73 @@
+package automotiveSim
+
+
+import (
+	"testing"
+	"time"
+)
+
+//descentRoute is 1km flat, 3km down a 6% grade and 1km flat again at 20 m/s
+func descentRoute() *Schedule {
+	return &Schedule{Name: "descent", Interval: time.Second,
+		Distances: []float64{0, 1000, 4000, 5000},
+		Speeds: []float64{20, 20, 20, 0},
+		Grades: []float64{0, -0.06, 0, 0}}
+}
+
+func descent(t *testing.T, v *Vehicle) DescentSegment {
+	t.Helper()
+	segments, err := v.DescentRecovery(descentRoute())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || segments[0].Segment != 1 || segments[0].Start != 1000 || segments[0].End != 4000 {
+		t.Fatalf("got descents %+v, want the one from 1000m to 4000m", segments)
+	}
+	return segments[0]
+}
+
+func TestDescentRecovery(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.InitialSOC = 0.5
+	s := descent(t, v)
+	if s.Drop != 180 {
+		t.Fatalf("%.1fm drop, not 180m", s.Drop)
+	}
+	//the grade outweighs the road load at 20 m/s, so most of the lost height has to be braked away
+	height := v.Body.Mass() * gravity * s.Drop
+	if s.Potential < 0.5 * height || s.Potential > height {
+		t.Fatalf("%.0f J to brake away from %.0f J of height", s.Potential, height)
+	}
+	if s.Friction > 0.01 * s.Potential || s.Recovered <= 0.7 * s.Potential || s.Recovered >= s.Regen {
+		t.Fatalf("a half full pack recovered %.0f J of %.0f J, %.0f J at the shafts, %.0f J on the friction brakes",
+			s.Recovered, s.Potential, s.Regen, s.Friction)
+	}
+}
+
+func TestDescentRecoveryFullPack(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.InitialSOC = 0.5
+	half := descent(t, v)
+	v.Battery.InitialSOC = 1
+	full := descent(t, v)
+	//the pack tops up on the way down and the friction brakes take over
+	if full.Recovered >= 0.5 * half.Recovered || full.Friction < 0.5 * full.Potential {
+		t.Fatalf("a full pack recovered %.0f J of %.0f J with %.0f J of friction, half full %.0f J",
+			full.Recovered, full.Potential, full.Friction, half.Recovered)
+	}
+	if full.RegenLimited <= 0 || half.RegenLimited != 0 {
+		t.Fatalf("regen limited for %v with a full pack, %v half full", full.RegenLimited, half.RegenLimited)
+	}
+}
+
+func TestDescentRecoveryRegenPower(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.InitialSOC = 0.5
+	full := descent(t, v)
+	v.Body.Wheelsets[1].Drive.Motor.MaxRegenPower = 5000
+	weak := descent(t, v)
+	if weak.Friction <= full.Friction || weak.Recovered >= full.Recovered {
+		t.Fatalf("5kW of regen recovered %.0f J with %.0f J of friction, unlimited %.0f J with %.0f J",
+			weak.Recovered, weak.Friction, full.Recovered, full.Friction)
+	}
+}
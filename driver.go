@@ -0,0 +1,86 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultSpeedTolerance = 2 * 0.44704 //m/s, the 2mph band drive cycle tests allow
+	defaultDriverKp = 2.0
+	defaultDriverKi = 0.5
+)
+
+//Driver follows the speed trace of a schedule in Run, deciding the acceleration to ask
+//the vehicle for each tick. Init is called at the start of every run
+type Driver interface {
+	Init() error
+	//Accel gets the trace speed at the start of the tick and how fast it is changing
+	Accel(sim *SimulatorState, traceSpeed, traceAccel float64) float64
+}
+
+//PIDDriver feeds forward the trace's own acceleration and corrects the speed error with
+//a PID loop. The integral only builds up within the speed tolerance, so the driver
+//doesn't wind up while the vehicle is at a limit. The default Driver
+type PIDDriver struct {
+	Kp float64 //1/s, defaults to 2
+	Ki float64 //1/s^2, defaults to 0.5
+	Kd float64 //optional
+	MaxAccel float64 //m/s^2, optional
+	MaxDecel float64 //m/s^2, optional
+	MaxJerk float64 //m/s^3, optional
+
+	//state
+	integral float64
+	lastError float64
+	lastAccel float64
+	started bool
+}
+
+func (d *PIDDriver)Init() error {
+	if d.Kp < 0 || d.Ki < 0 || d.Kd < 0 {
+		return fmt.Errorf("Driver gains must not be negative")
+	}
+	if d.MaxAccel < 0 || d.MaxDecel < 0 || d.MaxJerk < 0 {
+		return fmt.Errorf("Driver limits must not be negative")
+	}
+	if d.Kp == 0 && d.Ki == 0 && d.Kd == 0 {
+		d.Kp, d.Ki = defaultDriverKp, defaultDriverKi
+	}
+	d.integral, d.lastError, d.lastAccel, d.started = 0, 0, 0, false
+	return nil
+}
+
+func (d *PIDDriver)Accel(sim *SimulatorState, traceSpeed, traceAccel float64) float64 {
+	interval := sim.Interval.Seconds()
+	e := traceSpeed - sim.Speed
+	if math.Abs(e) < sim.Options.SpeedTolerance {
+		d.integral += e * interval
+	}
+	derivative := 0.0
+	if d.started {
+		derivative = (e - d.lastError) / interval
+	}
+	accel := traceAccel + d.Kp*e + d.Ki*d.integral + d.Kd*derivative
+	if d.MaxAccel > 0 {
+		accel = math.Min(accel, d.MaxAccel)
+	}
+	if d.MaxDecel > 0 {
+		accel = math.Max(accel, -d.MaxDecel)
+	}
+	if d.MaxJerk > 0 && d.started {
+		change := d.MaxJerk * interval
+		accel = math.Max(d.lastAccel - change, math.Min(d.lastAccel + change, accel))
+	}
+	d.lastError, d.lastAccel, d.started = e, accel, true
+	return accel
+}
+
+//TrackingError is how closely a run followed its speed trace
+type TrackingError struct {
+	Max float64 //m/s, the largest difference either way
+	RMS float64 //m/s
+	Outside time.Duration //time spent outside the speed tolerance
+}
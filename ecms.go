@@ -0,0 +1,157 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+//ECMS is the Equivalent Consumption Minimization Strategy. Each tick it picks the engine
+//power with the lowest total of fuel burned and battery energy used, the battery energy
+//weighted by EquivalenceFactor as the fuel it will eventually take to put it back. The
+//factor is raised as the state of charge falls below TargetSOC, which is what keeps the
+//battery charge sustaining. Motor losses are left out of the comparison
+type ECMS struct {
+	EquivalenceFactor float64 //J of fuel per J of battery, defaults to 3
+	TargetSOC float64 //defaults to the middle of the battery's usable window
+	SOCGain float64 //how hard the factor is pushed back toward the target, defaults to 1
+	Steps int //engine power candidates tried each tick, defaults to 20
+
+	//fraction by which starting or stopping the engine has to beat leaving it as it is,
+	//so it doesn't start and stop every tick. Defaults to 0.1
+	Hysteresis float64
+}
+
+func (e ECMS)EnginePower(sim *SimulatorState, h *Hybrid, demand float64) float64 {
+	b := &sim.Vehicle.Battery
+	factor := e.EquivalenceFactor
+	if factor == 0 {
+		factor = 3
+	}
+	target := e.TargetSOC
+	if target == 0 {
+		target = (b.MinSOC + b.MaxSOC)/2
+	}
+	gain := e.SOCGain
+	if gain == 0 {
+		gain = 1
+	}
+	steps := e.Steps
+	if steps <= 0 {
+		steps = 20
+	}
+	hysteresis := e.Hysteresis
+	if hysteresis == 0 {
+		hysteresis = 0.1
+	}
+	speed := h.engineSpeed
+	if speed <= 0 {
+		return 0
+	}
+
+	//a full battery is worth less than an empty one
+	window := math.Max((b.MaxSOC - b.MinSOC)/2, 1e-3)
+	factor *= math.Max(0, 1 - gain * (b.StateOfCharge() - target)/window)
+	conversion := 1.0
+	if h.Mode == Series {
+		conversion = h.GeneratorEfficiency
+	}
+
+	offCost := factor * demand
+	on, onCost := 0.0, math.Inf(1)
+	maxTorque, _ := h.Engine.MaxTorque(sim, speed)
+	for i := 1; i <= steps; i++ {
+		torque := maxTorque * float64(i) / float64(steps)
+		power := torque * speed
+		fuel := h.Engine.fuelRate(speed, torque) / 1000 / fuelDensity[h.Engine.Fuel] * JoulesPerUnit(h.Engine.Fuel)
+		cost := fuel + factor * (demand - power * conversion)
+		if cost < onCost {
+			on, onCost = power, cost
+		}
+	}
+	if h.engineOn {
+		if offCost + hysteresis * math.Abs(onCost) < onCost {
+			return 0
+		}
+		return on
+	}
+	if onCost + hysteresis * math.Abs(offCost) < offCost {
+		return on
+	}
+	return 0
+}
+
+//StrategyRun is where a hybrid's energy came from over one run of a cycle
+type StrategyRun struct {
+	Fuel float64 //liters
+	FuelEnergy float64 //J, chemical energy of the fuel burned
+	BatteryEnergy float64 //J net drawn from the battery, negative if it ended with more charge
+	EndSOC float64
+	EngineStarts int
+	FuelShare float64 //of the energy used, the fraction that came from fuel
+}
+
+//StrategyComparison is the same cycle driven under two energy management strategies
+type StrategyComparison struct {
+	Baseline StrategyRun //the rule-based ChargeSustaining
+	Strategy StrategyRun
+	FuelSaved float64 //liters, negative if the strategy burned more
+}
+
+//CompareEnergyManagement drives cycle with every hybrid drive of the vehicle under the
+//default ChargeSustaining controller and again under strategy, such as ECMS
+func (vehicle *Vehicle)CompareEnergyManagement(cycle *Schedule, strategy EnergyManagement) (StrategyComparison, error) {
+	if strategy == nil {
+		return StrategyComparison{}, fmt.Errorf("Comparison requires a strategy")
+	}
+	baseline, err := vehicle.strategyRun(cycle, ChargeSustaining{})
+	if err != nil {
+		return StrategyComparison{}, err
+	}
+	run, err := vehicle.strategyRun(cycle, strategy)
+	if err != nil {
+		return StrategyComparison{}, err
+	}
+	return StrategyComparison{Baseline: baseline, Strategy: run, FuelSaved: baseline.Fuel - run.Fuel}, nil
+}
+
+func (vehicle *Vehicle)strategyRun(cycle *Schedule, strategy EnergyManagement) (StrategyRun, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return StrategyRun{}, err
+	}
+	hybrid := false
+	for _,w := range v.Body.Wheelsets {
+		if w.Drive != nil && w.Drive.Hybrid != nil {
+			w.Drive.Hybrid.Strategy = strategy
+			hybrid = true
+		}
+	}
+	if !hybrid {
+		return StrategyRun{}, fmt.Errorf("Vehicle has no hybrid drive")
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return StrategyRun{}, err
+	}
+	result, err := sim.Run(cycle)
+	if err != nil {
+		return StrategyRun{}, err
+	}
+
+	run := StrategyRun{Fuel: result.Fuel, BatteryEnergy: result.Energy, EndSOC: result.EndSOC}
+	for fuel,amount := range sim.Resources {
+		if fuelDensity[fuel] != 0 {
+			run.FuelEnergy += amount * JoulesPerUnit(fuel)
+		}
+	}
+	for _,e := range result.EngineTimeline {
+		if e.On {
+			run.EngineStarts++
+		}
+	}
+	if used := run.FuelEnergy + math.Max(0, run.BatteryEnergy); used > 0 {
+		run.FuelShare = run.FuelEnergy / used
+	}
+	return run, nil
+}
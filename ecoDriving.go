@@ -0,0 +1,121 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	ecoSpeedStep = 1.0 //m/s a segment's target is lowered by in each step of the search
+	ecoMinSpeed = 3.0 //m/s, the search doesn't slow a segment below this
+	ecoMaxSteps = 200 //gives up refining after this many steps
+)
+
+//EcoProfile is the speed plan EcoDrive settled on for a route
+type EcoProfile struct {
+	Route *Schedule //copy of the route with each segment's speed lowered to its target
+	Targets []float64 //m/s, the target speed for each segment of the route
+	Result ScheduleResult //driving Route
+	Baseline ScheduleResult //driving the original route at the limits
+}
+
+//ecoEnergy is the cost being minimized, electricity and fuel both as joules
+func ecoEnergy(r ScheduleResult) float64 {
+	return r.Energy + r.Fuel / usGallon * GallonEquivalent
+}
+
+//ecoRoute is route with the segment speeds replaced by targets
+func ecoRoute(route *Schedule, targets []float64) *Schedule {
+	r := *route
+	r.Speeds = append([]float64(nil), targets...)
+	return &r
+}
+
+//EcoDrive finds the target speed for each segment of route (a Schedule with Distances,
+//its Speeds the limits) that uses the least energy while finishing within maxTime. It
+//starts at the limits and repeatedly slows whichever segment saves the most energy per
+//second it adds to the trip, running the simulation to score every candidate, until
+//nothing saves energy or the time budget is spent. Point limits and the finish speed are
+//left as they are
+func (vehicle *Vehicle)EcoDrive(route *Schedule, maxTime time.Duration) (EcoProfile, error) {
+	if route == nil || len(route.Distances) == 0 {
+		return EcoProfile{}, fmt.Errorf("Eco driving requires a route, a schedule with distances")
+	}
+	if maxTime <= 0 {
+		return EcoProfile{}, fmt.Errorf("Trip time must be positive")
+	}
+	run := func(targets []float64) (ScheduleResult, error) {
+		sim, err := InitSimulation(vehicle)
+		if err != nil {
+			return ScheduleResult{}, err
+		}
+		return sim.Run(ecoRoute(route, targets))
+	}
+
+	targets := append([]float64(nil), route.Speeds...)
+	baseline, err := run(targets)
+	if err != nil {
+		return EcoProfile{}, err
+	}
+	if baseline.Duration > maxTime {
+		return EcoProfile{}, fmt.Errorf("Route takes %v at the speed limits, longer than %v", baseline.Duration, maxTime)
+	}
+
+	//segments with length, the only ones a cruising speed means anything for
+	var adjustable []int
+	last := len(route.Distances) - 1
+	for i := 0; i < last; i++ {
+		if route.Distances[i+1] > route.Distances[i] {
+			adjustable = append(adjustable, i)
+		}
+	}
+
+	best := baseline
+	for step := 0; step < ecoMaxSteps; step++ {
+		candidates := make([]ScheduleResult, len(adjustable))
+		errs := make([]error, len(adjustable))
+		ok := make([]bool, len(adjustable))
+		parallel(len(adjustable), func(n int) {
+			i := adjustable[n]
+			if targets[i] <= ecoMinSpeed {
+				return
+			}
+			trial := append([]float64(nil), targets...)
+			trial[i] = math.Max(ecoMinSpeed, trial[i] - ecoSpeedStep)
+			candidates[n], errs[n] = run(trial)
+			ok[n] = errs[n] == nil
+		})
+
+		choice := -1
+		bestScore := 0.0
+		for n,r := range candidates {
+			if !ok[n] || r.Duration > maxTime {
+				continue
+			}
+			saved := ecoEnergy(best) - ecoEnergy(r)
+			if saved <= 0 {
+				continue
+			}
+			//a change that saves time as well is always worth taking
+			added := math.Max((r.Duration - best.Duration).Seconds(), 1e-3)
+			if score := saved / added; score > bestScore {
+				choice, bestScore = n, score
+			}
+		}
+		if choice < 0 {
+			break
+		}
+		i := adjustable[choice]
+		targets[i] = math.Max(ecoMinSpeed, targets[i] - ecoSpeedStep)
+		best = candidates[choice]
+	}
+
+	return EcoProfile{
+		Route: ecoRoute(route, targets),
+		Targets: targets,
+		Result: best,
+		Baseline: baseline,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	economyAccel = 1.0 //m/s^2, gentle acceleration used when pulsing
+	economyGlideBand = 0.2 //pulse-and-glide swings this fraction either side of the mean speed
+	economySpeedStep = 0.5 //m/s between candidate mean speeds
+	economySpeedRange = 2.0 //candidate speeds run up to this multiple of the minimum average speed
+	economySampleInterval = time.Second
+)
+
+//economyAttempt drives a copy of the vehicle over distance from a standstill, pulsing at
+//economyAccel up to high, then gliding (no drive force) down to low before pulsing again.
+//low == high is a steady cruise. It returns false if maxTime runs out first
+func (vehicle *Vehicle)economyAttempt(distance, low, high float64, maxTime time.Duration) (float64, []TelemetrySample, bool, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return 0, nil, false, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	
+	var samples []TelemetrySample
+	var nextSample time.Duration
+	gliding := false
+	for sim.Distance < distance {
+		if sim.Time > maxTime {
+			return 0, nil, false, nil
+		}
+		if sim.Time >= nextSample {
+			samples = append(samples, sim.Sample())
+			nextSample += economySampleInterval
+		}
+		
+		if gliding && sim.Speed <= low {
+			gliding = false
+		} else if !gliding && low < high && sim.Speed >= high {
+			gliding = true
+		}
+		
+		accel := math.Min(economyAccel, (high - sim.Speed)/sim.Interval.Seconds())
+		if gliding {
+			//coast, the only forces are the ones slowing the vehicle down
+			accel = -(v.Body.AeroDrag(sim) + v.Body.RollingDrag(sim)) / v.Body.Mass()
+		}
+		sim.Tick(accel)
+	}
+	samples = append(samples, sim.Sample())
+	return v.Battery.EnergyUsed(), samples, true, nil
+}
+
+//EconomyRun searches for the lowest-energy way to cover distance meters from a standstill
+//within maxTime, trying steady cruising and pulse-and-glide at a range of average speeds.
+//It returns the consumption in Wh/km and the winning speed profile
+func (vehicle *Vehicle)EconomyRun(distance float64, maxTime time.Duration) (float64, []TelemetrySample, error) {
+	if distance <= 0 || maxTime <= 0 {
+		return 0, nil, fmt.Errorf("Distance and time budget must be positive")
+	}
+	
+	minSpeed := distance / maxTime.Seconds()
+	bestEnergy := math.Inf(1)
+	var bestProfile []TelemetrySample
+	for speed := minSpeed; speed <= minSpeed * economySpeedRange; speed += economySpeedStep {
+		strategies := [][2]float64{
+			{speed, speed},
+			{speed * (1 - economyGlideBand), speed * (1 + economyGlideBand)},
+		}
+		for _,s := range strategies {
+			energy, profile, ok, err := vehicle.economyAttempt(distance, s[0], s[1], maxTime)
+			if err != nil {
+				return 0, nil, err
+			}
+			if ok && energy < bestEnergy {
+				bestEnergy = energy
+				bestProfile = profile
+			}
+		}
+	}
+	
+	if bestProfile == nil {
+		return 0, nil, fmt.Errorf("Can not cover %5.0fm in %v", distance, maxTime)
+	}
+	whPerKm := (bestEnergy / 3600) / (distance / 1000)
+	return whPerKm, bestProfile, nil
+}
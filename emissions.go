@@ -0,0 +1,45 @@
+package automotiveSim
+
+
+import (
+	"math"
+)
+
+//fuelCO2 is the CO2 (g) from burning a liter of each fuel, EPA's 8887 g/gal for gasoline and
+//10180 g/gal for diesel
+var fuelCO2 = map[string]float64{
+	"Gasoline": 8887 / usGallon,
+	"Diesel": 10180 / usGallon,
+}
+
+//Emissions is the CO2 of a run in grams
+type Emissions struct {
+	Tailpipe float64
+	Upstream float64 //generating the electricity, zero without a grid intensity
+	Total float64
+	PerKm float64 //g/km of Total
+}
+
+//tailpipeCO2 is the CO2 (g) from all the fuel burned so far
+func (state *SimulatorState)tailpipeCO2() float64 {
+	total := 0.0
+	for fuel,amount := range state.Resources {
+		total += amount * fuelCO2[fuel]
+	}
+	return total
+}
+
+//Emissions is the run's CO2, from the tailpipe and optionally from generating the
+//electricity it drew at gridIntensity grams per kWh at the wall. Well-to-tank emissions of
+//liquid fuels are left out. A run that ends with more charge than it started credits none back
+func (r ScheduleResult)Emissions(gridIntensity float64) Emissions {
+	e := Emissions{
+		Tailpipe: r.CO2,
+		Upstream: math.Max(0, r.GridEnergy) / KilowattHour * gridIntensity,
+	}
+	e.Total = e.Tailpipe + e.Upstream
+	if r.Distance > 0 {
+		e.PerKm = e.Total / (r.Distance / 1000)
+	}
+	return e
+}
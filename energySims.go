@@ -0,0 +1,341 @@
+package automotiveSim
+
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	sizingTolerance = 0.001 //relative change in capacity considered converged
+	sizingIterations = 100
+	cruiseSearchStep = 1.0 //m/s
+	cruiseSearchMax = 100.0 //m/s
+	rangeSampleDistance = 1000.0 //m between points on RangeResult.SOC
+)
+
+//RangeResult is how far the vehicle gets before the battery reaches MinSOC
+type RangeResult struct {
+	Range float64 //km
+	SOC Curve //state of charge against distance in km
+}
+
+//cycleConsumption runs the schedule once on a copy of the vehicle and returns
+//the energy drawn from the battery per meter travelled (J/m)
+func (vehicle *Vehicle)cycleConsumption(cycle *Schedule) (float64, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return 0, err
+	}
+	
+	sim, err := initSimulation(v)
+	if err != nil {
+		return 0, err
+	}
+	
+	result, err := sim.Run(cycle)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", cycle.Name, err)
+	}
+	if result.Distance <= 0 {
+		return 0, fmt.Errorf("%s: schedule does not cover any distance", cycle.Name)
+	}
+	return result.Energy / result.Distance, nil
+}
+
+//CapacityForRange returns the battery capacity (Wh) needed to cover targetRange
+//meters driving the given cycle. If the battery has a SpecificEnergy the mass of the
+//resized pack is fed back into the consumption until the capacity converges
+func (vehicle *Vehicle)CapacityForRange(targetRange float64, cycle *Schedule) (float64, error) {
+	if targetRange <= 0 {
+		return 0, fmt.Errorf("Target range must be positive")
+	}
+	
+	specificEnergy := vehicle.Battery.SpecificEnergy
+	currentCapacity := vehicle.Battery.Energy() / 3600
+	//only part of the pack is usable
+	window := vehicle.Battery.MaxSOC - vehicle.Battery.MinSOC
+	
+	v, err := vehicle.Clone()
+	if err != nil {
+		return 0, err
+	}
+	
+	capacity := currentCapacity
+	for i := 0; i < sizingIterations; i++ {
+		consumption, err := v.cycleConsumption(cycle)
+		if err != nil {
+			return 0, err
+		}
+		needed := (targetRange * consumption) / (3600 * window)
+		
+		//without the mass coupling it's a straight division
+		if specificEnergy == 0 || math.Abs(needed - capacity) <= sizingTolerance * needed {
+			return needed, nil
+		}
+		capacity = needed
+		
+		//swap the current pack's mass for the resized one
+		v.Body.Weight = vehicle.Body.Weight + (capacity - currentCapacity)/specificEnergy
+		if v.Body.Weight <= 0 {
+			return 0, fmt.Errorf("Resized pack leaves the vehicle with no mass")
+		}
+	}
+	return 0, fmt.Errorf("Battery capacity did not converge, pack mass grows faster than the range it adds")
+}
+
+//steadyConsumption returns the energy drawn from the battery per meter (J/m)
+//holding a constant speed on a copy of the vehicle
+func (vehicle *Vehicle)steadyConsumption(speed float64) (float64, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return 0, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return 0, err
+	}
+	
+	sim.Speed = speed
+	accel, err := sim.Tick(0)
+	if math.Abs(accel) > 0.01 {
+		return 0, fmt.Errorf("Vehicle can not maintain speed %5.2f: %v", speed, err)
+	}
+	return v.Battery.EnergyUsed() / sim.Distance, nil
+}
+
+//FastestWithinRange finds the highest constant cruise speed that covers distance
+//meters on the energy left in the battery, and the resulting trip time
+func (vehicle *Vehicle)FastestWithinRange(distance float64) (float64, time.Duration, error) {
+	if distance <= 0 {
+		return 0, 0, fmt.Errorf("Distance must be positive")
+	}
+	available := vehicle.Battery.RemainingEnergy()
+	feasible := func(speed float64) bool {
+		consumption, err := vehicle.steadyConsumption(speed)
+		return err == nil && consumption * distance <= available
+	}
+	
+	//consumption falls off with speed until the accessory load stops dominating,
+	//then only rises, so scan upwards for the first speed that no longer makes it
+	lastGood := 0.0
+	speed := cruiseSearchStep
+	for ; speed < cruiseSearchMax; speed += cruiseSearchStep {
+		if feasible(speed) {
+			lastGood = speed
+		} else if lastGood > 0 {
+			break
+		}
+	}
+	if lastGood == 0 {
+		return 0, 0, fmt.Errorf("%5.0fm exceeds the vehicle's range at any speed", distance)
+	}
+	
+	//narrow down between the last feasible speed and the first one that wasn't
+	low, high := lastGood, speed
+	for high - low > 0.01 {
+		mid := (low + high)/2
+		if feasible(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	tripTime := time.Duration((distance / low) * float64(time.Second))
+	return low, tripTime, nil
+}
+
+//RangeVsTirePressure returns the range (meters) on a full battery driving the cycle
+//with every tire set to each of the given pressures (kPa)
+func (vehicle *Vehicle)RangeVsTirePressure(pressures []float64, cycle *Schedule) ([]float64, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil, err
+	}
+	
+	ranges := make([]float64, len(pressures))
+	for i,pressure := range pressures {
+		for j := range v.Body.Wheelsets {
+			v.Body.Wheelsets[j].Tires.Pressure = pressure
+		}
+		consumption, err := v.cycleConsumption(cycle)
+		if err != nil {
+			return nil, fmt.Errorf("%5.1fkPa: %v", pressure, err)
+		}
+		ranges[i] = v.Battery.UsableEnergy() / consumption
+	}
+	return ranges, nil
+}
+
+//WithAeroAddon returns a copy of the vehicle carrying an add-on (roof box, bike rack...)
+//that adds deltaCdA of drag area and deltaMass kg. It returns nil if the result is
+//not a valid vehicle
+func (vehicle *Vehicle)WithAeroAddon(deltaCdA, deltaMass float64) *Vehicle {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil
+	}
+	v.Body.CdA += deltaCdA
+	v.Body.Weight += deltaMass
+	if v.Init() != nil {
+		return nil
+	}
+	return v
+}
+
+//AddonComparison is the steady-speed consumption (J/m) and full-battery range (m)
+//with and without an add-on
+type AddonComparison struct {
+	Speed float64
+	BaseConsumption float64
+	AddonConsumption float64
+	BaseRange float64
+	AddonRange float64
+}
+
+//CompareAeroAddon reports the consumption and range hit of an add-on at a steady speed
+func (vehicle *Vehicle)CompareAeroAddon(deltaCdA, deltaMass, speed float64) (AddonComparison, error) {
+	result := AddonComparison{Speed: speed}
+	addon := vehicle.WithAeroAddon(deltaCdA, deltaMass)
+	if addon == nil {
+		return result, fmt.Errorf("Add-on leaves the vehicle with negative drag area or mass")
+	}
+	
+	var err error
+	result.BaseConsumption, err = vehicle.steadyConsumption(speed)
+	if err != nil {
+		return result, err
+	}
+	result.AddonConsumption, err = addon.steadyConsumption(speed)
+	if err != nil {
+		return result, fmt.Errorf("With add-on: %v", err)
+	}
+	result.BaseRange = vehicle.Battery.UsableEnergy() / result.BaseConsumption
+	result.AddonRange = addon.Battery.UsableEnergy() / result.AddonConsumption
+	return result, nil
+}
+
+//TimeToDepleteAtPower returns how long the battery can supply a constant load of
+//watts while stationary (camping, accessories), starting from fromSOC (0-1)
+func (vehicle *Vehicle)TimeToDepleteAtPower(watts, fromSOC float64) (time.Duration, error) {
+	if watts <= 0 {
+		return 0, fmt.Errorf("Power draw must be positive")
+	}
+	if fromSOC < 0 || fromSOC > 1 {
+		return 0, fmt.Errorf("State of charge must be on the range [0,1]")
+	}
+	
+	b := &vehicle.Battery
+	amps := b.AmpsAtPower(watts)
+	if math.IsNaN(amps) || amps > b.MaxCurrent {
+		return 0, fmt.Errorf("Exceeds max pack current")
+	}
+	seconds := (b.Coulomb * math.Max(0, fromSOC - b.MinSOC)) / amps
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+//DraftingSavings returns the fraction of energy saved driving the cycle with its
+//DragReduction profile, compared to running the same cycle alone
+func (vehicle *Vehicle)DraftingSavings(cycle *Schedule) (float64, error) {
+	drafting, err := vehicle.cycleConsumption(cycle)
+	if err != nil {
+		return 0, err
+	}
+	
+	solo := *cycle
+	solo.DragReduction = nil
+	alone, err := vehicle.cycleConsumption(&solo)
+	if err != nil {
+		return 0, err
+	}
+	return 1 - drafting/alone, nil
+}
+
+//RangeAtSpeed drives a copy of the vehicle at a constant speed (m/s) until the battery
+//is depleted, starting from its current state of charge
+func (vehicle *Vehicle)RangeAtSpeed(speed float64) (RangeResult, error) {
+	if speed <= 0 {
+		return RangeResult{}, fmt.Errorf("Speed must be positive")
+	}
+	v, err := vehicle.Clone()
+	if err != nil {
+		return RangeResult{}, err
+	}
+	v.Battery.InitialSOC = vehicle.Battery.StateOfCharge()
+	err = v.Battery.Init()
+	if err != nil {
+		return RangeResult{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return RangeResult{}, err
+	}
+	
+	//nothing changes at a steady speed, so there's no need for fine steps
+	sim.Interval = time.Second
+	sim.Speed = speed
+	
+	result := RangeResult{SOC: Curve{{X: 0, Y: v.Battery.StateOfCharge()}}}
+	next := rangeSampleDistance
+	for {
+		accel, err := sim.Tick(0)
+		if errors.Is(err, errDepleted) {
+			break
+		}
+		if math.Abs(accel) > 0.01 {
+			return RangeResult{}, fmt.Errorf("Vehicle can not maintain speed %5.2f: %v", speed, err)
+		}
+		if sim.Distance >= next {
+			result.SOC = append(result.SOC, CurvePoint{X: sim.Distance/1000, Y: v.Battery.StateOfCharge()})
+			next += rangeSampleDistance
+		}
+	}
+	result.Range = sim.Distance/1000
+	result.SOC = append(result.SOC, CurvePoint{X: result.Range, Y: v.Battery.StateOfCharge()})
+	return result, nil
+}
+
+//RangeOnCycle repeats the cycle on a copy of the vehicle until the battery is depleted,
+//starting from its current state of charge. SOC has a point at the end of each repetition
+func (vehicle *Vehicle)RangeOnCycle(cycle *Schedule) (RangeResult, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return RangeResult{}, err
+	}
+	v.Battery.InitialSOC = vehicle.Battery.StateOfCharge()
+	err = v.Battery.Init()
+	if err != nil {
+		return RangeResult{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return RangeResult{}, err
+	}
+	
+	result := RangeResult{SOC: Curve{{X: 0, Y: v.Battery.StateOfCharge()}}}
+	for {
+		start := sim.Distance
+		_, err := sim.Run(cycle)
+		//a nearly empty pack can sag past its current limit before it reads as depleted
+		if errors.Is(err, errDepleted) || (err != nil && v.Battery.StateOfCharge() <= v.Battery.minSOC(sim)) {
+			break
+		}
+		if err != nil {
+			return RangeResult{}, fmt.Errorf("%s: %v", cycle.Name, err)
+		}
+		if sim.Distance <= start {
+			return RangeResult{}, fmt.Errorf("%s: schedule does not cover any distance", cycle.Name)
+		}
+		result.SOC = append(result.SOC, CurvePoint{X: sim.Distance/1000, Y: v.Battery.StateOfCharge()})
+		
+		//each repetition starts where the last one ended
+		sim.Time = 0
+	}
+	result.Range = sim.Distance/1000
+	if result.Range > result.SOC[len(result.SOC)-1].X {
+		result.SOC = append(result.SOC, CurvePoint{X: result.Range, Y: v.Battery.StateOfCharge()})
+	}
+	return result, nil
+}
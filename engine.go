@@ -0,0 +1,97 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	//naturally aspirated torque curves are quoted at this density (15C, sea level)
+	referenceAirDensity = 1.225
+)
+
+//Engine is an internal combustion engine. Below IdleRPM the clutch slips, so the engine
+//holds idle and delivers its idle torque. Fuel burned is tallied in liters under
+//SimulatorState.Resources[Fuel]
+type Engine struct {
+	Name string
+	Torque Curve //full load torque (Nm) against engine speed (rpm)
+	IdleRPM float64
+	RedlineRPM float64
+	BSFC Map //brake specific fuel consumption (g/kWh), X engine speed (rpm), Y torque (Nm)
+	IdleFuelRate float64 //g/s burned at idle or with no load
+	Fuel string //defaults to Gasoline
+	Turbocharged bool //holds its torque at altitude, otherwise torque falls with air density
+	
+	//state
+	limits limitErrors
+}
+
+func (e *Engine)Init() error {
+	if len(e.Torque) == 0 {
+		return fmt.Errorf("Engine requires a torque curve")
+	}
+	err := e.Torque.Init()
+	if err != nil {
+		return fmt.Errorf("Torque: %v", err)
+	}
+	if e.IdleRPM <= 0 {
+		return fmt.Errorf("Idle speed must be positive")
+	}
+	if e.RedlineRPM <= e.IdleRPM {
+		return fmt.Errorf("Redline must be above idle speed")
+	}
+	err = e.BSFC.Init()
+	if err != nil {
+		return fmt.Errorf("BSFC: %v", err)
+	}
+	if e.IdleFuelRate < 0 {
+		return fmt.Errorf("Idle fuel rate must not be negative")
+	}
+	if e.Fuel == "" {
+		e.Fuel = "Gasoline"
+	}
+	if fuelDensity[e.Fuel] == 0 {
+		return fmt.Errorf("Unknown fuel %q", e.Fuel)
+	}
+	e.limits.init(e.Name)
+	return nil
+}
+
+//engineRPM is the engine speed for a drive shaft speed (rad/s), held at idle by the clutch
+func (e *Engine)engineRPM(shaftSpeed float64) float64 {
+	return math.Max(e.IdleRPM, math.Abs(shaftSpeed) / rpmToRadS)
+}
+
+func (e *Engine)MaxTorque(sim *SimulatorState, shaftSpeed float64) (float64, error) {
+	if math.Abs(shaftSpeed) / rpmToRadS > e.RedlineRPM {
+		return 0, e.limits.of(LimitEngineRedline, e.Name)
+	}
+	torque := e.Torque.At(e.engineRPM(shaftSpeed))
+	if !e.Turbocharged {
+		torque *= math.Min(1, sim.Vehicle.Ambient.AirDensity() / referenceAirDensity)
+	}
+	return torque, e.limits.of(LimitEngineTorque, e.Name)
+}
+
+//fuelRate is the fuel burned in g/s delivering torque at shaftSpeed
+func (e *Engine)fuelRate(shaftSpeed, torque float64) float64 {
+	rpm := e.engineRPM(shaftSpeed)
+	power := torque * rpm * rpmToRadS
+	if power <= 0 {
+		return e.IdleFuelRate
+	}
+	//g/kWh to g/s
+	rate := e.BSFC.At(rpm, torque) * power / 3.6e6
+	return math.Max(rate, e.IdleFuelRate)
+}
+
+//Operate burns the fuel for one tick, returning the shaft output and the chemical power
+//of the fuel burned. The engine draws nothing from the battery
+func (e *Engine)Operate(sim *SimulatorState, shaftSpeed, torque float64) (mechanical, fuel float64) {
+	rate := e.fuelRate(shaftSpeed, torque)
+	liters := rate / 1000 / fuelDensity[e.Fuel] * sim.Interval.Seconds()
+	sim.Resources[e.Fuel] += liters
+	return torque * e.engineRPM(shaftSpeed) * rpmToRadS, rate / 1000 / fuelDensity[e.Fuel] * JoulesPerUnit(e.Fuel)
+}
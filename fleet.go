@@ -0,0 +1,224 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	day = 24 * time.Hour
+	fleetDemandBin = 15 * time.Minute //charging demand is averaged over bins this long, like a utility meter
+)
+
+//Shift is one trip of a vehicle's working day
+type Shift struct {
+	Start time.Duration //from midnight
+	Schedule *Schedule
+}
+
+//FleetVehicle is one vehicle of a fleet and the shifts it drives every day. Between
+//shifts, and overnight, it charges on Charger if it has one
+type FleetVehicle struct {
+	Name string
+	Vehicle *Vehicle
+	Shifts []Shift //in order of Start
+	Charger *Charger //optional
+}
+
+//Fleet runs every vehicle through its shifts day after day
+type Fleet struct {
+	Vehicles []FleetVehicle
+	Days int
+}
+
+//FleetVehicleResult is one vehicle's totals over the whole run
+type FleetVehicleResult struct {
+	Name string
+	Distance float64 //m
+	Energy float64 //J drawn from the battery driving
+	GridEnergy float64 //J from the wall charging
+	Fuel float64 //liters
+	Trips int
+	FailedTrips int //shifts the vehicle couldn't finish, usually for lack of charge
+	LowestSOC float64 //at the end of any shift
+	Errs []error //why each failed trip failed
+}
+
+//FleetResult is the fleet's totals and the charging load it puts on the site
+type FleetResult struct {
+	Vehicles []FleetVehicleResult
+	Distance float64
+	Energy float64
+	GridEnergy float64
+	Fuel float64
+	Trips int
+	FailedTrips int
+	Demand Curve //W from the wall for the whole fleet, averaged over 15 minute bins, against hours from the start
+	PeakDemand float64 //W, the highest bin of Demand
+}
+
+func (f *Fleet)Init() error {
+	if len(f.Vehicles) == 0 {
+		return fmt.Errorf("Fleet requires at least one vehicle")
+	}
+	if f.Days <= 0 {
+		return fmt.Errorf("Fleet must run for at least one day")
+	}
+	for _,v := range f.Vehicles {
+		if v.Vehicle == nil {
+			return fmt.Errorf("%s: no vehicle", v.Name)
+		}
+		if len(v.Shifts) == 0 {
+			return fmt.Errorf("%s: no shifts", v.Name)
+		}
+		for i,s := range v.Shifts {
+			if s.Schedule == nil {
+				return fmt.Errorf("%s: shift %d has no schedule", v.Name, i)
+			}
+			if s.Start < 0 || s.Start >= day {
+				return fmt.Errorf("%s: shifts must start within the day", v.Name)
+			}
+			if i > 0 && s.Start <= v.Shifts[i-1].Start {
+				return fmt.Errorf("%s: shifts must be in order of start time", v.Name)
+			}
+		}
+		if v.Charger != nil {
+			err := v.Charger.Init()
+			if err != nil {
+				return fmt.Errorf("%s: %v", v.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Fleet)Run() (FleetResult, error) {
+	return f.RunContext(context.Background())
+}
+
+//RunContext is Run, giving up with ctx's error once ctx is done. Vehicles run in parallel,
+//each on its own clone
+func (f *Fleet)RunContext(ctx context.Context) (FleetResult, error) {
+	err := f.Init()
+	if err != nil {
+		return FleetResult{}, err
+	}
+	bins := int((time.Duration(f.Days) * day) / fleetDemandBin)
+	results := make([]FleetVehicleResult, len(f.Vehicles))
+	demands := make([][]float64, len(f.Vehicles))
+	errs := make([]error, len(f.Vehicles))
+	parallel(len(f.Vehicles), func(i int) {
+		demands[i] = make([]float64, bins)
+		results[i], errs[i] = f.runVehicle(ctx, &f.Vehicles[i], demands[i])
+	})
+	for _,err := range errs {
+		if err != nil {
+			return FleetResult{}, err
+		}
+	}
+
+	result := FleetResult{Vehicles: results}
+	for _,r := range results {
+		result.Distance += r.Distance
+		result.Energy += r.Energy
+		result.GridEnergy += r.GridEnergy
+		result.Fuel += r.Fuel
+		result.Trips += r.Trips
+		result.FailedTrips += r.FailedTrips
+	}
+	binEnergy := make([]float64, bins)
+	for _,d := range demands {
+		for j,energy := range d {
+			binEnergy[j] += energy
+		}
+	}
+	for j,energy := range binEnergy {
+		power := energy / fleetDemandBin.Seconds()
+		result.Demand = append(result.Demand, CurvePoint{X: (time.Duration(j) * fleetDemandBin).Hours(), Y: power})
+		result.PeakDemand = math.Max(result.PeakDemand, power)
+	}
+	return result, nil
+}
+
+//runVehicle drives one vehicle through every day, adding the energy it takes from the
+//wall to demand by bin
+func (f *Fleet)runVehicle(ctx context.Context, fv *FleetVehicle, demand []float64) (FleetVehicleResult, error) {
+	result := FleetVehicleResult{Name: fv.Name, LowestSOC: math.Inf(1)}
+	sim, err := InitSimulation(fv.Vehicle)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", fv.Name, err)
+	}
+	end := time.Duration(f.Days) * day
+	now := time.Duration(0)
+	for d := 0; d < f.Days; d++ {
+		for i,shift := range fv.Shifts {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			//a late vehicle leaves as soon as it's back
+			start := time.Duration(d) * day + shift.Start
+			if now > start {
+				start = now
+			}
+			if now < start {
+				fv.charge(sim, now, start, demand, &result)
+			}
+
+			sim.Time = 0
+			r, err := sim.RunContext(ctx, shift.Schedule)
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Trips++
+			if err != nil {
+				result.FailedTrips++
+				result.Errs = append(result.Errs, fmt.Errorf("day %d shift %d: %v", d+1, i+1, err))
+			}
+			result.Distance += r.Distance
+			result.Energy += r.Energy
+			result.Fuel += r.Fuel
+			result.LowestSOC = math.Min(result.LowestSOC, r.EndSOC)
+			now = start + r.Duration
+		}
+	}
+	if now < end {
+		fv.charge(sim, now, end, demand, &result)
+	}
+	return result, nil
+}
+
+//charge plugs the vehicle in from one time to another, as far as its charger allows
+func (fv *FleetVehicle)charge(sim *SimulatorState, from, to time.Duration, demand []float64, result *FleetVehicleResult) {
+	if fv.Charger == nil {
+		return
+	}
+	b := &sim.Vehicle.Battery
+	efficiency := fv.Charger.Efficiency
+	if efficiency == 0 {
+		efficiency = b.ChargerEfficency
+	}
+	saved := sim.Interval
+	sim.Interval = chargeInterval
+	defer func() { sim.Interval = saved }()
+
+	for t := from; t < to; t += chargeInterval {
+		step := chargeInterval
+		if to - t < step {
+			step = to - t
+		}
+		sim.Interval = step
+		power := math.Min(fv.Charger.MaxPower, b.chargePower(sim, b.MaxSOC))
+		if power <= 0 {
+			return
+		}
+		b.Operate(sim, -power)
+		wall := power * step.Seconds() / efficiency
+		result.GridEnergy += wall
+		if bin := int(t / fleetDemandBin); bin < len(demand) {
+			demand[bin] += wall
+		}
+	}
+}
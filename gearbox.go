@@ -0,0 +1,170 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	shiftHeadroom = 0.95 //fraction of top shaft speed a new gear may start at
+)
+
+//ShiftStrategy decides which gear a drive should be in
+type ShiftStrategy interface {
+	//Gear returns the gear (index into Ratios) to use for the next tick
+	Gear(sim *SimulatorState, w *Wheelset) int
+}
+
+//Gearbox sits between the motor and the final drive. While shifting the drive
+//can neither drive nor regen
+type Gearbox struct {
+	Ratios []float64 //first gear first, multiplied with Drive.Gearing
+	Efficiencies []float64 //optional, one per gear
+	ShiftTime time.Duration
+	Strategy ShiftStrategy `json:"-"` //defaults to PerformanceShift. Set in code, not in a description
+
+	//state
+	gear int
+	shifting time.Duration
+}
+
+func (g *Gearbox)Init() error {
+	if len(g.Ratios) == 0 {
+		return fmt.Errorf("Gearbox requires at least one ratio")
+	}
+	for _,r := range g.Ratios {
+		if r <= 0 {
+			return fmt.Errorf("Gear ratios must be positive")
+		}
+	}
+	if len(g.Efficiencies) != 0 && len(g.Efficiencies) != len(g.Ratios) {
+		return fmt.Errorf("Gearbox requires one efficiency per gear")
+	}
+	for _,e := range g.Efficiencies {
+		if e <= 0 || e > 1 {
+			return fmt.Errorf("Gear efficiency must be on the range (0,1]")
+		}
+	}
+	if g.ShiftTime < 0 {
+		return fmt.Errorf("Shift time must not be negative")
+	}
+	if g.Strategy == nil {
+		g.Strategy = PerformanceShift{}
+	}
+	g.gear = 0
+	g.shifting = 0
+	return nil
+}
+
+func (g *Gearbox)efficiency(gear int) float64 {
+	if len(g.Efficiencies) == 0 {
+		return 1
+	}
+	return g.Efficiencies[gear]
+}
+
+//Gear is the currently selected gear, counting from zero
+func (g *Gearbox)Gear() int {
+	return g.gear
+}
+
+//Shifting reports whether a shift is in progress
+func (g *Gearbox)Shifting() bool {
+	return g.shifting > 0
+}
+
+//update finishes any shift in progress, then asks the strategy for the next gear
+func (g *Gearbox)update(sim *SimulatorState, w *Wheelset) {
+	if g.shifting > 0 {
+		g.shifting -= sim.Interval
+		if g.shifting > 0 {
+			return
+		}
+	}
+	gear := g.Strategy.Gear(sim, w)
+	if gear < 0 {
+		gear = 0
+	} else if gear >= len(g.Ratios) {
+		gear = len(g.Ratios) - 1
+	}
+	if gear != g.gear {
+		g.gear = gear
+		g.shifting = g.ShiftTime
+	}
+}
+
+//PerformanceShift picks whichever gear gives the most force at the wheels right now
+type PerformanceShift struct{}
+
+func (PerformanceShift)Gear(sim *SimulatorState, w *Wheelset) int {
+	d := w.Drive
+	g := d.Gearbox
+	best := g.gear
+	bestForce := gearForce(sim, w, best, 1)
+	for i := range g.Ratios {
+		//only move for a clear gain, and not right up against the speed limit, so the
+		//gearbox doesn't hunt between equal gears or back down after a redline upshift
+		force := gearForce(sim, w, i, shiftHeadroom)
+		if force > bestForce * 1.01 {
+			best = i
+			bestForce = force
+		}
+	}
+	return best
+}
+
+//RPMShift upshifts above UpshiftRPM and downshifts below DownshiftRPM (motor speed)
+type RPMShift struct {
+	UpshiftRPM float64
+	DownshiftRPM float64
+}
+
+func (s RPMShift)Gear(sim *SimulatorState, w *Wheelset) int {
+	g := w.Drive.Gearbox
+	rpm := (sim.Speed / w.Tires.Radius) * w.Drive.Ratio() / rpmToRadS
+	if rpm > s.UpshiftRPM && g.gear + 1 < len(g.Ratios) {
+		return g.gear + 1
+	}
+	if rpm < s.DownshiftRPM && g.gear > 0 {
+		return g.gear - 1
+	}
+	return g.gear
+}
+
+//HeldGear stays in one gear, counting from zero, as a driver holding it manually does,
+//e.g. a low gear for more regen on a long descent
+type HeldGear struct {
+	Selected int
+}
+
+func (s HeldGear)Gear(sim *SimulatorState, w *Wheelset) int {
+	return s.Selected
+}
+
+//SpeedShift upshifts as the vehicle passes each of Upshift (m/s), first gear's first, and
+//never downshifts. PerfectLaunch finds the fastest one
+type SpeedShift struct {
+	Upshift []float64
+}
+
+func (s SpeedShift)Gear(sim *SimulatorState, w *Wheelset) int {
+	gear := w.Drive.Gearbox.gear
+	for gear < len(s.Upshift) && sim.Speed >= s.Upshift[gear] {
+		gear++
+	}
+	return gear
+}
+
+//gearForce is the most tractive force the drive could make in the given gear, treating
+//anything above limit times its top shaft speed as out of reach
+func gearForce(sim *SimulatorState, w *Wheelset, gear int, limit float64) float64 {
+	d := w.Drive
+	ratio := d.Gearing * d.Gearbox.Ratios[gear]
+	shaftSpeed := (sim.Speed / w.Tires.Radius) * ratio
+	if shaftSpeed > limit * d.maxShaftSpeed() {
+		return 0
+	}
+	torque, _ := d.maxTorque(sim, shaftSpeed)
+	return torque * ratio * d.Gearbox.efficiency(gear) / w.Tires.Radius
+}
@@ -0,0 +1,62 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+//twoSpeed gives the test vehicle a two speed gearbox held in gear, behind a motor with
+//little enough torque that it limits regen
+func twoSpeed(t *testing.T, gear int) *Vehicle {
+	v := testVehicle(t)
+	drive := v.Body.Wheelsets[1].Drive
+	drive.Gearing = 4
+	drive.Gearbox = &Gearbox{Ratios: []float64{2.4, 1}, ShiftTime: 200 * time.Millisecond, Strategy: HeldGear{gear}}
+	drive.Motor.Peak.Torque, drive.Motor.Continuous.Torque = 60, 40
+	v.Battery.InitialSOC = 0.5
+	return v
+}
+
+func TestRegenTorqueFollowsGear(t *testing.T) {
+	regenForce := func(gear int) float64 {
+		sim := testSimulation(t, twoSpeed(t, gear))
+		sim.Speed = 20
+		sim.Vehicle.Body.shift(sim)
+		w := &sim.Body.Wheelsets[1]
+		w.Drive.Gearbox.shifting = 0
+		if w.Drive.Gearbox.Gear() != gear {
+			t.Fatalf("in gear %d, not %d", w.Drive.Gearbox.Gear(), gear)
+		}
+		f, _ := w.Fmin(sim)
+		return -(f + w.RollingDrag(sim))
+	}
+	low, high := regenForce(0), regenForce(1)
+	//torque limited in both, so the regen force scales with the ratio
+	if math.Abs(low/high - 2.4) > 0.01 {
+		t.Fatalf("regen force %.0f N in first and %.0f N in second, not 2.4 times", low, high)
+	}
+}
+
+func TestDescentRegenInEachGear(t *testing.T) {
+	route := descentRoute()
+	route.Grades[1] = -0.1
+	var results [2]DescentSegment
+	for gear := range results {
+		segments, err := twoSpeed(t, gear).DescentRecovery(route)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results[gear] = segments[0]
+	}
+	low, high := results[0], results[1]
+	//second gear's regen can't hold the car on a 10% grade, first gear's can
+	if low.Friction > 0.01 * low.Potential {
+		t.Fatalf("first gear left %.0f J of %.0f J to the friction brakes", low.Friction, low.Potential)
+	}
+	if high.Friction < 0.2 * high.Potential || high.Recovered >= low.Recovered {
+		t.Fatalf("second gear recovered %.0f J with %.0f J of friction, first gear %.0f J",
+			high.Recovered, high.Friction, low.Recovered)
+	}
+}
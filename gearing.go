@@ -0,0 +1,184 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	gearingSteps = 20 //ratios tried across the range before refining
+	gearingTolerance = 0.01 //how closely the refined ratio is found
+)
+
+//GearingSearch is a search for the best final drive (or one gearbox ratio) on a range
+type GearingSearch struct {
+	Wheelset string //the driven wheelset to regear, empty for all of them
+	Gearbox bool //search Gearbox.Ratios[Gear] instead of Drive.Gearing
+	Gear int
+	Min float64
+	Max float64
+	Metric Metric
+	Maximize bool //find the highest Metric rather than the lowest
+	MinTopSpeed float64 //m/s, optional. Rejects ratios spinning the powerplant past its limit below this
+}
+
+//GearingResult is the best ratio found and the coarse sweep that led to it
+type GearingResult struct {
+	Ratio float64
+	Result float64
+	Sweep SweepTable
+}
+
+//TopSpeedMetric is the top speed in m/s
+func TopSpeedMetric(v *Vehicle) (float64, error) {
+	profile, err := v.RunAccelerationProfile()
+	return profile.TopSpeed, err
+}
+
+//speedLimit is the vehicle speed (m/s) where the powerplant reaches its top shaft speed
+//in the tallest gear
+func (w *Wheelset)speedLimit() float64 {
+	ratio := w.Drive.Gearing
+	if w.Drive.Gearbox != nil {
+		tallest := math.Inf(1)
+		for _,r := range w.Drive.Gearbox.Ratios {
+			tallest = math.Min(tallest, r)
+		}
+		ratio *= tallest
+	}
+	return w.Drive.maxShaftSpeed() * w.Tires.Radius / ratio
+}
+
+//parameter is the sweep axis for the ratio being searched
+func (s *GearingSearch)parameter(values []float64) Parameter {
+	return Parameter{Name: "Ratio", Values: values, Apply: func(v *Vehicle, value float64) {
+		for i := range v.Body.Wheelsets {
+			w := &v.Body.Wheelsets[i]
+			if w.Drive == nil || (s.Wheelset != "" && w.Name != s.Wheelset) {
+				continue
+			}
+			if !s.Gearbox {
+				w.Drive.Gearing = value
+			} else if w.Drive.Gearbox != nil && s.Gear < len(w.Drive.Gearbox.Ratios) {
+				ratios := append([]float64(nil), w.Drive.Gearbox.Ratios...)
+				ratios[s.Gear] = value
+				w.Drive.Gearbox.Ratios = ratios
+			}
+		}
+	}}
+}
+
+//gearingCost is the metric at one ratio, negated when maximizing so lower is always better
+func (vehicle *Vehicle)gearingCost(s *GearingSearch, ratio float64) (float64, error) {
+	param := s.parameter([]float64{ratio})
+	metric := func(v *Vehicle) (float64, error) {
+		for _,w := range v.Body.Wheelsets {
+			if w.Drive != nil && w.speedLimit() < s.MinTopSpeed {
+				return 0, fmt.Errorf("%s: top shaft speed limits the vehicle to %5.2fm/s", w.Name, w.speedLimit())
+			}
+		}
+		return s.Metric(v)
+	}
+	result, err := vehicle.measure([]Parameter{param}, []float64{ratio}, metric)
+	if err != nil {
+		return math.Inf(1), err
+	}
+	if s.Maximize {
+		return -result, nil
+	}
+	return result, nil
+}
+
+//OptimizeGearing sweeps the ratio across [Min,Max], then narrows in on the best point
+//with a golden section search between its neighbours. The vehicle itself is never
+//modified. Ratios the metric fails on, or that break MinTopSpeed, are never chosen
+func (vehicle *Vehicle)OptimizeGearing(s GearingSearch) (GearingResult, error) {
+	if s.Min <= 0 || s.Max <= s.Min {
+		return GearingResult{}, fmt.Errorf("Gearing search range must be positive and increasing")
+	}
+	if s.Metric == nil {
+		return GearingResult{}, fmt.Errorf("Gearing search requires a metric")
+	}
+	if s.Gearbox && s.Gear < 0 {
+		return GearingResult{}, fmt.Errorf("Gear must not be negative")
+	}
+	found := false
+	for _,w := range vehicle.Body.Wheelsets {
+		if w.Drive == nil || (s.Wheelset != "" && w.Name != s.Wheelset) {
+			continue
+		}
+		if s.Gearbox && (w.Drive.Gearbox == nil || s.Gear >= len(w.Drive.Gearbox.Ratios)) {
+			return GearingResult{}, fmt.Errorf("%s: no gear %d to search", w.Name, s.Gear)
+		}
+		found = true
+	}
+	if !found {
+		return GearingResult{}, fmt.Errorf("No driven wheelset named %q", s.Wheelset)
+	}
+
+	values := Steps(s.Min, s.Max, gearingSteps)
+	costs := make([]float64, len(values))
+	table := SweepTable{Parameters: []string{"Ratio"}, Rows: make([]SweepRow, len(values))}
+	parallel(len(values), func(i int) {
+		row := &table.Rows[i]
+		row.Values = []float64{values[i]}
+		costs[i], row.Err = vehicle.gearingCost(&s, values[i])
+		if row.Err == nil {
+			row.Result = costs[i]
+			if s.Maximize {
+				row.Result = -costs[i]
+			}
+		}
+	})
+	best := 0
+	for i := range costs {
+		if costs[i] < costs[best] {
+			best = i
+		}
+	}
+	if math.IsInf(costs[best], 1) {
+		return GearingResult{Sweep: table}, fmt.Errorf("No ratio on the range works: %v", table.Rows[best].Err)
+	}
+
+	//golden section search between the neighbours of the best sweep point
+	low := values[int(math.Max(0, float64(best - 1)))]
+	high := values[int(math.Min(float64(len(values) - 1), float64(best + 1)))]
+	ratio, cost := goldenSection(low, high, gearingTolerance, values[best], costs[best], func(x float64) float64 {
+		c, _ := vehicle.gearingCost(&s, x)
+		return c
+	})
+	if s.Maximize {
+		cost = -cost
+	}
+	return GearingResult{Ratio: ratio, Result: cost, Sweep: table}, nil
+}
+
+//goldenSection narrows [low,high] down to tolerance around the lowest cost, returning
+//the best point found and its cost. best and its cost are the point to beat, usually
+//the sweep point the range was found around
+func goldenSection(low, high, tolerance, best, bestCost float64, cost func(float64) float64) (float64, float64) {
+	invPhi := (math.Sqrt(5) - 1)/2
+	a := high - invPhi*(high - low)
+	b := low + invPhi*(high - low)
+	costA := cost(a)
+	costB := cost(b)
+	for high - low > tolerance {
+		if costA < costB {
+			high, b, costB = b, a, costA
+			a = high - invPhi*(high - low)
+			costA = cost(a)
+		} else {
+			low, a, costA = a, b, costB
+			b = low + invPhi*(high - low)
+			costB = cost(b)
+		}
+	}
+	if costA < bestCost {
+		best, bestCost = a, costA
+	}
+	if costB < bestCost {
+		best, bestCost = b, costB
+	}
+	return best, bestCost
+}
@@ -0,0 +1,3 @@
+module github.com/evantandersen/automotiveSim
+
+go 1.23
@@ -0,0 +1,142 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+const (
+	maxGradeSearch = 10.0 //rise over run, far steeper than any road
+	gradeTolerance = 1e-4
+	startAccel = 0.05 //m/s^2, what pulling away from rest needs beyond holding still
+)
+
+//Gradeability is the steepest grade the vehicle can climb at a steady speed
+type Gradeability struct {
+	Speed float64 //m/s
+	Grade float64 //rise over run
+	Reason LimitReason //what stops it going steeper or faster, LimitNone if nothing did
+}
+
+//holds reports whether the vehicle can climb grade at speed without slowing, or pull
+//away on it from rest, in whichever gear suits it best
+func (sim *SimulatorState)holds(speed, grade float64) error {
+	sim.Speed = speed
+	sim.Grade = grade
+	accel := 0.0
+	if speed == 0 {
+		accel = startAccel
+	}
+	gears := 1
+	for _,w := range sim.Vehicle.Body.Wheelsets {
+		if w.Drive != nil && w.Drive.Gearbox != nil && len(w.Drive.Gearbox.Ratios) > gears {
+			gears = len(w.Drive.Gearbox.Ratios)
+		}
+	}
+	var err error
+	for gear := 0; gear < gears; gear++ {
+		for _,w := range sim.Vehicle.Body.Wheelsets {
+			if w.Drive != nil && w.Drive.Gearbox != nil {
+				w.Drive.Gearbox.gear = gear
+				if gear >= len(w.Drive.Gearbox.Ratios) {
+					w.Drive.Gearbox.gear = len(w.Drive.Gearbox.Ratios) - 1
+				}
+			}
+		}
+		err = sim.CanOperate(accel)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+//MaxGrade finds the steepest grade the vehicle can climb at a steady speed (m/s). At
+//zero speed it is the startability, the steepest grade it can pull away on
+func (vehicle *Vehicle)MaxGrade(speed float64) (Gradeability, error) {
+	if speed < 0 {
+		return Gradeability{}, fmt.Errorf("Speed must not be negative")
+	}
+	v, err := vehicle.Clone()
+	if err != nil {
+		return Gradeability{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return Gradeability{}, err
+	}
+
+	result := Gradeability{Speed: speed}
+	err = sim.holds(speed, 0)
+	if err != nil {
+		return result, fmt.Errorf("Vehicle can not hold %5.2fm/s on level ground: %v", speed, err)
+	}
+	err = sim.holds(speed, maxGradeSearch)
+	if err == nil {
+		result.Grade = maxGradeSearch
+		return result, nil
+	}
+	low, high := 0.0, maxGradeSearch
+	for high - low > gradeTolerance {
+		mid := (low + high)/2
+		midErr := sim.holds(speed, mid)
+		if midErr == nil {
+			low = mid
+		} else {
+			high, err = mid, midErr
+		}
+	}
+	result.Grade = low
+	result.Reason = asLimit(err)
+	return result, nil
+}
+
+//MaxSpeedOnGrade finds the highest steady speed the vehicle can hold up grade (rise
+//over run, negative downhill)
+func (vehicle *Vehicle)MaxSpeedOnGrade(grade float64) (Gradeability, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return Gradeability{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return Gradeability{}, err
+	}
+
+	//scan upwards for the first speed past the ones it can hold
+	result := Gradeability{Grade: grade}
+	lastGood := 0.0
+	speed := cruiseSearchStep
+	for ; speed < cruiseSearchMax; speed += cruiseSearchStep {
+		speedErr := sim.holds(speed, grade)
+		if speedErr == nil {
+			lastGood = speed
+		} else {
+			err = speedErr
+			if lastGood > 0 {
+				break
+			}
+		}
+	}
+	if lastGood == 0 {
+		return result, fmt.Errorf("Vehicle can not climb a %4.1f%% grade at any speed: %v", grade*100, err)
+	}
+	if speed >= cruiseSearchMax {
+		result.Speed = lastGood
+		return result, nil
+	}
+
+	low, high := lastGood, speed
+	for high - low > 0.01 {
+		mid := (low + high)/2
+		midErr := sim.holds(mid, grade)
+		if midErr == nil {
+			low = mid
+		} else {
+			high, err = mid, midErr
+		}
+	}
+	result.Speed = low
+	result.Reason = asLimit(err)
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package automotiveSim
+
+
+import (
+	"testing"
+	"time"
+)
+
+//testVehicleJSON is a mid-size rear drive EV
+const testVehicleJSON = `{
+	"Accessory": 500,
+	"Battery": {"NominalVoltage": 350, "Resistance": 0.1, "Coulomb": 700000, "MaxCurrent": 1000, "ChargerEfficency": 0.9},
+	"Body": {"Weight": 1800, "CdA": 0.6, "Wheelsets": [
+		{"Name": "Front", "WeightDistribution": 0.5, "Tires": {"Grip": 1.0, "RollingResistance": 0.01, "Radius": 0.33}},
+		{"Name": "Rear", "WeightDistribution": 0.5, "Tires": {"Grip": 1.0, "RollingResistance": 0.01, "Radius": 0.33},
+			"Drive": {"Gearing": 9.7, "Efficiency": 0.97, "Motor": {"Name": "M", "Peak": {"Torque": 400, "Power": 250000},
+				"Continuous": {"Torque": 200, "Power": 100000}, "MaxShaftSpeed": 2600, "Efficiency": 0.92}}}
+	]},
+	"Ambient": {"Temperature": 293, "Pressure": 101325}
+}`
+
+//testVehicle is a fresh, initialized copy of the test vehicle
+func testVehicle(t *testing.T) *Vehicle {
+	t.Helper()
+	v, err := Parse([]byte(testVehicleJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+//testSimulation simulates v after applying any changes made to it
+func testSimulation(t *testing.T, v *Vehicle) *SimulatorState {
+	t.Helper()
+	err := v.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim, err := InitSimulation(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+//testCycle pulls away to 30 m/s, holds it for five minutes and stops again
+func testCycle() *Schedule {
+	s := &Schedule{Name: "test", Interval: time.Second}
+	for i := 0; i <= 60; i++ {
+		s.Speeds = append(s.Speeds, float64(i)*0.5)
+	}
+	for i := 0; i < 300; i++ {
+		s.Speeds = append(s.Speeds, 30)
+	}
+	for i := 60; i >= 0; i-- {
+		s.Speeds = append(s.Speeds, float64(i)*0.5)
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type HybridMode int
+
+const (
+	Series HybridMode = iota //engine drives a generator, only the motor turns the wheels
+	Parallel //engine and motor share the drive shaft
+)
+
+//EnergyManagement is the supervisory controller of a hybrid drive
+type EnergyManagement interface {
+	//EnginePower is the mechanical power (W) the engine should make this tick, given
+	//the power demanded at the drive shaft (negative when braking). Zero turns it off
+	EnginePower(sim *SimulatorState, h *Hybrid, demand float64) float64
+}
+
+//EngineEvent marks the engine of a hybrid starting or stopping
+type EngineEvent struct {
+	Time time.Duration
+	On bool
+}
+
+//Hybrid adds an engine to a drive that also has an electric motor
+type Hybrid struct {
+	Mode HybridMode
+	Engine Engine
+	GeneratorEfficiency float64 //series only, engine shaft to battery bus
+	GeneratorRPM float64 //series only, the genset runs at this fixed speed
+	Strategy EnergyManagement `json:"-"` //defaults to ChargeSustaining. Set in code, not in a description
+
+	//state
+	engineOn bool
+	engineSpeed float64 //rad/s the engine would turn this tick, for the strategy
+}
+
+func (h *Hybrid)Init() error {
+	err := h.Engine.Init()
+	if err != nil {
+		return fmt.Errorf("Engine: %v", err)
+	}
+	if h.Mode == Series {
+		if h.GeneratorEfficiency <= 0 || h.GeneratorEfficiency > 1 {
+			return fmt.Errorf("Generator efficiency must be on the range (0,1]")
+		}
+		if h.GeneratorRPM < h.Engine.IdleRPM || h.GeneratorRPM > h.Engine.RedlineRPM {
+			return fmt.Errorf("Generator speed must be between engine idle and redline")
+		}
+	} else if h.Mode != Parallel {
+		return fmt.Errorf("Unknown hybrid mode %d", h.Mode)
+	}
+	if h.Strategy == nil {
+		h.Strategy = ChargeSustaining{}
+	}
+	h.engineOn = false
+	return nil
+}
+
+//EngineOn reports whether the engine ran on the last tick
+func (h *Hybrid)EngineOn() bool {
+	return h.engineOn
+}
+
+//split decides the engine torque for a parallel hybrid delivering shaftTorque at
+//shaftSpeed. The motor makes up the rest
+func (h *Hybrid)split(sim *SimulatorState, motor *Motor, shaftSpeed, shaftTorque float64) (engineTorque float64, on bool) {
+	if shaftTorque <= 0 || shaftSpeed <= 0 {
+		return 0, false
+	}
+	h.engineSpeed = shaftSpeed
+	power := h.Strategy.EnginePower(sim, h, shaftSpeed * shaftTorque)
+	engineMax, _ := h.Engine.MaxTorque(sim, shaftSpeed)
+	motorMax, _ := motor.MaxTorque(sim, shaftSpeed)
+
+	//the engine has to cover whatever the motor can't
+	engineTorque = math.Max(power / shaftSpeed, shaftTorque - motorMax)
+	engineTorque = math.Min(math.Max(engineTorque, 0), engineMax)
+	return engineTorque, engineTorque > 0
+}
+
+//generate decides the electrical power (W) a series genset feeds to the bus this tick
+func (h *Hybrid)generate(sim *SimulatorState, demand float64) (electrical, engineSpeed, engineTorque float64) {
+	engineSpeed = h.GeneratorRPM * rpmToRadS
+	h.engineSpeed = engineSpeed
+	power := h.Strategy.EnginePower(sim, h, demand)
+	if power <= 0 {
+		return 0, engineSpeed, 0
+	}
+	engineMax, _ := h.Engine.MaxTorque(sim, engineSpeed)
+	engineTorque = math.Min(power / engineSpeed, engineMax)
+	return engineTorque * engineSpeed * h.GeneratorEfficiency, engineSpeed, engineTorque
+}
+
+//setEngine records the engine state after a tick, noting any start or stop
+func (h *Hybrid)setEngine(sim *SimulatorState, on bool) {
+	if on != h.engineOn {
+		sim.EngineEvents = append(sim.EngineEvents, EngineEvent{Time: sim.Time, On: on})
+	}
+	h.engineOn = on
+}
+
+//ChargeSustaining keeps the battery around TargetSOC. The engine starts when the state of
+//charge falls Band below the target and stops once it is Band above it. While running it
+//follows the demand plus ChargePower to recharge the battery
+type ChargeSustaining struct {
+	TargetSOC float64 //defaults to the middle of the battery's usable window
+	Band float64 //defaults to 0.05
+	ChargePower float64 //W, defaults to 10kW
+}
+
+func (c ChargeSustaining)EnginePower(sim *SimulatorState, h *Hybrid, demand float64) float64 {
+	b := &sim.Vehicle.Battery
+	target := c.TargetSOC
+	if target == 0 {
+		target = (b.MinSOC + b.MaxSOC)/2
+	}
+	band := c.Band
+	if band == 0 {
+		band = 0.05
+	}
+	charge := c.ChargePower
+	if charge == 0 {
+		charge = 10000
+	}
+
+	on := h.engineOn
+	soc := b.StateOfCharge()
+	if soc < target - band {
+		on = true
+	} else if soc > target + band {
+		on = false
+	}
+	if !on {
+		return 0
+	}
+	return math.Max(demand, 0) + charge
+}
@@ -1,13 +1,26 @@
 package automotiveSim
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	standardTemperature = 293.15 //kelvin
 )
 
 
 func Parse(vehicleJSON []byte) (*Vehicle, error) {        
     var vehicle Vehicle
-    err := json.Unmarshal(vehicleJSON, &vehicle)
+    vehicleJSON, err := migrateVehicle(vehicleJSON)
+    if err != nil {
+        return nil, err
+    }
+    err = json.Unmarshal(vehicleJSON, &vehicle)
     if err != nil {
         return nil, err
     }
@@ -20,3 +33,203 @@ func Parse(vehicleJSON []byte) (*Vehicle, error) {
     return &vehicle, nil
 }
 
+//ValidationError points at one bad field in a vehicle description
+type ValidationError struct {
+	Field string //path into the description, e.g. Body.Wheelsets[1].Tires.Radius
+	Reason string
+	Range string //typical values, as a hint
+}
+
+func (e *ValidationError)Error() string {
+	msg := e.Reason
+	if e.Field != "" {
+		msg = e.Field + ": " + msg
+	}
+	if e.Range != "" {
+		msg += " (typically " + e.Range + ")"
+	}
+	return msg
+}
+
+//ValidationErrors is every problem found in a vehicle description
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors)Error() string {
+	lines := make([]string, len(e))
+	for i,err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+//LoadVehicle reads a vehicle description from a JSON file
+func LoadVehicle(path string) (*Vehicle, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return nil, fmt.Errorf("%s: YAML vehicle files are not supported, convert to JSON", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vehicle, err := ParseVehicle(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vehicle, nil
+}
+
+//ParseVehicle is a stricter Parse. Unknown fields are rejected, unset ambient conditions
+//default to 20C and the standard pressure at Altitude, and every bad field is reported
+//as a ValidationError. Fields measuring a physical quantity may be given as a string with
+//a unit, like "4000 lb" or "300 hp", which must measure the same thing as the field.
+//Descriptions from older schema versions are migrated first, see VehicleSchemaVersion
+func ParseVehicle(vehicleJSON []byte) (*Vehicle, error) {
+	var vehicle Vehicle
+	vehicleJSON, err := migrateVehicle(vehicleJSON)
+	if err != nil {
+		return nil, ValidationErrors{{Reason: err.Error()}}
+	}
+	vehicleJSON, err = convertUnits(vehicleJSON)
+	if errs, ok := err.(ValidationErrors); ok {
+		return nil, errs
+	}
+	if err != nil {
+		return nil, ValidationErrors{{Reason: err.Error()}}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(vehicleJSON))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&vehicle)
+	if err != nil {
+		return nil, ValidationErrors{{Reason: err.Error()}}
+	}
+
+	vehicle.applyDefaults()
+	errs := vehicle.validate()
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	//Init checks the relationships between fields that validate doesn't
+	err = vehicle.Init()
+	if err != nil {
+		return nil, ValidationErrors{{Reason: err.Error()}}
+	}
+	return &vehicle, nil
+}
+
+func (v *Vehicle)applyDefaults() {
+	if v.Ambient.Temperature == 0 {
+		v.Ambient.Temperature = standardTemperature
+	}
+	if v.Ambient.Pressure == 0 {
+		v.Ambient.Pressure = pressureAtAltitude(v.Ambient.Altitude)
+	}
+}
+
+//validate checks each field on its own against its allowed range
+func (v *Vehicle)validate() ValidationErrors {
+	var errs ValidationErrors
+	check := func(ok bool, field, reason, typical string) {
+		if !ok {
+			errs = append(errs, &ValidationError{Field: field, Reason: reason, Range: typical})
+		}
+	}
+
+	check(v.Accessory >= 0, "Accessory", "must not be negative", "200-1000 W")
+	check(v.SpeedLimiter >= 0, "SpeedLimiter", "must not be negative", "25-70 m/s")
+	if v.LowVoltage != nil {
+		l := v.LowVoltage
+		check(l.Load >= 0, "LowVoltage.Load", "must not be negative", "150-600 W")
+		if len(l.EfficiencyCurve) == 0 {
+			check(l.Efficiency > 0 && l.Efficiency <= 1, "LowVoltage.Efficiency", "must be on the range (0,1]", "0.90-0.96")
+		}
+		check(l.MaxPower == 0 || l.Load <= l.MaxPower, "LowVoltage.MaxPower", "must cover the load", "1500-3000 W")
+	}
+
+	b := v.Battery
+	if b.Cell != nil {
+		c := b.Cell
+		check(b.Series > 0, "Battery.Series", "must be positive", "96-200 cells")
+		check(b.Parallel > 0, "Battery.Parallel", "must be positive", "1-80 cells")
+		check(c.NominalVoltage > 0, "Battery.Cell.NominalVoltage", "must be positive", "3.2-3.7 V")
+		check(c.Capacity > 0, "Battery.Cell.Capacity", "must be positive", "3-200 Ah")
+		check(c.Resistance >= 0, "Battery.Cell.Resistance", "must not be negative", "0.0005-0.03 ohm")
+		check(c.MaxDischargeCurrent > 0, "Battery.Cell.MaxDischargeCurrent", "must be positive", "10-600 A")
+	} else {
+		check(b.NominalVoltage > 0, "Battery.NominalVoltage", "must be positive", "300-800 V")
+		check(b.Resistance >= 0, "Battery.Resistance", "must not be negative", "0.05-0.3 ohm")
+		check(b.Coulomb > 0, "Battery.Coulomb", "must be positive", "180000-720000 C (50-200 Ah)")
+		check(b.MaxCurrent > 0, "Battery.MaxCurrent", "must be positive", "200-1500 A")
+	}
+	check(b.ChargerEfficency > 0 && b.ChargerEfficency <= 1, "Battery.ChargerEfficency", "must be on the range (0,1]", "0.85-0.95")
+
+	body := v.Body
+	check(body.Weight > 0, "Body.Weight", "must be positive", "1000-3000 kg for cars")
+	check(body.CdA >= 0, "Body.CdA", "must not be negative", "0.5-1.0 m^2 for cars")
+	check(body.Wheelbase >= 0, "Body.Wheelbase", "must not be negative", "2.4-3.2 m for cars")
+	check(body.CGHeight >= 0, "Body.CGHeight", "must not be negative", "0.45-0.65 m for cars")
+	check(len(body.Wheelsets) != 0, "Body.Wheelsets", "at least one wheelset is required", "")
+	for i,w := range body.Wheelsets {
+		path := fmt.Sprintf("Body.Wheelsets[%d]", i)
+		check(w.WeightDistribution > 0 && w.WeightDistribution <= 1, path + ".WeightDistribution", "must be on the range (0,1]", "0.4-0.6")
+		check(w.Tires.Grip > 0, path + ".Tires.Grip", "must be positive", "0.7-1.2")
+		check(w.Tires.RollingResistance >= 0, path + ".Tires.RollingResistance", "must not be negative", "0.007-0.015")
+		check(w.Tires.RollingResistanceSpeed >= 0, path + ".Tires.RollingResistanceSpeed", "must not be negative", "0-0.0001 s/m")
+		check(w.Tires.RollingResistanceSpeedSquared >= 0, path + ".Tires.RollingResistanceSpeedSquared", "must not be negative", "0-0.000002 s^2/m^2")
+		check(w.Tires.Radius > 0, path + ".Tires.Radius", "must be positive", "0.28-0.40 m")
+		if w.Drive == nil {
+			continue
+		}
+		d := w.Drive
+		check(d.Gearing > 0, path + ".Drive.Gearing", "must be positive", "7-12")
+		for j,s := range d.Stages {
+			check(s.Efficiency > 0 && s.Efficiency <= 1, fmt.Sprintf("%s.Drive.Stages[%d].Efficiency", path, j), "must be on the range (0,1]", "0.97-0.99")
+		}
+		if len(d.Stages) == 0 && len(d.EfficiencyCurve) == 0 {
+			check(d.Efficiency > 0 && d.Efficiency <= 1, path + ".Drive.Efficiency", "must be on the range (0,1]", "0.95-0.98")
+		}
+		if d.Engine != nil {
+			e := d.Engine
+			check(len(e.Torque) != 0, path + ".Drive.Engine.Torque", "a torque curve is required", "100-400 Nm peak")
+			check(e.IdleRPM > 0, path + ".Drive.Engine.IdleRPM", "must be positive", "600-900 rpm")
+			check(e.RedlineRPM > e.IdleRPM, path + ".Drive.Engine.RedlineRPM", "must be above idle", "5000-7500 rpm")
+			check(len(e.BSFC.X) != 0, path + ".Drive.Engine.BSFC", "a fuel consumption map is required", "230-400 g/kWh")
+			continue
+		}
+		if d.Hybrid != nil {
+			e := d.Hybrid.Engine
+			check(len(e.Torque) != 0, path + ".Drive.Hybrid.Engine.Torque", "a torque curve is required", "100-300 Nm peak")
+			check(e.IdleRPM > 0, path + ".Drive.Hybrid.Engine.IdleRPM", "must be positive", "800-1200 rpm")
+			check(e.RedlineRPM > e.IdleRPM, path + ".Drive.Hybrid.Engine.RedlineRPM", "must be above idle", "4500-6500 rpm")
+			check(len(e.BSFC.X) != 0, path + ".Drive.Hybrid.Engine.BSFC", "a fuel consumption map is required", "210-350 g/kWh")
+			if d.Hybrid.Mode == Series {
+				check(d.Hybrid.GeneratorEfficiency > 0 && d.Hybrid.GeneratorEfficiency <= 1, path + ".Drive.Hybrid.GeneratorEfficiency", "must be on the range (0,1]", "0.88-0.95")
+				check(d.Hybrid.GeneratorRPM >= e.IdleRPM && d.Hybrid.GeneratorRPM <= e.RedlineRPM, path + ".Drive.Hybrid.GeneratorRPM", "must be between idle and redline", "2000-3500 rpm")
+			}
+		}
+		m := d.Motor
+		check(m.Continuous.Torque > 0, path + ".Drive.Motor.Continuous.Torque", "must be positive", "100-400 Nm")
+		check(m.Peak.Torque >= m.Continuous.Torque, path + ".Drive.Motor.Peak.Torque", "must be at least the continuous torque", "200-700 Nm")
+		if m.BaseSpeedRPM == 0 {
+			check(m.Continuous.Power > 0, path + ".Drive.Motor.Continuous.Power", "must be positive", "50000-200000 W")
+			check(m.Peak.Power >= m.Continuous.Power, path + ".Drive.Motor.Peak.Power", "must be at least the continuous power", "100000-400000 W")
+		}
+		check(m.MaxShaftSpeed > 0, path + ".Drive.Motor.MaxShaftSpeed", "must be positive", "1000-2200 rad/s")
+		if len(m.EfficiencyMap.X) == 0 {
+			check(m.Efficiency > 0 && m.Efficiency <= 1, path + ".Drive.Motor.Efficiency", "must be on the range (0,1]", "0.85-0.95")
+		}
+		if m.Inverter != nil {
+			check(m.Inverter.Standby >= 0, path + ".Drive.Motor.Inverter.Standby", "must not be negative", "20-150 W")
+			check(m.Inverter.SwitchingVoltage >= 0, path + ".Drive.Motor.Inverter.SwitchingVoltage", "must not be negative", "1-3 V")
+			check(m.Inverter.Resistance >= 0, path + ".Drive.Motor.Inverter.Resistance", "must not be negative", "0.002-0.02 ohm")
+		}
+	}
+
+	//a common slip is giving the temperature in celsius
+	check(v.Ambient.Temperature > 200, "Ambient.Temperature", "must be in kelvin", "250-320 K")
+	check(v.Ambient.RoadGrip >= 0, "Ambient.RoadGrip", "must not be negative", "0.1 (ice)-1 (dry)")
+	check(v.Ambient.Pressure > 0, "Ambient.Pressure", "must be positive", "70000-105000 Pa")
+	check(v.Ambient.Altitude >= -500 && v.Ambient.Altitude <= maxAltitude, "Ambient.Altitude", "must be within the troposphere", "0-4000 m")
+	return errs
+}
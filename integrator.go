@@ -0,0 +1,135 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultTolerance = 1e-4 //m/s
+	maxSubsteps = 64
+)
+
+type Integrator int
+
+const (
+	Euler Integrator = iota //forward Euler, one operating point per tick. The default
+	RK4 //fourth order Runge-Kutta on speed, trapezoidal rule on distance and energy
+	Adaptive //RK4, splitting each tick into substeps until they agree to within Tolerance
+)
+
+//SimulationOptions trades accuracy for speed, and sets how schedules are followed
+type SimulationOptions struct {
+	Integrator Integrator
+	Tolerance float64 //m/s of speed error per tick allowed by Adaptive, defaults to 1e-4
+	Driver Driver //follows schedules in Run, defaults to a PIDDriver. Holds state, so give each concurrent simulation its own
+	SpeedTolerance float64 //m/s either side of the trace a limited vehicle may fall before Run fails, defaults to 2mph
+}
+
+func (o *SimulationOptions)Init() error {
+	if o.Integrator < Euler || o.Integrator > Adaptive {
+		return fmt.Errorf("Unknown integrator %d", o.Integrator)
+	}
+	if o.Tolerance < 0 {
+		return fmt.Errorf("Integrator tolerance must not be negative")
+	}
+	if o.SpeedTolerance < 0 {
+		return fmt.Errorf("Speed tolerance must not be negative")
+	}
+	if o.SpeedTolerance == 0 {
+		o.SpeedTolerance = defaultSpeedTolerance
+	}
+	if o.Driver == nil {
+		o.Driver = &PIDDriver{}
+	}
+	return o.Driver.Init()
+}
+
+//accelAt is the operating point with the vehicle momentarily at speed
+func (state *SimulatorState)accelAt(speed, targetAccel float64) (float64, error) {
+	saved := state.Speed
+	state.Speed = speed
+	accel, err := state.FindOperatingPoint(targetAccel)
+	state.Speed = saved
+	return accel, err
+}
+
+//rk4 is the average acceleration over a step of dt seconds starting at speed. The
+//limit is the one at the start of the step
+func (state *SimulatorState)rk4(speed, targetAccel, dt float64) (float64, error) {
+	k1, limit := state.accelAt(speed, targetAccel)
+	k2, _ := state.accelAt(speed + k1*dt/2, targetAccel)
+	k3, _ := state.accelAt(speed + k2*dt/2, targetAccel)
+	k4, _ := state.accelAt(speed + k3*dt, targetAccel)
+	return (k1 + 2*k2 + 2*k3 + k4)/6, limit
+}
+
+//substeps finds how finely the next tick has to be split for one RK4 step to agree
+//with two half steps to within the tolerance
+func (state *SimulatorState)substeps(targetAccel float64) int {
+	tolerance := state.Options.Tolerance
+	if tolerance == 0 {
+		tolerance = defaultTolerance
+	}
+	steps := 1
+	for steps < maxSubsteps {
+		dt := state.Interval.Seconds() / float64(steps)
+		full, _ := state.rk4(state.Speed, targetAccel, dt)
+		half, _ := state.rk4(state.Speed, targetAccel, dt/2)
+		mid := state.Speed + half*dt/2
+		second, _ := state.rk4(mid, targetAccel, dt/2)
+		if math.Abs((state.Speed + full*dt) - (mid + second*dt/2)) <= tolerance {
+			break
+		}
+		steps *= 2
+	}
+	return steps
+}
+
+//adaptiveStep runs one tick as RK4 substeps, returning the average acceleration over
+//the tick and the limit at its start
+func (state *SimulatorState)adaptiveStep(targetAccel float64) (float64, error) {
+	steps := state.substeps(targetAccel)
+	interval := state.Interval
+	startSpeed := state.Speed
+	state.Interval = interval / time.Duration(steps)
+
+	var limit error
+	for i := 0; i < steps; i++ {
+		accel, err := state.rk4(state.Speed, targetAccel, state.Interval.Seconds())
+		if state.CanOperate(accel) != nil {
+			accel, err = state.FindOperatingPoint(targetAccel)
+			if err != nil && state.CanOperate(accel) != nil {
+				//nowhere to operate, the rest of the tick doesn't happen
+				limit = err
+				break
+			}
+		}
+		if i == 0 {
+			limit = err
+		}
+		state.Operate(accel)
+	}
+	state.Interval = interval
+	return (state.Speed - startSpeed) / interval.Seconds(), limit
+}
+
+//endPower is the bus power at the end of a tick at accel, for the trapezoidal rule.
+//If the vehicle couldn't operate there the power at the start is used instead
+func (state *SimulatorState)endPower(accel, startPower float64) float64 {
+	saved := state.Speed
+	state.Speed += accel * state.Interval.Seconds()
+	defer func() { state.Speed = saved }()
+
+	power, err := state.Vehicle.Body.CanOperate(state, accel)
+	if err != nil {
+		return startPower
+	}
+	power += state.Vehicle.auxiliaryPower()
+	if state.Vehicle.Climate != nil {
+		power += state.Vehicle.Climate.Load(state)
+	}
+	return power
+}
@@ -0,0 +1,35 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+//cycleEnergy is the energy the test vehicle draws over the test cycle
+func cycleEnergy(t *testing.T, integrator Integrator, interval time.Duration) float64 {
+	t.Helper()
+	sim := testSimulation(t, testVehicle(t))
+	sim.Interval = interval
+	sim.Options.Integrator = integrator
+	result, err := sim.Run(testCycle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Energy
+}
+
+func TestRK4ConvergesAtCoarseInterval(t *testing.T) {
+	reference := cycleEnergy(t, Euler, 10*time.Millisecond)
+	euler := cycleEnergy(t, Euler, time.Second)
+	if miss := math.Abs(euler - reference)/reference; miss < 0.003 {
+		t.Fatalf("Euler at 1s within %.3f%% of the 10ms energy, expected it to be off", miss*100)
+	}
+	for _,integrator := range []Integrator{RK4, Adaptive} {
+		energy := cycleEnergy(t, integrator, time.Second)
+		if miss := math.Abs(energy - reference)/reference; miss > 0.0005 {
+			t.Errorf("integrator %d at 1s off the 10ms energy by %.3f%%", integrator, miss*100)
+		}
+	}
+}
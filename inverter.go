@@ -0,0 +1,55 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+//Inverter is the power electronics between the pack and a motor. Its losses are a fixed
+//Standby, switching losses growing with current and conduction losses growing with its
+//square, so they don't shrink in step with the load the way a constant efficiency does
+type Inverter struct {
+	Standby float64 //W, gate drive and control, lost whenever the drive is on
+	SwitchingVoltage float64 //V, switching and diode losses per amp of DC current
+	Resistance float64 //ohm, conduction losses
+
+	//optional, DC power magnitude (W) to efficiency. Replaces SwitchingVoltage and
+	//Resistance, Standby still applies
+	EfficiencyCurve Curve
+}
+
+func (inv *Inverter)Init() error {
+	if inv.Standby < 0 {
+		return fmt.Errorf("Inverter standby power must not be negative")
+	}
+	if inv.SwitchingVoltage < 0 {
+		return fmt.Errorf("Inverter switching voltage must not be negative")
+	}
+	if inv.Resistance < 0 {
+		return fmt.Errorf("Inverter resistance must not be negative")
+	}
+	err := inv.EfficiencyCurve.Init()
+	if err != nil {
+		return fmt.Errorf("Inverter efficiency: %v", err)
+	}
+	for _,p := range inv.EfficiencyCurve {
+		if p.Y <= 0 || p.Y > 1 {
+			return fmt.Errorf("Inverter efficiency must be on the range (0,1]")
+		}
+	}
+	return nil
+}
+
+//loss is the power lost converting power (W, negative while regenerating) for the motor
+func (inv *Inverter)loss(sim *SimulatorState, power float64) float64 {
+	if len(inv.EfficiencyCurve) != 0 {
+		return inv.Standby + math.Abs(et(power, inv.EfficiencyCurve.At(math.Abs(power))) - power)
+	}
+	voltage := sim.BusVoltage
+	if voltage <= 0 {
+		voltage = sim.Vehicle.Battery.NominalVoltage
+	}
+	current := math.Abs(power) / voltage
+	return inv.Standby + inv.SwitchingVoltage * current + inv.Resistance * current * current
+}
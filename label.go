@@ -0,0 +1,83 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	labelAdjustment = 0.7 //EPA's derived 5-cycle factor, applied to 2-cycle economy and range
+	labelCityShare = 0.55 //of combined fuel use, the rest is highway
+	usGallon = 3.785411784 //liters
+)
+
+//EPALabel is the fuel economy and range a window sticker would show, from the two-cycle
+//test with EPA's 0.7 adjustment standing in for the full 5-cycle procedure. Electricity is
+//counted from the wall, through the charger
+type EPALabel struct {
+	CityMPGe float64
+	HighwayMPGe float64
+	CombinedMPGe float64
+	Consumption float64 //J/m of electricity from the wall, combined
+	Range float64 //m on a full charge, zero if the battery isn't what moves the vehicle
+}
+
+//labelCycle is one of the two test cycles, unadjusted
+type labelCycle struct {
+	mpge float64
+	battery float64 //J/m
+}
+
+//EPALabel runs the city (UDDS) and highway (HWFET) cycles, which cycles.LoadTrace reads
+//from EPA's uddscol.txt and hwycol.txt, and adjusts the results to label values
+func (vehicle *Vehicle)EPALabel(city, highway *Schedule) (EPALabel, error) {
+	if city == nil || highway == nil {
+		return EPALabel{}, fmt.Errorf("EPA label requires city and highway cycles")
+	}
+	c, err := vehicle.labelCycle(city)
+	if err != nil {
+		return EPALabel{}, err
+	}
+	h, err := vehicle.labelCycle(highway)
+	if err != nil {
+		return EPALabel{}, err
+	}
+
+	label := EPALabel{
+		CityMPGe: c.mpge * labelAdjustment,
+		HighwayMPGe: h.mpge * labelAdjustment,
+	}
+	//fuel use, not economy, is what averages
+	label.CombinedMPGe = 1 / (labelCityShare/label.CityMPGe + (1 - labelCityShare)/label.HighwayMPGe)
+	battery := (labelCityShare * c.battery + (1 - labelCityShare) * h.battery) / labelAdjustment
+	label.Consumption = battery / vehicle.Battery.ChargerEfficency
+	if battery > 0 {
+		label.Range = vehicle.Battery.UsableEnergy() / battery
+	}
+	return label, nil
+}
+
+func (vehicle *Vehicle)labelCycle(cycle *Schedule) (labelCycle, error) {
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return labelCycle{}, err
+	}
+	result, err := sim.Run(cycle)
+	if err != nil {
+		return labelCycle{}, fmt.Errorf("%s: %v", cycle.Name, err)
+	}
+	if result.Distance <= 0 {
+		return labelCycle{}, fmt.Errorf("%s: schedule does not cover any distance", cycle.Name)
+	}
+	//charge sustaining hybrids can end a cycle with more charge than they started with
+	wall := math.Max(0, result.Energy) / vehicle.Battery.ChargerEfficency
+	gallons := result.Fuel / usGallon + wall / GallonEquivalent
+	if gallons <= 0 {
+		return labelCycle{}, fmt.Errorf("%s: vehicle used no energy", cycle.Name)
+	}
+	return labelCycle{
+		mpge: (result.Distance / Mile) / gallons,
+		battery: result.Energy / result.Distance,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	launchSteps = 8 //shift speeds tried across each gear's range before refining
+	launchTolerance = 0.1 //m/s, how closely each shift speed is found
+	launchEarliestShift = 0.3 //fraction of a gear's top speed the earliest upshift tried is at
+)
+
+//LaunchResult is the fastest acceleration run PerfectLaunch found, and the shifts it took
+type LaunchResult struct {
+	AccelProfile
+	ShiftSpeeds []float64 //m/s the vehicle leaves each gear at, first gear first. Empty without a gearbox
+}
+
+//gearTopSpeeds is the speed (m/s) each gear runs out at, the lowest of any drive's,
+//nil if no drive has a gearbox. Gearboxes are taken to have the same number of gears
+func (v *Vehicle)gearTopSpeeds() []float64 {
+	var speeds []float64
+	for _,w := range v.Body.Wheelsets {
+		if w.Drive == nil || w.Drive.Gearbox == nil {
+			continue
+		}
+		for i,r := range w.Drive.Gearbox.Ratios {
+			speed := w.Drive.maxShaftSpeed() * w.Tires.Radius / (w.Drive.Gearing * r)
+			if i < len(speeds) {
+				speeds[i] = math.Min(speeds[i], speed)
+			} else {
+				speeds = append(speeds, speed)
+			}
+		}
+	}
+	return speeds
+}
+
+//launchWith is the acceleration profile with every gearbox on shifts
+func (v *Vehicle)launchWith(shifts []float64) (AccelProfile, error) {
+	c, err := v.Clone()
+	if err != nil {
+		return AccelProfile{}, err
+	}
+	strategy := SpeedShift{Upshift: append([]float64(nil), shifts...)}
+	for _,w := range c.Body.Wheelsets {
+		if w.Drive != nil && w.Drive.Gearbox != nil {
+			w.Drive.Gearbox.Strategy = strategy
+		}
+	}
+	return c.RunAccelerationProfile()
+}
+
+//launchCost is the quarter mile time with shifts[gear] moved to speed
+func (v *Vehicle)launchCost(shifts []float64, gear int, speed float64) float64 {
+	trial := append([]float64(nil), shifts...)
+	trial[gear] = speed
+	profile, err := v.launchWith(trial)
+	if err != nil {
+		return math.Inf(1)
+	}
+	return profile.QuarterMile
+}
+
+//PerfectLaunch is the fastest realistic run a driver could make: the driven tires held at
+//peak grip off the line whatever traction control the vehicle has, the throttle giving
+//as much as they take, and every upshift at the speed that gives the best quarter mile.
+//Shift speeds are searched a gear at a time, first gear first, the later ones left at
+//their gear's top speed until it is their turn. The vehicle itself is never modified
+func (vehicle *Vehicle)PerfectLaunch() (LaunchResult, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return LaunchResult{}, err
+	}
+	v.Body.TractionControl = nil
+	
+	tops := v.gearTopSpeeds()
+	if len(tops) == 0 {
+		profile, err := v.RunAccelerationProfile()
+		return LaunchResult{AccelProfile: profile}, err
+	}
+	shifts := make([]float64, len(tops) - 1)
+	for i := range shifts {
+		shifts[i] = tops[i] * shiftHeadroom
+	}
+	for gear := range shifts {
+		values := Steps(tops[gear] * launchEarliestShift, tops[gear] * shiftHeadroom, launchSteps)
+		costs := make([]float64, len(values))
+		parallel(len(values), func(i int) {
+			costs[i] = v.launchCost(shifts, gear, values[i])
+		})
+		best := 0
+		for i := range costs {
+			if costs[i] < costs[best] {
+				best = i
+			}
+		}
+		if math.IsInf(costs[best], 1) {
+			return LaunchResult{}, fmt.Errorf("No shift out of gear %d completes a quarter mile", gear + 1)
+		}
+		low := values[int(math.Max(0, float64(best - 1)))]
+		high := values[int(math.Min(float64(len(values) - 1), float64(best + 1)))]
+		shifts[gear], _ = goldenSection(low, high, launchTolerance, values[best], costs[best], func(speed float64) float64 {
+			return v.launchCost(shifts, gear, speed)
+		})
+	}
+	profile, err := v.launchWith(shifts)
+	return LaunchResult{AccelProfile: profile, ShiftSpeeds: shifts}, err
+}
@@ -0,0 +1,81 @@
+package automotiveSim
+
+
+import (
+	"testing"
+	"time"
+)
+
+//threeSpeed gives the test vehicle a three speed gearbox, low grip tires and a traction
+//control that cuts torque hard once they spin
+func threeSpeed(t *testing.T) *Vehicle {
+	v := testVehicle(t)
+	drive := v.Body.Wheelsets[1].Drive
+	drive.Gearing = 4
+	drive.Gearbox = &Gearbox{Ratios: []float64{3, 1.6, 1}, ShiftTime: 200 * time.Millisecond}
+	for i := range v.Body.Wheelsets {
+		v.Body.Wheelsets[i].Tires.Grip = 0.7
+	}
+	v.Body.TractionControl = &TractionControl{Strategy: TorqueCut, TorqueCut: 0.4}
+	err := v.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestPerfectLaunchBeatsStockLaunch(t *testing.T) {
+	v := threeSpeed(t)
+	stock, err := v.RunAccelerationProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	launch, err := v.PerfectLaunch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if launch.QuarterMile >= stock.QuarterMile || launch.Accel100 >= stock.Accel100 {
+		t.Fatalf("perfect launch ran 0-100 in %.2fs and the quarter in %.2fs, stock %.2fs and %.2fs",
+			launch.Accel100, launch.QuarterMile, stock.Accel100, stock.QuarterMile)
+	}
+	if v.Body.TractionControl == nil || v.Body.Wheelsets[1].Drive.Gearbox.Strategy != (PerformanceShift{}) {
+		t.Fatal("the vehicle was modified")
+	}
+
+	tops := v.gearTopSpeeds()
+	if len(launch.ShiftSpeeds) != 2 {
+		t.Fatalf("%d shift speeds for three gears", len(launch.ShiftSpeeds))
+	}
+	for i,speed := range launch.ShiftSpeeds {
+		if speed < tops[i] * launchEarliestShift || speed > tops[i] * shiftHeadroom {
+			t.Fatalf("shift out of gear %d at %.1f m/s, outside its %.1f m/s range", i + 1, speed, tops[i])
+		}
+	}
+	//shifting at the redline is among what was tried
+	redline, err := v.launchWith([]float64{tops[0] * shiftHeadroom, tops[1] * shiftHeadroom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if launch.QuarterMile > redline.QuarterMile {
+		t.Fatalf("quarter mile %.2fs, slower than %.2fs shifting at the redline", launch.QuarterMile, redline.QuarterMile)
+	}
+}
+
+func TestPerfectLaunchWithoutGearbox(t *testing.T) {
+	v := testVehicle(t)
+	v.Body.TractionControl = &TractionControl{Strategy: TorqueCut}
+	launch, err := v.PerfectLaunch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(launch.ShiftSpeeds) != 0 {
+		t.Fatalf("shift speeds %v without a gearbox", launch.ShiftSpeeds)
+	}
+	held, err := testVehicle(t).RunAccelerationProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if launch.QuarterMile != held.QuarterMile {
+		t.Fatalf("quarter mile %.2fs, not the %.2fs with the tires held at peak grip", launch.QuarterMile, held.QuarterMile)
+	}
+}
@@ -0,0 +1,140 @@
+package automotiveSim
+
+
+import (
+	"errors"
+)
+
+//LimitKind is what kept the vehicle from doing what was asked
+type LimitKind int
+
+const (
+	LimitNone LimitKind = iota
+	LimitMotorSpeed
+	LimitMotorTorque //constant torque, below base speed
+	LimitMotorPower //constant power, above base speed
+	LimitFieldWeakening //power falling away at high speed, see Motor.PowerRolloff
+	LimitMotorTemperature
+	LimitRegenPower
+	LimitEngineRedline
+	LimitEngineTorque
+	LimitEngineRegen //an engine can't brake the car by regenerating
+	LimitTireGrip
+	LimitTraction //wheelspin, the driven tires can't put down any more force
+	LimitShifting
+	LimitFreewheel
+	LimitPackCurrent
+	LimitChargeCurrent
+	LimitBatteryTemperature
+	LimitBatteryFull
+	LimitBatteryDepleted
+	LimitSpeedLimiter //the vehicle's electronic governor, see Vehicle.SpeedLimiter
+	limitKinds //how many kinds there are, not a limit
+)
+
+var limitNames = map[LimitKind]string{
+	LimitNone: "None",
+	LimitMotorSpeed: "Maximum shaft speed",
+	LimitMotorTorque: "Maximum torque",
+	LimitMotorPower: "Maximum power",
+	LimitFieldWeakening: "Field weakening",
+	LimitMotorTemperature: "Motor temperature",
+	LimitRegenPower: "Maximum regen power",
+	LimitEngineRedline: "Engine redline",
+	LimitEngineTorque: "Engine torque",
+	LimitEngineRegen: "Engine can not regenerate",
+	LimitTireGrip: "Tire grip",
+	LimitTraction: "Traction limited",
+	LimitShifting: "Shifting",
+	LimitFreewheel: "Freewheel",
+	LimitPackCurrent: "Exceeds max pack current",
+	LimitChargeCurrent: "Exceeds max charge current",
+	LimitBatteryTemperature: "Battery temperature",
+	LimitBatteryFull: "Battery full",
+	LimitBatteryDepleted: "Battery Energy depleted",
+	LimitSpeedLimiter: "Speed limiter",
+}
+
+func (k LimitKind)String() string {
+	return limitNames[k]
+}
+
+//MarshalText writes the kind by name, so JSON output stays readable
+func (k LimitKind)MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+//LimitReason is the error returned by Tick (and the CanOperate checks under it) when the
+//vehicle can't do what was asked
+type LimitReason struct {
+	Kind LimitKind
+	Component string //motor, engine or wheelset name, empty for the battery and whole vehicle
+	Headroom float64 //fraction of the limit left at the last attempt, negative when exceeded
+}
+
+func (l LimitReason)Error() string {
+	if l.Component == "" {
+		return l.Kind.String()
+	}
+	return l.Kind.String() + " (" + l.Component + ")"
+}
+
+//Is matches another LimitReason of the same kind, and the same component if it names one,
+//so errors.Is(err, LimitReason{Kind: LimitTireGrip}) finds any grip limit
+func (l LimitReason)Is(target error) bool {
+	t, ok := target.(LimitReason)
+	if !ok {
+		return false
+	}
+	return t.Kind == l.Kind && (t.Component == "" || t.Component == l.Component)
+}
+
+//battery reports whether the kind is a limit of the pack rather than the wheelsets
+func (k LimitKind)battery() bool {
+	switch k {
+	case LimitPackCurrent, LimitChargeCurrent, LimitBatteryTemperature, LimitBatteryFull, LimitBatteryDepleted:
+		return true
+	}
+	return false
+}
+
+//same reports whether two reasons name the same limit, whatever the headroom
+func (l LimitReason)same(other LimitReason) bool {
+	return l.Kind == other.Kind && l.Component == other.Component
+}
+
+//asLimit extracts the LimitReason from err, LimitNone if there isn't one
+func asLimit(err error) LimitReason {
+	if reason, ok := err.(LimitReason); ok {
+		return reason
+	}
+	var reason LimitReason
+	errors.As(err, &reason)
+	return reason
+}
+
+//limitErrors is every LimitReason a component can report, boxed once by Init so
+//reporting a limit on every tick doesn't allocate
+type limitErrors [limitKinds]error
+
+func (l *limitErrors)init(component string) {
+	for kind := range l {
+		l[kind] = LimitReason{Kind: LimitKind(kind), Component: component}
+	}
+}
+
+//of is the error for kind, boxing a new one if init hasn't filled it in
+func (l *limitErrors)of(kind LimitKind, component string) error {
+	if err := l[kind]; err != nil {
+		return err
+	}
+	return LimitReason{Kind: kind, Component: component}
+}
+
+//headroom is the fraction of limit left after demand
+func headroom(limit, demand float64) float64 {
+	if limit <= 0 {
+		return -1
+	}
+	return (limit - demand)/limit
+}
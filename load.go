@@ -0,0 +1,71 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+//Trailer is towed behind the body. Its mass adds to what the drive accelerates and
+//lifts up grades, but none of it rests on the vehicle's tires
+type Trailer struct {
+	Mass float64 //kg
+	CdA float64 //m^2, drag it adds to the vehicle's own
+	RollingResistance float64 //coefficient for the trailer's own tires
+}
+
+func (t *Trailer)Init() error {
+	if t.Mass < 0 {
+		return fmt.Errorf("Trailer mass must not be negative")
+	}
+	if t.CdA < 0 {
+		return fmt.Errorf("Trailer drag area must not be negative")
+	}
+	if t.RollingResistance < 0 {
+		return fmt.Errorf("Trailer rolling resistance must not be negative")
+	}
+	return nil
+}
+
+//LoadConfig is what a vehicle is carrying for one run: passengers and cargo spread like
+//the body's own weight, and optionally a trailer
+type LoadConfig struct {
+	Payload float64 //kg
+	Trailer *Trailer
+}
+
+//WithLoad returns a copy of the vehicle carrying load, leaving the vehicle itself as is
+func (vehicle *Vehicle)WithLoad(load LoadConfig) (*Vehicle, error) {
+	if load.Payload < 0 {
+		return nil, fmt.Errorf("Payload must not be negative")
+	}
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil, err
+	}
+	v.Body.Weight += load.Payload
+	if load.Trailer != nil {
+		trailer := *load.Trailer
+		v.Body.Trailer = &trailer
+	}
+	err = v.Init()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//Mass is everything the drive has to accelerate, trailer included, in kg
+func (b *Body)Mass() float64 {
+	if b.Trailer == nil {
+		return b.Weight
+	}
+	return b.Weight + b.Trailer.Mass
+}
+
+//trailerDrag is the rolling resistance of the trailer's tires
+func (b *Body)trailerDrag(sim *SimulatorState) float64 {
+	if b.Trailer == nil {
+		return 0
+	}
+	return b.Trailer.Mass * gravity * b.Trailer.RollingResistance
+}
@@ -0,0 +1,63 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+//LowVoltage is the 12V system: lights, infotainment, pumps, fans and controllers, fed
+//from the traction pack through a DC-DC converter
+type LowVoltage struct {
+	Load float64 //W drawn by the 12V loads
+	Efficiency float64 //of the DC-DC converter
+	EfficiencyCurve Curve //optional, converter output (W) to efficiency. Overrides Efficiency
+	Standby float64 //optional, W the converter draws whatever the load
+	MaxPower float64 //optional, converter output rating in W
+}
+
+func (l *LowVoltage)Init() error {
+	if l.Load < 0 {
+		return fmt.Errorf("Low voltage load must not be negative")
+	}
+	if len(l.EfficiencyCurve) == 0 && (l.Efficiency <= 0 || l.Efficiency > 1) {
+		return fmt.Errorf("DC-DC efficiency must be on the range (0,1]")
+	}
+	err := l.EfficiencyCurve.Init()
+	if err != nil {
+		return fmt.Errorf("DC-DC efficiency: %v", err)
+	}
+	for _,p := range l.EfficiencyCurve {
+		if p.Y <= 0 || p.Y > 1 {
+			return fmt.Errorf("DC-DC efficiency must be on the range (0,1]")
+		}
+	}
+	if l.Standby < 0 {
+		return fmt.Errorf("DC-DC standby power must not be negative")
+	}
+	if l.MaxPower < 0 {
+		return fmt.Errorf("DC-DC rating must not be negative")
+	}
+	if l.MaxPower > 0 && l.Load > l.MaxPower {
+		return fmt.Errorf("Low voltage load of %.0fW is more than the DC-DC converter's %.0fW", l.Load, l.MaxPower)
+	}
+	return nil
+}
+
+//Draw is the 12V load and what the converter loses supplying it, both in W
+func (l *LowVoltage)Draw() (load, loss float64) {
+	efficiency := l.Efficiency
+	if len(l.EfficiencyCurve) != 0 {
+		efficiency = l.EfficiencyCurve.At(l.Load)
+	}
+	return l.Load, l.Load/efficiency - l.Load + l.Standby
+}
+
+//auxiliaryPower is what everything but the drives and climate draws from the pack
+func (v *Vehicle)auxiliaryPower() float64 {
+	power := v.Accessory
+	if v.LowVoltage != nil {
+		load, loss := v.LowVoltage.Draw()
+		power += load + loss
+	}
+	return power
+}
@@ -0,0 +1,44 @@
+package automotiveSim
+
+
+import (
+	"math"
+)
+
+//ConsumptionMetrics is a run's energy use in the usual units. Electricity is counted as
+//drawn from the pack, so divide by the charger efficiency for wall figures. Values that
+//don't apply (fuel economy with no fuel burned) are zero
+type ConsumptionMetrics struct {
+	WhPerKm float64
+	WhPerMile float64
+	KWhPer100km float64
+	KWhPer100Miles float64
+	LitersPer100km float64 //fuel burned
+	MPG float64 //US miles per gallon of fuel burned
+	MPGe float64 //miles per gallon equivalent, fuel and electricity both counted
+	LitersPer100kmEquivalent float64 //fuel and electricity both counted as liters of gasoline
+}
+
+//Consumption converts the run's energy and fuel into ConsumptionMetrics. Net charging over
+//the run, as a hybrid can do, counts as no electricity used
+func (r ScheduleResult)Consumption() ConsumptionMetrics {
+	if r.Distance <= 0 {
+		return ConsumptionMetrics{}
+	}
+	wh := math.Max(0, r.Energy) / 3600
+	km, miles := r.Distance / 1000, r.Distance / Mile
+	m := ConsumptionMetrics{
+		WhPerKm: wh / km,
+		WhPerMile: wh / miles,
+		KWhPer100km: wh / 1000 / km * 100,
+		KWhPer100Miles: wh / 1000 / miles * 100,
+		LitersPer100km: r.LitersPer100km(),
+		MPG: r.MPG(),
+	}
+	gallons := r.Fuel / usGallon + math.Max(0, r.Energy) / GallonEquivalent
+	if gallons > 0 {
+		m.MPGe = miles / gallons
+		m.LitersPer100kmEquivalent = gallons * usGallon / km * 100
+	}
+	return m
+}
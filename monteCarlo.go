@@ -0,0 +1,178 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+//Distribution draws random parameter values
+type Distribution interface {
+	Sample(r *rand.Rand) float64
+}
+
+type Normal struct {
+	Mean float64
+	StdDev float64
+}
+
+func (d Normal)Sample(r *rand.Rand) float64 {
+	return d.Mean + r.NormFloat64() * d.StdDev
+}
+
+type Uniform struct {
+	Min float64
+	Max float64
+}
+
+func (d Uniform)Sample(r *rand.Rand) float64 {
+	return d.Min + r.Float64() * (d.Max - d.Min)
+}
+
+type Triangular struct {
+	Min float64
+	Mode float64
+	Max float64
+}
+
+func (d Triangular)Sample(r *rand.Rand) float64 {
+	//inverse of the cumulative distribution
+	u := r.Float64()
+	width := d.Max - d.Min
+	if width <= 0 {
+		return d.Mode
+	}
+	split := (d.Mode - d.Min)/width
+	if u < split {
+		return d.Min + math.Sqrt(u * width * (d.Mode - d.Min))
+	}
+	return d.Max - math.Sqrt((1 - u) * width * (d.Max - d.Mode))
+}
+
+//Variable is a randomly sampled parameter, Apply sets the drawn value on a private copy
+//of the vehicle
+type Variable struct {
+	Name string
+	Distribution Distribution
+	Apply func(v *Vehicle, value float64)
+}
+
+//MonteCarloMetric is a metric reported by name in the results
+type MonteCarloMetric struct {
+	Name string
+	Metric Metric
+}
+
+//Statistics summarizes one metric over every successful run
+type Statistics struct {
+	Samples []float64 //sorted
+	Failures int //runs where the metric returned an error
+}
+
+func (s Statistics)Mean() float64 {
+	if len(s.Samples) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _,x := range s.Samples {
+		total += x
+	}
+	return total / float64(len(s.Samples))
+}
+
+//Percentile interpolates between samples, p on the range [0,100]
+func (s Statistics)Percentile(p float64) float64 {
+	if len(s.Samples) == 0 {
+		return 0
+	}
+	pos := math.Max(0, math.Min(1, p/100)) * float64(len(s.Samples) - 1)
+	i := int(pos)
+	if i + 1 >= len(s.Samples) {
+		return s.Samples[len(s.Samples) - 1]
+	}
+	frac := pos - float64(i)
+	return s.Samples[i] + frac * (s.Samples[i+1] - s.Samples[i])
+}
+
+//MonteCarlo measures the given number of runs, each on a copy of the vehicle with every
+//variable drawn afresh.
+//Values are all drawn up front from seed, so the same seed gives the same results however
+//the runs are scheduled. The results are keyed by metric name
+func (vehicle *Vehicle)MonteCarlo(vars []Variable, metrics []MonteCarloMetric, runs int, seed int64) (map[string]Statistics, error) {
+	return vehicle.MonteCarloContext(context.Background(), vars, metrics, runs, seed)
+}
+
+//MonteCarloContext is MonteCarlo, giving up with ctx's error once ctx is done
+func (vehicle *Vehicle)MonteCarloContext(ctx context.Context, vars []Variable, metrics []MonteCarloMetric, runs int, seed int64) (map[string]Statistics, error) {
+	return vehicle.MonteCarloSource(ctx, vars, metrics, runs, rand.NewSource(seed))
+}
+
+//MonteCarloSource is MonteCarloContext drawing every value from source, for callers that
+//share one source of randomness across features
+func (vehicle *Vehicle)MonteCarloSource(ctx context.Context, vars []Variable, metrics []MonteCarloMetric, runs int, source rand.Source) (map[string]Statistics, error) {
+	if source == nil {
+		return nil, fmt.Errorf("Monte Carlo requires a source of randomness")
+	}
+	if runs <= 0 {
+		return nil, fmt.Errorf("Monte Carlo requires at least one run")
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("Monte Carlo requires a metric")
+	}
+	params := make([]Parameter, len(vars))
+	for i,v := range vars {
+		if v.Distribution == nil || v.Apply == nil {
+			return nil, fmt.Errorf("Variable %s requires a distribution and an Apply function", v.Name)
+		}
+		params[i] = Parameter{Name: v.Name, Apply: v.Apply}
+	}
+	for _,m := range metrics {
+		if m.Metric == nil {
+			return nil, fmt.Errorf("Metric %s has no function", m.Name)
+		}
+	}
+
+	r := rand.New(source)
+	values := make([][]float64, runs)
+	for i := range values {
+		values[i] = make([]float64, len(vars))
+		for j,v := range vars {
+			values[i][j] = v.Distribution.Sample(r)
+		}
+	}
+
+	results := make([][]float64, runs)
+	errs := make([][]error, runs)
+	parallel(runs, func(i int) {
+		results[i] = make([]float64, len(metrics))
+		errs[i] = make([]error, len(metrics))
+		if ctx.Err() != nil {
+			return
+		}
+		for j,m := range metrics {
+			results[i][j], errs[i][j] = vehicle.measure(params, values[i], m.Metric)
+		}
+	})
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	stats := make(map[string]Statistics)
+	for j,m := range metrics {
+		s := Statistics{}
+		for i := range results {
+			if errs[i][j] != nil {
+				s.Failures++
+			} else {
+				s.Samples = append(s.Samples, results[i][j])
+			}
+		}
+		sort.Float64s(s.Samples)
+		stats[m.Name] = s
+	}
+	return stats, nil
+}
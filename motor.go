@@ -2,11 +2,14 @@ package automotiveSim
 
 
 import (
-	"time"
 	"fmt"
 	"math"
 )
 
+const (
+	rpmToRadS = 2 * math.Pi / 60
+)
+
 type MotorPerformance struct {
 	Torque float64
 	Power float64
@@ -18,9 +21,45 @@ type Motor struct {
 	Continuous MotorPerformance
     MaxShaftSpeed float64
 	Efficiency float64
+	
+	//optional, efficiency against torque magnitude in Nm (X) and shaft speed in rad/s (Y).
+	//Replaces Efficiency, which is then not required
+	EfficiencyMap Map
+	
+	MaxRegenPower float64 //W, optional. Caps braking power, zero leaves only the torque/power envelope
+	
+	//optional, for datasheets that give torque and a base (corner) speed instead of power.
+	//any power left at zero is derived from its torque at this speed
+	BaseSpeedRPM float64
+	
+	//optional, the fraction of Peak and Continuous power left against shaft speed in rad/s,
+	//for power rolling off at high speed in field weakening. Defaults to 1 throughout
+	PowerRolloff Curve
+	
+	Thermal *Thermal //optional, derates peak output toward Continuous as the motor heats up
+	Inverter *Inverter //optional, power electronics losses on top of Efficiency
+	
+	//state
+	limits limitErrors
+	efficiency float64 //at the operating point of the last tick
 }
 
 func (m *Motor)Init() error {
+	if m.MaxRegenPower < 0 {
+		return fmt.Errorf("Maximum regen power must not be negative")
+	}
+	if m.BaseSpeedRPM < 0 {
+		return fmt.Errorf("Base speed must not be negative")
+	}
+	if m.BaseSpeedRPM > 0 {
+		baseSpeed := m.BaseSpeedRPM * rpmToRadS
+		if m.Peak.Power == 0 {
+			m.Peak.Power = m.Peak.Torque * baseSpeed
+		}
+		if m.Continuous.Power == 0 {
+			m.Continuous.Power = m.Continuous.Torque * baseSpeed
+		}
+	}
 	if m.Peak.Torque < m.Continuous.Torque {
 		return fmt.Errorf("Peak torque must not be less than continuous torque")
 	}
@@ -36,33 +75,117 @@ func (m *Motor)Init() error {
 	if m.MaxShaftSpeed <= 0 {
 		return fmt.Errorf("Maximum shaft speed must be positive")
 	}
-	if m.Efficiency <= 0 || m.Efficiency > 1 {
+	if len(m.EfficiencyMap.X) != 0 || len(m.EfficiencyMap.Y) != 0 {
+		err := m.EfficiencyMap.Init()
+		if err != nil {
+			return fmt.Errorf("Efficiency map: %v", err)
+		}
+		for _,row := range m.EfficiencyMap.Z {
+			for _,e := range row {
+				if e <= 0 || e > 1 {
+					return fmt.Errorf("Motor efficiency must be on the range (0,1]")
+				}
+			}
+		}
+	} else if m.Efficiency <= 0 || m.Efficiency > 1 {
 		return fmt.Errorf("Motor efficiency must be on the range (0,1]")
 	}
+	err := m.PowerRolloff.Init()
+	if err != nil {
+		return fmt.Errorf("Power rolloff: %v", err)
+	}
+	for _,p := range m.PowerRolloff {
+		if p.Y <= 0 || p.Y > 1 {
+			return fmt.Errorf("Power rolloff must be on the range (0,1]")
+		}
+	}
+	if m.Thermal != nil {
+		err := m.Thermal.Init()
+		if err != nil {
+			return fmt.Errorf("Thermal: %v", err)
+		}
+	}
+	if m.Inverter != nil {
+		err := m.Inverter.Init()
+		if err != nil {
+			return err
+		}
+	}
+	m.limits.init(m.Name)
 	return nil
 }
 
+//EfficiencyAt is the motor efficiency at an operating point, the same whether driving
+//or regenerating
+func (m *Motor)EfficiencyAt(shaftSpeed, torque float64) float64 {
+	if len(m.EfficiencyMap.X) == 0 {
+		return m.Efficiency
+	}
+	return m.EfficiencyMap.At(math.Abs(torque), math.Abs(shaftSpeed))
+}
+
 func (m *Motor)powerUse(shaftSpeed, torque float64) (mechanical, loss float64) {
 	mechanical = shaftSpeed * torque
-	total := et(mechanical, m.Efficiency)
-	loss = math.Abs(total) * (1 - m.Efficiency)
+	efficiency := m.EfficiencyAt(shaftSpeed, torque)
+	total := et(mechanical, efficiency)
+	loss = math.Abs(total) * (1 - efficiency)
 	return
 }
 
-func (m *Motor)MaxTorque(sim *SimulatorState, shaftSpeed) (float64, error) {
+func (m *Motor)MaxTorque(sim *SimulatorState, shaftSpeed float64) (float64, error) {
 	shaftSpeed = math.Abs(shaftSpeed)
 	if (shaftSpeed > m.MaxShaftSpeed) {
-		return 0, fmt.Errorf("Maximum shaft speed")
+		return 0, m.limits.of(LimitMotorSpeed, m.Name)
+	}
+	torque, power := m.Peak.Torque, m.Peak.Power
+	powerLimit, torqueLimit := LimitMotorPower, LimitMotorTorque
+	if m.Thermal != nil {
+		available := m.Thermal.available(sim)
+		if available < 1 {
+			torque = derated(m.Continuous.Torque, m.Peak.Torque, available)
+			power = derated(m.Continuous.Power, m.Peak.Power, available)
+			powerLimit, torqueLimit = LimitMotorTemperature, LimitMotorTemperature
+		}
+	}
+	if len(m.PowerRolloff) != 0 {
+		rolloff := m.PowerRolloff.At(shaftSpeed)
+		power *= rolloff
+		if rolloff < 1 && powerLimit == LimitMotorPower {
+			powerLimit = LimitFieldWeakening
+		}
 	}
-	if((m.Peak.Torque * shaftSpeed) > m.Peak.Power) {
-		return m.Peak.Power/shaftSpeed, fmt.Errorf("Maximum power")
+	if((torque * shaftSpeed) > power) {
+		return power/shaftSpeed, m.limits.of(powerLimit, m.Name)
 	}
-	return m.Peak.Torque, fmt.Errorf("Maximum torque")
+	return torque, m.limits.of(torqueLimit, m.Name)
 }
 
-func (m *Motor)Operate(sim *SimulatorState, shaftSpeed, torque float64) Power {
+//MaxRegenTorque is the largest braking torque the motor can absorb at this shaft speed
+func (m *Motor)MaxRegenTorque(sim *SimulatorState, shaftSpeed float64) (float64, error) {
+	torque, limit := m.MaxTorque(sim, shaftSpeed)
+	shaftSpeed = math.Abs(shaftSpeed)
+	if m.MaxRegenPower > 0 && (torque * shaftSpeed) > m.MaxRegenPower {
+		return m.MaxRegenPower/shaftSpeed, m.limits.of(LimitRegenPower, m.Name)
+	}
+	return torque, limit
+}
+
+//inverterLoss is what the inverter loses supplying the motor with power (W, negative
+//while regenerating) at its terminals
+func (m *Motor)inverterLoss(sim *SimulatorState, power float64) float64 {
+	if m.Inverter == nil {
+		return 0
+	}
+	return m.Inverter.loss(sim, power)
+}
+
+//Operate runs the motor for one tick, returning its shaft output, its losses and those of
+//its inverter. The battery supplies the sum
+func (m *Motor)Operate(sim *SimulatorState, shaftSpeed, torque float64) (mechanical, losses, inverter float64) {
 	mech, loss := m.powerUse(shaftSpeed, torque)
-	s.power["Losses"] = loss
-	s.power["Mechanical"] = mech
-	return mech + loss
+	m.efficiency = m.EfficiencyAt(shaftSpeed, torque)
+	if m.Thermal != nil {
+		m.Thermal.heat(sim, loss)
+	}
+	return mech, loss, m.inverterLoss(sim, mech + loss)
 }
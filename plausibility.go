@@ -0,0 +1,111 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	plausibleAccel = 2 * gravity //m/s^2, beyond what any tire can put down
+	highwaySpeed = 100 / 3.6 //m/s
+)
+
+//Validate checks the vehicle description without running it. errs are what would stop it
+//initializing, the same ValidationErrors ParseVehicle returns. warnings are inputs that
+//are allowed but physically implausible, most often a unit slip (a torque in lb-ft, a
+//radius given as a diameter) that would otherwise quietly give bad results. Warnings are
+//only looked for once there are no errors. The vehicle itself isn't modified
+func (v *Vehicle)Validate() (errs, warnings ValidationErrors) {
+	errs = v.validate()
+	if len(errs) != 0 {
+		return errs, nil
+	}
+	c, err := v.Clone()
+	if err != nil {
+		return ValidationErrors{{Reason: err.Error()}}, nil
+	}
+	return nil, c.plausibility()
+}
+
+//plausibility looks over an initialized vehicle for values outside what real ones have
+func (v *Vehicle)plausibility() ValidationErrors {
+	var warnings ValidationErrors
+	warn := func(ok bool, field, reason, typical string) {
+		if !ok {
+			warnings = append(warnings, &ValidationError{Field: field, Reason: reason, Range: typical})
+		}
+	}
+
+	b := &v.Body
+	warn(b.CdA >= 0.3 && b.CdA <= 1.5, "Body.CdA", fmt.Sprintf("%.2f m^2 is implausible for a road vehicle", b.CdA), "0.3-1.5 m^2, more for trucks and buses")
+	warn(b.Weight >= 300 && b.Weight <= 40000, "Body.Weight", fmt.Sprintf("%.0f kg is implausible", b.Weight), "800-3000 kg for cars")
+
+	mass := b.Mass()
+	launch, highway := 0.0, 0.0
+	for i,w := range b.Wheelsets {
+		field := fmt.Sprintf("Body.Wheelsets[%d]", i)
+		warn(w.Tires.Radius >= 0.2 && w.Tires.Radius <= 0.6, field + ".Tires.Radius", fmt.Sprintf("%.3f m is implausible, is it a diameter?", w.Tires.Radius), "0.28-0.40 m")
+		if w.Drive == nil {
+			continue
+		}
+		launch += w.peakForce(0)
+		highway += w.peakForce(highwaySpeed)
+
+		//the shaft speed at 100 km/h in top gear, and the top speed the shaft allows
+		d := w.Drive
+		top := d.Gearing
+		if d.Gearbox != nil {
+			top *= d.Gearbox.Ratios[len(d.Gearbox.Ratios)-1]
+		}
+		maxShaft := d.Motor.MaxShaftSpeed
+		if d.Engine != nil {
+			maxShaft = d.Engine.RedlineRPM * 2 * math.Pi / 60
+		}
+		rpm := highwaySpeed / w.Tires.Radius * top * 60 / (2 * math.Pi)
+		warn(rpm >= 500 && rpm <= 20000, field + ".Drive.Gearing", fmt.Sprintf("the drive turns at %.0f rpm at 100 km/h in top gear", rpm), "1500-3000 rpm for engines, 5000-14000 rpm for motors")
+		if maxShaft > 0 {
+			topSpeed := maxShaft * w.Tires.Radius / top
+			warn(topSpeed >= 15 && topSpeed <= 120, field + ".Drive", fmt.Sprintf("the drive tops out at %.0f km/h", topSpeed * 3.6), "120-300 km/h")
+		}
+	}
+	if mass > 0 {
+		warn(launch / mass <= plausibleAccel, "Body", fmt.Sprintf("peak torque would launch the vehicle at %.1f g", launch / mass / gravity), "under 1.5 g, tire grip permitting")
+		warn(highway / mass <= plausibleAccel, "Body", fmt.Sprintf("peak power would accelerate the vehicle at %.1f g at 100 km/h", highway / mass / gravity), "under 0.5 g")
+	}
+
+	bat := &v.Battery
+	sag := bat.MaxCurrent * bat.Resistance / bat.NominalVoltage
+	warn(sag <= 0.3, "Battery.Resistance", fmt.Sprintf("the pack sags %.0f%% at MaxCurrent", sag * 100), "under 15%")
+	return warnings
+}
+
+//peakForce is the most tractive force the drive can produce at speed, from its peak
+//torque and power in the lowest gear, before tire grip
+func (w Wheelset)peakForce(speed float64) float64 {
+	d := w.Drive
+	ratio := d.Gearing
+	if d.Gearbox != nil {
+		ratio *= d.Gearbox.Ratios[0]
+	}
+	shaftSpeed := speed / w.Tires.Radius * ratio
+	torque := 0.0
+	if d.Engine == nil || d.Hybrid != nil {
+		torque = d.Motor.Peak.Torque
+		if shaftSpeed > 0 && d.Motor.Peak.Power > 0 {
+			torque = math.Min(torque, d.Motor.Peak.Power / shaftSpeed)
+		}
+	}
+	engine := d.Engine
+	if d.Hybrid != nil && d.Hybrid.Mode == Parallel {
+		engine = &d.Hybrid.Engine
+	}
+	if engine != nil {
+		peak := 0.0
+		for _,p := range engine.Torque {
+			peak = math.Max(peak, p.Y)
+		}
+		torque += peak
+	}
+	return torque * ratio / w.Tires.Radius
+}
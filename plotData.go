@@ -0,0 +1,93 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"time"
+)
+
+//PlotSeries is a single labeled x/y series, ready to hand to a plotting frontend
+type PlotSeries struct {
+	Name string
+	XLabel string
+	XUnit string
+	YLabel string
+	YUnit string
+	X []float64
+	Y []float64
+}
+
+//PlotData is a self-describing set of series. It is kept separate from the
+//result structs so the export format can change without touching the physics
+type PlotData struct {
+	Title string
+	Series []PlotSeries
+}
+
+func (p *AccelProfile)PlotData() PlotData {
+	speed := PlotSeries{
+		Name: "Speed",
+		XLabel: "Time",
+		XUnit: "s",
+		YLabel: "Speed",
+		YUnit: "m/s",
+		X: make([]float64, len(p.Profile)),
+		Y: make([]float64, len(p.Profile)),
+	}
+	for i,s := range p.Profile {
+		speed.X[i] = (profileInterval * time.Duration(i)).Seconds()
+		speed.Y[i] = s
+	}
+	return PlotData{Title: "Acceleration Profile", Series: []PlotSeries{speed}}
+}
+
+//PlotData is the speed against time through the run
+func (r *ScheduleResult)PlotData() PlotData {
+	speed := PlotSeries{Name: "Speed", XLabel: "Time", XUnit: "s", YLabel: "Speed", YUnit: "m/s"}
+	for _,sample := range r.Profile {
+		speed.X = append(speed.X, sample.Time.Seconds())
+		speed.Y = append(speed.Y, sample.Speed)
+	}
+	return PlotData{Title: r.Name, Series: []PlotSeries{speed}}
+}
+
+//PowerPlotData is where the pack's power went through the run, one series per bucket of
+//PowerBreakdown, and the friction brakes
+func (r *ScheduleResult)PowerPlotData() PlotData {
+	buckets := []struct {
+		name string
+		power func(p *PowerBreakdown) float64
+	}{
+		{"Traction", func(p *PowerBreakdown) float64 { return p.Traction }},
+		{"Accessory", func(p *PowerBreakdown) float64 { return p.Accessory }},
+		{"Climate", func(p *PowerBreakdown) float64 { return p.Climate }},
+		{"Low voltage", func(p *PowerBreakdown) float64 { return p.LowVoltage + p.DCDC }},
+		{"Battery losses", func(p *PowerBreakdown) float64 { return p.Battery }},
+		{"Friction brakes", func(p *PowerBreakdown) float64 { return p.FrictionBrakes }},
+	}
+	data := PlotData{Title: r.Name + " Power"}
+	for _,b := range buckets {
+		series := PlotSeries{Name: b.name, XLabel: "Time", XUnit: "s", YLabel: "Power", YUnit: "kW"}
+		for i := range r.Profile {
+			series.X = append(series.X, r.Profile[i].Time.Seconds())
+			series.Y = append(series.Y, b.power(&r.Profile[i].Power)/1000)
+		}
+		data.Series = append(data.Series, series)
+	}
+	return data
+}
+
+//PlotData is the regen and friction brake power through the stop, showing where regen
+//blends out to the friction brakes
+func (s *BrakingStop)PlotData() PlotData {
+	regen := PlotSeries{Name: "Regen", XLabel: "Time", XUnit: "s", YLabel: "Power", YUnit: "kW"}
+	friction := PlotSeries{Name: "Friction brakes", XLabel: "Time", XUnit: "s", YLabel: "Power", YUnit: "kW"}
+	for i := range s.Profile {
+		sample := &s.Profile[i]
+		regen.X = append(regen.X, sample.Time.Seconds())
+		regen.Y = append(regen.Y, sample.Regen()/1000)
+		friction.X = append(friction.X, sample.Time.Seconds())
+		friction.Y = append(friction.Y, sample.Power.FrictionBrakes/1000)
+	}
+	return PlotData{Title: fmt.Sprintf("Braking from %.0f km/h", s.Speed * 3.6), Series: []PlotSeries{regen, friction}}
+}
@@ -0,0 +1,62 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAccelPlotDataStartsAtZero(t *testing.T) {
+	v := testVehicle(t)
+	profile, err := v.RunAccelerationProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	speed := profile.PlotData().Series[0]
+	if speed.X[0] != 0 || speed.Y[0] != 0 {
+		t.Fatalf("first point (%g s, %g m/s), want the standing start", speed.X[0], speed.Y[0])
+	}
+	for i := range speed.X {
+		if want := (profileInterval * time.Duration(i)).Seconds(); speed.X[i] != want {
+			t.Fatalf("point %d at %g s, want %g s", i, speed.X[i], want)
+		}
+	}
+	//the profile crosses 100km/h when the result says it does
+	i := int(math.Round(profile.Accel100 / profileInterval.Seconds()))
+	if math.Abs(speed.Y[i] - kph100) > 0.1 {
+		t.Fatalf("%.2f m/s at the 0-100 time, want %.2f m/s", speed.Y[i], kph100)
+	}
+}
+
+func TestScheduleResultPlotData(t *testing.T) {
+	result, err := testSimulation(t, testVehicle(t)).Run(testCycle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int(result.Duration/scheduleProfileInterval) + 1; len(result.Profile) != want {
+		t.Fatalf("%d samples over %v, want %d", len(result.Profile), result.Duration, want)
+	}
+	speed := result.PlotData().Series[0]
+	for i := range speed.X {
+		if speed.X[i] != float64(i) {
+			t.Fatalf("sample %d at %g s", i, speed.X[i])
+		}
+	}
+	//the cycle pulls away at 0.5 m/s^2
+	if math.Abs(speed.Y[20] - 10) > 0.2 {
+		t.Fatalf("%.2f m/s 20 s in, want 10 m/s", speed.Y[20])
+	}
+
+	power := result.PowerPlotData()
+	if len(power.Series) != 6 {
+		t.Fatalf("%d power buckets", len(power.Series))
+	}
+	traction := power.Series[0]
+	if traction.Name != "Traction" || traction.Y[200] <= 0 {
+		t.Fatalf("%s at %.1f kW cruising, want traction drawing power", traction.Name, traction.Y[200])
+	}
+	if accessory := power.Series[1].Y[200]; math.Abs(accessory - 0.5) > 1e-9 {
+		t.Fatalf("accessories at %.3f kW, want 0.5 kW", accessory)
+	}
+}
@@ -2,35 +2,64 @@ package automotiveSim
 
 
 import (
+	"strconv"
 )
 
-type Power map[string]interface{}
+//PowerBreakdown is where the power went on the last tick, in watts. It is overwritten
+//every tick, so copy it to keep it
+type PowerBreakdown struct {
+	Traction float64 //drawn by the drives, net of any series hybrid generation
+	Accessory float64
+	Climate float64
+	LowVoltage float64 //12V loads
+	DCDC float64 //lost converting for the 12V loads
+	Battery float64 //lost to the pack's internal resistance
+	FrictionBrakes float64 //dissipated in the friction brakes
+	Drives []DrivePower //one per wheelset in Body.Wheelsets order, zero for undriven ones
+}
 
-func (p Power)Total() float64 {
-	sum := 0.0
-	for _, val := range p {
-		switch t := val.(type) {
-			case float64:
-				sum += t
-				
-			case Power:
-				sum += t.Total()
-		}
-	}
-	return sum
+//DrivePower is one drive's share of a PowerBreakdown
+type DrivePower struct {
+	Mechanical float64 //motor shaft output, negative while regenerating
+	MotorLosses float64
+	InverterLosses float64
+	GearFriction float64 //lost in the gear meshes, growing with the load carried
+	SpinLoss float64 //bearing, seal and oil churning drag, loaded or not
+	Engine float64 //engine shaft output
+	Fuel float64 //chemical power of the fuel burned
+}
+
+//Total is the power drawn from the pack's cells
+func (p *PowerBreakdown)Total() float64 {
+	return p.Traction + p.Accessory + p.Climate + p.LowVoltage + p.DCDC + p.Battery
 }
 
-func (p Power)Copy() Power {
-	result := make(Power)
-	for key, val := range p {
-		switch t := val.(type) {
-			case float64:
-				result[key] = t
-				
-			case Power:
-				result[key] = t.Copy()
-		}
+//Copy is a copy that later ticks won't overwrite
+func (p PowerBreakdown)Copy() PowerBreakdown {
+	p.Drives = append([]DrivePower(nil), p.Drives...)
+	return p
+}
+
+//Flatten lists every entry with drive ones named by their path, e.g. "Drive 0/Gear friction"
+func (p *PowerBreakdown)Flatten() map[string]float64 {
+	result := map[string]float64{
+		"Traction": p.Traction,
+		"Accessory": p.Accessory,
+		"Climate": p.Climate,
+		"Low voltage": p.LowVoltage,
+		"DC-DC losses": p.DCDC,
+		"Battery/Internal Resistance": p.Battery,
+		"Friction brakes": p.FrictionBrakes,
+	}
+	for i,d := range p.Drives {
+		prefix := "Drive " + strconv.Itoa(i) + "/"
+		result[prefix + "Mechanical"] = d.Mechanical
+		result[prefix + "Motor losses"] = d.MotorLosses
+		result[prefix + "Inverter losses"] = d.InverterLosses
+		result[prefix + "Gear friction"] = d.GearFriction
+		result[prefix + "Spin loss"] = d.SpinLoss
+		result[prefix + "Engine"] = d.Engine
+		result[prefix + "Fuel"] = d.Fuel
 	}
 	return result
 }
-
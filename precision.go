@@ -0,0 +1,96 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"time"
+)
+
+//Precision is how finely Round reports each kind of value, as the step it is rounded to.
+//Zero fields take the defaults, which are fine enough for any comparison table and coarse
+//enough that regression snapshots don't churn on float noise
+type Precision struct {
+	Time float64 //s, defaults to 0.01
+	Speed float64 //m/s, defaults to 0.1 km/h
+	Accel float64 //m/s^2, defaults to 0.01
+	Distance float64 //m, defaults to 0.1
+	Energy float64 //J, defaults to 1 Wh
+	Power float64 //W, defaults to 1
+	Current float64 //A, defaults to 0.1
+	Temperature float64 //kelvin, defaults to 0.1
+	SOC float64 //fraction, defaults to 0.0001
+	Fuel float64 //liters, defaults to 0.001
+	Mass float64 //g, defaults to 0.1
+}
+
+func (p *Precision)Init() {
+	defaults := []struct {
+		step *float64
+		value float64
+	}{
+		{&p.Time, 0.01},
+		{&p.Speed, 0.1 * KilometersPerHour},
+		{&p.Accel, 0.01},
+		{&p.Distance, 0.1},
+		{&p.Energy, 3600},
+		{&p.Power, 1},
+		{&p.Current, 0.1},
+		{&p.Temperature, 0.1},
+		{&p.SOC, 0.0001},
+		{&p.Fuel, 0.001},
+		{&p.Mass, 0.1},
+	}
+	for _,d := range defaults {
+		if *d.step <= 0 {
+			*d.step = d.value
+		}
+	}
+}
+
+//roundStep rounds x to the nearest multiple of step
+func roundStep(x, step float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+	return math.Round(x/step) * step
+}
+
+//duration rounds d to the time step
+func (p *Precision)duration(d time.Duration) time.Duration {
+	step := time.Duration(p.Time * float64(time.Second))
+	if step <= 0 {
+		return d
+	}
+	return d.Round(step)
+}
+
+func (p *Precision)stats(s TickStatistics, step float64) TickStatistics {
+	return TickStatistics{
+		Min: roundStep(s.Min, step),
+		Max: roundStep(s.Max, step),
+		Mean: roundStep(s.Mean, step),
+		P50: roundStep(s.P50, step),
+		P95: roundStep(s.P95, step),
+		P99: roundStep(s.P99, step),
+	}
+}
+
+func (p *Precision)power(b PowerBreakdown) PowerBreakdown {
+	b.Traction = roundStep(b.Traction, p.Power)
+	b.Accessory = roundStep(b.Accessory, p.Power)
+	b.Climate = roundStep(b.Climate, p.Power)
+	b.LowVoltage = roundStep(b.LowVoltage, p.Power)
+	b.DCDC = roundStep(b.DCDC, p.Power)
+	b.Battery = roundStep(b.Battery, p.Power)
+	b.FrictionBrakes = roundStep(b.FrictionBrakes, p.Power)
+	b.Drives = nil
+	return b
+}
+
+func (p *Precision)energy(e EnergyBreakdown) EnergyBreakdown {
+	for _,x := range []*float64{&e.Aero, &e.Rolling, &e.Grade, &e.Kinetic, &e.Accessory, &e.Climate, &e.LowVoltage,
+		&e.DCDC, &e.Drivetrain, &e.Motor, &e.Inverter, &e.Battery, &e.FrictionBrakes, &e.Regen} {
+		*x = roundStep(*x, p.Energy)
+	}
+	return e
+}
@@ -0,0 +1,52 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+)
+
+//onStep reports whether x is a whole number of steps
+func onStep(x, step float64) bool {
+	n := x/step
+	return math.Abs(n - math.Round(n)) < 1e-6
+}
+
+func TestRoundPerField(t *testing.T) {
+	result, err := testSimulation(t, testVehicle(t)).Run(testCycle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := result.Round(Precision{})
+	if !onStep(r.Energy, 3600) || !onStep(r.Distance, 0.1) || !onStep(r.EndSOC, 0.0001) {
+		t.Fatalf("energy %g J, distance %g m, SOC %g not on their default steps", r.Energy, r.Distance, r.EndSOC)
+	}
+	if !onStep(r.Stats.Speed.Max, 0.1 * KilometersPerHour) || !onStep(r.Stats.BatteryCurrent.Max, 0.1) {
+		t.Fatalf("top speed %g m/s, peak current %g A not on their default steps", r.Stats.Speed.Max, r.Stats.BatteryCurrent.Max)
+	}
+	if math.Abs(r.Energy - result.Energy) > 1800 {
+		t.Fatalf("rounded %g J to %g J", result.Energy, r.Energy)
+	}
+	if onStep(result.Energy, 3600) {
+		t.Fatal("rounding changed the original")
+	}
+
+	fine := result.Round(Precision{Energy: 1})
+	if math.Abs(fine.Energy - math.Round(result.Energy)) > 1e-6 {
+		t.Fatalf("rounded %g J to %g J at 1 J", result.Energy, fine.Energy)
+	}
+}
+
+func TestRoundAccelProfile(t *testing.T) {
+	profile, err := testVehicle(t).RunAccelerationProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := profile.Round(Precision{})
+	if !onStep(r.Accel100, 0.01) || !onStep(r.QuarterMile, 0.01) {
+		t.Fatalf("times %g s, %g s not to 0.01 s", r.Accel100, r.QuarterMile)
+	}
+	if !onStep(r.TopSpeed, 0.1 * KilometersPerHour) || !onStep(r.PeakAccel, 0.01) {
+		t.Fatalf("top speed %g m/s, peak acceleration %g m/s^2 not on their steps", r.TopSpeed, r.PeakAccel)
+	}
+}
@@ -0,0 +1,108 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const preconditionInterval = time.Second
+
+//Preconditioning brings the parked vehicle's cabin and battery toward a temperature
+//before it sets off, so a winter trip doesn't start with a frozen cabin and a cold pack
+type Preconditioning struct {
+	Duration time.Duration //how long before departure it starts
+	CabinSetpoint float64 //kelvin, zero leaves the cabin alone. Needs Vehicle.Climate
+	BatteryTarget float64 //kelvin, zero leaves the pack alone. Needs Battery.Thermal and Battery.HeaterPower
+	FromGrid bool //plugged in, so the power comes from the wall through the charger, not the pack
+}
+
+func (p *Preconditioning)Init() error {
+	if p.Duration <= 0 {
+		return fmt.Errorf("Preconditioning duration must be positive")
+	}
+	if p.CabinSetpoint < 0 || p.BatteryTarget < 0 {
+		return fmt.Errorf("Preconditioning temperatures must be above absolute zero")
+	}
+	if p.CabinSetpoint == 0 && p.BatteryTarget == 0 {
+		return fmt.Errorf("Preconditioning requires a cabin setpoint or battery target")
+	}
+	return nil
+}
+
+//PreconditionResult is what preconditioning used and where it left the vehicle
+type PreconditionResult struct {
+	GridEnergy float64 //J from the wall
+	BatteryEnergy float64 //J drawn from the pack
+	CabinTemperature float64 //kelvin at departure, zero without a climate model
+	BatteryTemperature float64 //kelvin at departure, zero without a battery thermal model
+	SOC float64 //at departure
+}
+
+//Precondition runs p on the parked vehicle ahead of a schedule. The simulation clock
+//isn't advanced, so a Run afterwards starts from zero with the cabin and pack as they were
+//left, and the first kilometers of a cold trip show the load that's been taken off them
+func (sim *SimulatorState)Precondition(p Preconditioning) (PreconditionResult, error) {
+	err := p.Init()
+	if err != nil {
+		return PreconditionResult{}, err
+	}
+	v := sim.Vehicle
+	b := &v.Battery
+	if p.CabinSetpoint != 0 && v.Climate == nil {
+		return PreconditionResult{}, fmt.Errorf("Vehicle has no climate model to precondition the cabin with")
+	}
+	if p.BatteryTarget != 0 && (b.Thermal == nil || b.HeaterPower <= 0) {
+		return PreconditionResult{}, fmt.Errorf("Battery needs a thermal model and heater to precondition")
+	}
+
+	saved := sim.Interval
+	sim.Interval = preconditionInterval
+	defer func() { sim.Interval = saved }()
+	speed := sim.Speed
+	sim.Speed = 0
+	defer func() { sim.Speed = speed }()
+
+	var result PreconditionResult
+	dt := sim.Interval.Seconds()
+	for elapsed := time.Duration(0); elapsed < p.Duration; elapsed += sim.Interval {
+		load := 0.0
+		if p.CabinSetpoint != 0 {
+			load += v.Climate.condition(sim, p.CabinSetpoint)
+		}
+		if p.BatteryTarget != 0 {
+			t := b.Thermal
+			if t.temperature == 0 {
+				t.temperature = v.Ambient.Temperature
+			}
+			//the heater only warms, and holds the pack at target against the cold
+			needed := t.HeatCapacity * (p.BatteryTarget - t.temperature) / dt + t.Cooling * (t.temperature - v.Ambient.Temperature)
+			heater := math.Max(0, math.Min(b.HeaterPower, needed))
+			t.heat(sim, heater)
+			load += heater
+		}
+
+		if p.FromGrid {
+			energy := load * dt / b.ChargerEfficency
+			sim.Resources["Electricity"] += energy
+			result.GridEnergy += energy
+			continue
+		}
+		err := b.CanOperate(sim, load)
+		if err != nil {
+			return result, fmt.Errorf("Battery can't supply preconditioning: %w", err)
+		}
+		b.Operate(sim, load)
+		result.BatteryEnergy += load * dt
+	}
+
+	if v.Climate != nil {
+		result.CabinTemperature = v.Climate.CabinTemperature()
+	}
+	if b.Thermal != nil {
+		result.BatteryTemperature = b.Thermal.Temperature()
+	}
+	result.SOC = b.StateOfCharge()
+	return result, nil
+}
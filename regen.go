@@ -0,0 +1,38 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+//RegenLevels separates the regen a driver gets by lifting off the accelerator from what
+//the brake pedal blends in, as most EVs do. Lifting off regens at LiftOff; pressing the
+//brake raises regen up to BrakePedal before the friction brakes join in. Both are
+//decelerations from regen alone, on top of the road load, and the motors and pack can
+//still cap them lower
+type RegenLevels struct {
+	LiftOff float64 //m/s^2, zero coasts with no regen at all off the accelerator
+	BrakePedal float64 //m/s^2, at least LiftOff
+}
+
+func (r *RegenLevels)Init() error {
+	if r.LiftOff < 0 {
+		return fmt.Errorf("Lift-off regen must not be negative")
+	}
+	if r.BrakePedal <= 0 {
+		return fmt.Errorf("Brake pedal regen must be positive")
+	}
+	if r.BrakePedal < r.LiftOff {
+		return fmt.Errorf("Brake pedal regen must be at least the lift-off regen")
+	}
+	return nil
+}
+
+//level is the regen deceleration available with the accelerator lifted, or with the brake
+//pedal pressed
+func (r *RegenLevels)level(liftOff bool) float64 {
+	if liftOff {
+		return r.LiftOff
+	}
+	return r.BrakePedal
+}
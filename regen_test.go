@@ -0,0 +1,82 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+)
+
+//liftOffDecel is how hard the test vehicle slows from 25 m/s with both pedals released,
+//and the friction brake energy it took
+func liftOffDecel(t *testing.T, regen *RegenLevels) (float64, float64) {
+	v := testVehicle(t)
+	v.Battery.InitialSOC = 0.8 //room for the regen
+	v.Body.Regen = regen
+	sim := testSimulation(t, v)
+	sim.Speed = 25
+	accel, err := sim.LiftOff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return -accel, sim.FrictionBrakeEnergy
+}
+
+func TestLiftOffRegenLevel(t *testing.T) {
+	coast, _ := liftOffDecel(t, &RegenLevels{BrakePedal: 3})
+	gentle, friction := liftOffDecel(t, &RegenLevels{LiftOff: 0.5, BrakePedal: 3})
+	if friction != 0 {
+		t.Fatalf("lifting off used %.0f J of friction brakes", friction)
+	}
+	if math.Abs(gentle - coast - 0.5) > 0.01 {
+		t.Fatalf("lift-off regen slowed by %.3f m/s^2 over coasting, not 0.5", gentle - coast)
+	}
+	strong, _ := liftOffDecel(t, &RegenLevels{LiftOff: 2, BrakePedal: 3})
+	if strong <= gentle {
+		t.Fatalf("stronger lift-off regen slowed at %.2f m/s^2, no more than %.2f", strong, gentle)
+	}
+}
+
+func TestBrakePedalBlendsFriction(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.InitialSOC = 0.8
+	v.Body.Regen = &RegenLevels{LiftOff: 0.2, BrakePedal: 1}
+	sim := testSimulation(t, v)
+	sim.Speed = 25
+
+	//within the brake pedal's regen there's no friction, however little the lift-off gives
+	_, err := sim.Tick(-0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.FrictionBrakeEnergy != 0 {
+		t.Fatalf("braking within the pedal's regen used %.0f J of friction brakes", sim.FrictionBrakeEnergy)
+	}
+	_, err = sim.Tick(-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Power.FrictionBrakes <= 0 {
+		t.Fatal("friction brakes didn't take the demand beyond the pedal's regen")
+	}
+}
+
+func TestBrakePedalRegenCycleConsumption(t *testing.T) {
+	run := func(regen *RegenLevels) float64 {
+		v := testVehicle(t)
+		v.Body.Regen = regen
+		result, err := testSimulation(t, v).Run(testCycle())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Energy
+	}
+	full := run(nil)
+	strong := run(&RegenLevels{LiftOff: 0.1, BrakePedal: 2})
+	weak := run(&RegenLevels{LiftOff: 0.1, BrakePedal: 0.2})
+	if math.Abs(strong - full) > full * 1e-6 {
+		t.Fatalf("brake pedal regen beyond the cycle's 0.5 m/s^2 changed its energy from %.0f J to %.0f J", full, strong)
+	}
+	if weak <= full {
+		t.Fatalf("0.2 m/s^2 of brake pedal regen used %.0f J, no more than full regen's %.0f J", weak, full)
+	}
+}
@@ -0,0 +1,156 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	routeStep = 1.0 //meters between points of the speed profile
+	routeAccel = 1.5 //m/s^2, the most a driver asks for pulling away or up to a new limit
+	routeDecel = 2.0 //m/s^2, comfortable braking ahead of a lower limit
+	routeCreep = 0.5 //m/s, the slowest the driver rolls through a point limit of zero
+	maxRouteTime = 24 * time.Hour //gives up on a vehicle that never gets to the end
+)
+
+//segment is the index of the schedule entry in force at position (m along the route)
+func (input *Schedule)segment(position float64) int {
+	segment := 0
+	for i := 1; i < len(input.Distances) - 1; i++ {
+		if input.Distances[i] > position {
+			break
+		}
+		segment = i
+	}
+	return segment
+}
+
+//routeProfile is the target speed at every routeStep along the route. Each limit is
+//met by accelerating as hard as the driver and vehicle allow after it rises and
+//braking ahead of it when it falls
+func (sim *SimulatorState)routeProfile(input *Schedule) []float64 {
+	last := len(input.Distances) - 1
+	length := input.Distances[last] - input.Distances[0]
+	speeds := make([]float64, int(math.Ceil(length/routeStep)) + 1)
+	for j := range speeds {
+		speeds[j] = input.Speeds[input.segment(input.Distances[0] + float64(j)*routeStep)]
+	}
+	//point limits, and the finish
+	for i,d := range input.Distances {
+		if i == last || d == input.Distances[i+1] {
+			j := int(math.Round((d - input.Distances[0])/routeStep))
+			speeds[j] = math.Min(speeds[j], input.Speeds[i])
+		}
+	}
+
+	saved := sim.Grade
+	speeds[0] = math.Min(speeds[0], sim.Speed)
+	for j := 1; j < len(speeds); j++ {
+		prev := speeds[j-1]
+		sim.Grade = sim.routeGrade(input, float64(j-1)*routeStep)
+		accel, _ := sim.accelAt(prev, routeAccel)
+		speeds[j] = math.Min(speeds[j], math.Sqrt(math.Max(0, prev*prev + 2*accel*routeStep)))
+	}
+	sim.Grade = saved
+	for j := len(speeds) - 2; j >= 0; j-- {
+		next := speeds[j+1]
+		speeds[j] = math.Min(speeds[j], math.Sqrt(next*next + 2*routeDecel*routeStep))
+	}
+	return speeds
+}
+
+//routeGrade is the grade at position along the route
+func (sim *SimulatorState)routeGrade(input *Schedule, position float64) float64 {
+	if len(input.Elevation) != 0 {
+		return input.Elevation.Slope(sim.Distance + position)
+	}
+	if len(input.Grades) != 0 {
+		return input.Grades[input.segment(input.Distances[0] + position)]
+	}
+	return sim.Grade
+}
+
+//followRoute drives a distance indexed schedule, aiming a tick ahead on the speed
+//profile. Where the vehicle can't keep up it does what it can rather than failing
+func (sim *SimulatorState)followRoute(ctx context.Context, input *Schedule) error {
+	profile := sim.routeProfile(input)
+	last := len(input.Distances) - 1
+	length := input.Distances[last] - input.Distances[0]
+	start, startTime := sim.Distance, sim.Time
+	interval := sim.Interval.Seconds()
+	for sim.Distance - start < length {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if sim.Time - startTime > maxRouteTime {
+			return fmt.Errorf("%s: vehicle did not reach the end of the route", input.Name)
+		}
+		position := sim.Distance - start
+		segment := input.segment(input.Distances[0] + position)
+		if len(input.DragReduction) != 0 {
+			sim.DragReduction = input.DragReduction[segment]
+		}
+		if len(input.Headwind) != 0 {
+			sim.Headwind = input.Headwind[segment]
+		}
+		if len(input.Crosswind) != 0 {
+			sim.Crosswind = input.Crosswind[segment]
+		}
+		if len(input.Elevation) != 0 {
+			sim.Grade = input.Elevation.Slope(sim.Distance)
+		} else if len(input.Grades) != 0 {
+			sim.Grade = input.Grades[segment]
+		}
+		ahead := math.Min(position + math.Max(sim.Speed, routeCreep)*interval, length)
+		target := math.Max(profileAt(profile, ahead), routeCreep)
+		err := sim.routeTick((target - sim.Speed)/interval)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input.Name, err)
+		}
+	}
+	//come to a stop at the end if asked to
+	if input.Speeds[last] == 0 {
+		for sim.Speed > 1e-6 {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			speed := sim.Speed
+			err := sim.routeTick(math.Max(-routeDecel, -sim.Speed/interval))
+			if err != nil {
+				return fmt.Errorf("%s: %w", input.Name, err)
+			}
+			if sim.Speed >= speed {
+				return fmt.Errorf("%s: vehicle could not stop at the end of the route", input.Name)
+			}
+		}
+	}
+	return nil
+}
+
+//routeTick is one tick of a route. Limits are only an error when the vehicle can't go
+//on at all, e.g. the pack is flat
+func (sim *SimulatorState)routeTick(accel float64) error {
+	before := sim.Time
+	_, err := sim.Tick(accel)
+	if errors.Is(err, errDepleted) {
+		return err
+	}
+	if sim.Time == before {
+		return fmt.Errorf("Vehicle can not operate: %w", err)
+	}
+	return nil
+}
+
+//profileAt interpolates a routeStep spaced profile at position
+func profileAt(profile []float64, position float64) float64 {
+	j := int(position/routeStep)
+	if j >= len(profile) - 1 {
+		return profile[len(profile) - 1]
+	}
+	f := position/routeStep - float64(j)
+	return profile[j] + f*(profile[j+1] - profile[j])
+}
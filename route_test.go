@@ -0,0 +1,50 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRouteReportsDepletion(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.Coulomb = 2000
+	v.Battery.MinSOC = 0.1
+	sim := testSimulation(t, v)
+	route := &Schedule{Name: "route", Distances: []float64{0, 20000}, Speeds: []float64{25, 0}}
+
+	_, err := sim.RunContext(context.Background(), route)
+	if !errors.Is(err, errDepleted) {
+		t.Fatalf("got %v, want the pack depleted", err)
+	}
+}
+
+func TestBusRouteReportsDepletion(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.Coulomb = 2000
+	v.Battery.MinSOC = 0.1
+	err := v.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := BusRoute{Name: "line", Stops: EvenStops(20, 500, 20*time.Second), SpeedLimit: 14}
+
+	_, err = v.RunBusRoute(route)
+	if !errors.Is(err, errDepleted) {
+		t.Fatalf("got %v, want the pack depleted", err)
+	}
+}
+
+func TestRouteStopsWhenCancelled(t *testing.T) {
+	sim := testSimulation(t, testVehicle(t))
+	route := &Schedule{Name: "route", Distances: []float64{0, 20000}, Speeds: []float64{25, 0}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := sim.RunContext(ctx, route)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want the run cancelled", err)
+	}
+}
@@ -0,0 +1,94 @@
+package automotiveSim
+
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+//VehicleSchemaVersion is the version of the vehicle description format this release reads
+//and writes. Descriptions carry theirs as a top level SchemaVersion field; those without
+//one predate versioning and are version 1. Whenever a field is renamed, moved or removed
+//the version goes up and a migration is added, so stored descriptions keep loading
+const VehicleSchemaVersion = 3
+
+//migration upgrades a decoded description by one version, in place
+type migration func(doc map[string]interface{}) error
+
+//migrations[i] upgrades version i+1 to i+2
+var migrations = []migration{
+	migrateBatteryComponent,
+	migrateBatteryThermalMass,
+}
+
+//version 2: Battery no longer embeds Component, whose Power field was written out with it
+func migrateBatteryComponent(doc map[string]interface{}) error {
+	if battery, ok := doc["Battery"].(map[string]interface{}); ok {
+		delete(battery, "Power")
+	}
+	return nil
+}
+
+//version 3: Battery.ThermalMass is gone, the pack's heat capacity is Thermal.HeatCapacity.
+//A pack with a thermal model already has one; without it there is nothing to carry the
+//mass over into, since Thermal also needs its cooling and derate temperatures
+func migrateBatteryThermalMass(doc map[string]interface{}) error {
+	if battery, ok := doc["Battery"].(map[string]interface{}); ok {
+		delete(battery, "ThermalMass")
+	}
+	return nil
+}
+
+//migrateVehicle brings a vehicle description up to VehicleSchemaVersion and strips the
+//version field, leaving JSON that decodes into Vehicle
+func migrateVehicle(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	version := 1
+	if raw, ok := doc["SchemaVersion"]; ok {
+		number, ok := raw.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("SchemaVersion must be a number")
+		}
+		v, err := number.Int64()
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("SchemaVersion must be a positive integer")
+		}
+		version = int(v)
+		delete(doc, "SchemaVersion")
+	}
+	if version > VehicleSchemaVersion {
+		return nil, fmt.Errorf("Vehicle schema version %d is newer than this release supports (%d)", version, VehicleSchemaVersion)
+	}
+	for ; version < VehicleSchemaVersion; version++ {
+		err := migrations[version-1](doc)
+		if err != nil {
+			return nil, fmt.Errorf("Migrating vehicle schema version %d: %v", version, err)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+//MarshalVehicle writes a vehicle description stamped with VehicleSchemaVersion
+func MarshalVehicle(v *Vehicle) ([]byte, error) {
+	return json.MarshalIndent(struct {
+		SchemaVersion int
+		*Vehicle
+	}{VehicleSchemaVersion, v}, "", "\t")
+}
+
+//SaveVehicle writes a vehicle description to a JSON file, for LoadVehicle to read back
+func SaveVehicle(path string, v *Vehicle) error {
+	data, err := MarshalVehicle(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
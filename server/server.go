@@ -0,0 +1,253 @@
+//Package server runs the simulator behind an HTTP API, for front ends that can't link it in.
+//Every endpoint takes a POST of a JSON Request:
+//
+//	/accel       the acceleration profile
+//	/cycle       one run through the schedule
+//	/range       range repeating the schedule
+//	/efficiency  consumption by cause at Speeds
+//	/trace       the schedule run as server-sent events, one "tick" event per
+//	             TraceInterval and a final "result" or "error" event
+//
+//Results are JSON in SI units. Bad requests get a 400 and failed simulations a 422, both
+//with a JSON Error
+package server
+
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/evantandersen/automotiveSim"
+	"github.com/evantandersen/automotiveSim/cycles"
+)
+
+const (
+	maxRequestSize = 10 << 20 //bytes
+	defaultTraceInterval = 1.0 //seconds between trace events
+)
+
+//Request is the body of every endpoint
+type Request struct {
+	Vehicle json.RawMessage //a vehicle description, as read by automotiveSim.ParseVehicle
+	Cycle string //a standard cycle for cycles.ByName, when Schedule isn't given. Defaults to nedc
+	Schedule *automotiveSim.Schedule
+	Speeds []float64 //m/s, for /efficiency
+	TraceInterval float64 //seconds of simulated time between /trace events, defaults to 1
+}
+
+//errorResponse is the body of any failed request
+type errorResponse struct {
+	Error string
+	Fields automotiveSim.ValidationErrors `json:",omitempty"`
+}
+
+//accelResponse is AccelProfile without the NaN it uses for times never reached
+type accelResponse struct {
+	TopSpeed float64
+	Governed bool
+	DragTopSpeed float64
+	Accel100 *float64 //null if 100km/h is never reached
+	AccelTop *float64
+	QuarterMile *float64
+	SixtyFoot float64
+	PeakAccel float64
+	TractionLimited float64
+	Limits []automotiveSim.LimitingReason
+	Warnings []automotiveSim.Warning
+}
+
+//badRequest is an error in the request itself rather than the simulation
+type badRequest struct {
+	error
+}
+
+//Handler serves every endpoint
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accel", endpoint(accel))
+	mux.HandleFunc("/cycle", endpoint(cycle))
+	mux.HandleFunc("/range", endpoint(vehicleRange))
+	mux.HandleFunc("/efficiency", endpoint(efficiency))
+	mux.HandleFunc("/trace", trace)
+	return mux
+}
+
+//parse reads the request and its vehicle
+func parse(w http.ResponseWriter, r *http.Request) (*Request, *automotiveSim.Vehicle, error) {
+	if r.Method != http.MethodPost {
+		return nil, nil, badRequest{fmt.Errorf("Use POST")}
+	}
+	var req Request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	err := decoder.Decode(&req)
+	if err != nil {
+		return nil, nil, badRequest{err}
+	}
+	if len(req.Vehicle) == 0 {
+		return nil, nil, badRequest{fmt.Errorf("Request requires a vehicle")}
+	}
+	vehicle, err := automotiveSim.ParseVehicle(req.Vehicle)
+	if err != nil {
+		return nil, nil, badRequest{err}
+	}
+	return &req, vehicle, nil
+}
+
+//schedule is the request's schedule, or its named cycle
+func (req *Request)schedule() (*automotiveSim.Schedule, error) {
+	if req.Schedule != nil {
+		return req.Schedule, nil
+	}
+	if req.Cycle == "" {
+		return cycles.NEDC(), nil
+	}
+	schedule, err := cycles.ByName(req.Cycle)
+	if err != nil {
+		return nil, badRequest{err}
+	}
+	return schedule, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusUnprocessableEntity
+	body := errorResponse{Error: err.Error()}
+	if bad, ok := err.(badRequest); ok {
+		status = http.StatusBadRequest
+		body.Fields, _ = bad.error.(automotiveSim.ValidationErrors)
+	}
+	writeJSON(w, status, body)
+}
+
+//endpoint turns a simulation into a JSON handler
+func endpoint(run func(ctx context.Context, req *Request, vehicle *automotiveSim.Vehicle) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, vehicle, err := parse(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		result, err := run(r.Context(), req, vehicle)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+//finite is x, or nil where it is NaN
+func finite(x float64) *float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return nil
+	}
+	return &x
+}
+
+func accel(ctx context.Context, req *Request, vehicle *automotiveSim.Vehicle) (interface{}, error) {
+	p, err := vehicle.RunAccelerationProfileContext(ctx, automotiveSim.AccelOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return accelResponse{
+		TopSpeed: p.TopSpeed,
+		Governed: p.Governed,
+		DragTopSpeed: p.DragTopSpeed,
+		Accel100: finite(p.Accel100),
+		AccelTop: finite(p.AccelTop),
+		QuarterMile: finite(p.QuarterMile),
+		SixtyFoot: p.SixtyFoot,
+		PeakAccel: p.PeakAccel,
+		TractionLimited: p.TractionLimited,
+		Limits: p.Limits,
+		Warnings: p.Warnings,
+	}, nil
+}
+
+func cycle(ctx context.Context, req *Request, vehicle *automotiveSim.Vehicle) (interface{}, error) {
+	schedule, err := req.schedule()
+	if err != nil {
+		return nil, err
+	}
+	sim, err := automotiveSim.InitSimulation(vehicle)
+	if err != nil {
+		return nil, err
+	}
+	return sim.RunContext(ctx, schedule)
+}
+
+func vehicleRange(ctx context.Context, req *Request, vehicle *automotiveSim.Vehicle) (interface{}, error) {
+	schedule, err := req.schedule()
+	if err != nil {
+		return nil, err
+	}
+	return vehicle.RangeOnCycle(schedule)
+}
+
+func efficiency(ctx context.Context, req *Request, vehicle *automotiveSim.Vehicle) (interface{}, error) {
+	if len(req.Speeds) == 0 {
+		return nil, badRequest{fmt.Errorf("Efficiency requires speeds")}
+	}
+	return vehicle.EfficiencyAtSpeeds(req.Speeds)
+}
+
+//trace streams a run through the schedule. Once the stream has started, failures come as
+//an error event rather than a status
+func trace(w http.ResponseWriter, r *http.Request) {
+	req, vehicle, err := parse(w, r)
+	if err == nil && req.TraceInterval < 0 {
+		err = badRequest{fmt.Errorf("Trace interval must not be negative")}
+	}
+	var schedule *automotiveSim.Schedule
+	if err == nil {
+		schedule, err = req.schedule()
+	}
+	var sim *automotiveSim.SimulatorState
+	if err == nil {
+		sim, err = automotiveSim.InitSimulation(vehicle)
+	}
+	flusher, ok := w.(http.Flusher)
+	if err == nil && !ok {
+		err = fmt.Errorf("Streaming is not supported")
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	event := func(name string, data interface{}) {
+		body, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, body)
+		flusher.Flush()
+	}
+
+	interval := time.Duration(defaultTraceInterval * float64(time.Second))
+	if req.TraceInterval > 0 {
+		interval = time.Duration(req.TraceInterval * float64(time.Second))
+	}
+	next := sim.Time
+	sim.OnTick(func(s *automotiveSim.TickState) {
+		if s.Time >= next {
+			event("tick", s)
+			next += interval
+		}
+	})
+	result, err := sim.RunContext(r.Context(), schedule)
+	if err != nil {
+		event("error", errorResponse{Error: err.Error()})
+		return
+	}
+	event("result", result)
+}
@@ -2,40 +2,96 @@ package automotiveSim
 
 import (
 	"fmt"
+	"math"
 	"time"
 )
 	
 const (
 	gravity = 9.81
+	maxSlowdown = gravity/2 //m/s^2, the most the vehicle slows by itself to get within the pack's limits
 )
 
+//SimulatorState is one simulation. It must only be used from one goroutine at a time,
+//but any number of them can run at once on the same Vehicle
 type SimulatorState struct {
-    Vehicle *Vehicle
+    Vehicle *Vehicle //the simulation's own clone of the vehicle, holding its state
+	Battery *Battery //&Vehicle.Battery
+	Body *Body //&Vehicle.Body
 	
 	Time time.Duration
     Speed float64
     Distance float64
     Interval time.Duration
-	Power Power
+	Power PowerBreakdown //on the last tick
 	Resources map[string]float64
 	BusVoltage float64
+	DragReduction float64 //fraction of aero drag removed, e.g. by drafting another vehicle
+	Grade float64 //road grade as rise/run, positive uphill
+	Headwind float64 //m/s of wind against the direction of travel, negative for a tailwind
+	Crosswind float64 //m/s of wind across the direction of travel
+	LateralAccel float64 //m/s^2 of cornering, taking its share of tire grip
+	FrictionBrakeEnergy float64 //dissipated in the friction brakes so far, joules
+	RegenLimitedTime time.Duration //braking on the friction brakes because the battery couldn't take all the regen
+	Energy EnergyBreakdown //where the energy went so far
+	Warnings []Warning
+	EngineEvents []EngineEvent //hybrid engine starts and stops
+	Trace *Trace //optional, a sample is appended every tick
+	Options SimulationOptions
+	
+	stability stability
+	lastAccel float64
+	accel float64 //being tried by the wheelsets, for weight transfer
+	lastLimit error
+	regenLimited bool //the battery capped regen for the forces last worked out
+	liftOff bool //ticking with both pedals released, for Body.Regen
+	observers []func(*TickState)
+	stats *runStats //collecting for the Run in progress
+	buffers tickBuffers
+}
+
+//tickBuffers are per wheelset slices reused every time the forces are worked out, so
+//ticking doesn't allocate
+type tickBuffers struct {
+	fmax []float64
+	fmin []float64
+	limits []error
+	forces []float64
+	power []float64
+	fixed []bool
+}
+
+//tickBuffers sizes the buffers for n wheelsets. Their contents are left over from the
+//last use
+func (state *SimulatorState)tickBuffers(n int) *tickBuffers {
+	t := &state.buffers
+	if len(t.fmax) != n {
+		t.fmax, t.fmin, t.forces, t.power = make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+		t.limits, t.fixed = make([]error, n), make([]bool, n)
+	}
+	return t
 }
 
+//InitSimulation starts a simulation of a clone of vehicle, which is never modified
 func InitSimulation(vehicle *Vehicle) (*SimulatorState, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return initSimulation(v)
+}
+
+//initSimulation simulates vehicle itself, for callers that already own a clone
+func initSimulation(vehicle *Vehicle) (*SimulatorState, error) {
     var state SimulatorState
     state.Vehicle = vehicle
 	
-	//initialize the map of power use
-	state.Power = make(Power)
-	
 	state.Resources = make(map[string]float64)
 	
-	state.Battery = NewBatteryState(&vehicle.Battery)
-	state.BusVoltage = state.Battery.pack.NominalVoltage
-	state.Power["Battery"] = state.Battery.power
+	state.Battery = &vehicle.Battery
+	state.BusVoltage = vehicle.Battery.NominalVoltage
 	
-	state.Body = NewBodyState(&vehicle.Body)
-	state.Power["Body"] = state.Body.power
+	state.Body = &vehicle.Body
+	state.Power.Drives = make([]DrivePower, len(vehicle.Body.Wheelsets))
 
 	//10ms default interval 
     state.Interval = 10 * time.Millisecond	
@@ -59,7 +115,10 @@ func (state *SimulatorState)CanOperate(accel float64) error {
 		return err
 	}
 	powerUse += tractionPower
-	powerUse += vehicle.Accessory
+	powerUse += vehicle.auxiliaryPower()
+	if vehicle.Climate != nil {
+		powerUse += vehicle.Climate.Load(state)
+	}
 	
 	err = state.Battery.CanOperate(state, powerUse)
 	if err != nil {
@@ -71,13 +130,31 @@ func (state *SimulatorState)CanOperate(accel float64) error {
 
 func (state *SimulatorState)Operate(accel float64) {
 	power := state.Body.Operate(state, accel)
-	power += state.Vehicle.Accessory
-	state.Power["Accessory"] = state.Vehicle.Accessory
-	state.BusVoltage = state.Battery.Operate(state, power)
-		
-	
+	power += state.Vehicle.auxiliaryPower()
+	climate := 0.0
+	if state.Vehicle.Climate != nil {
+		climate = state.Vehicle.Climate.Operate(state)
+		power += climate
+	}
+	state.Power.Accessory, state.Power.Climate = state.Vehicle.Accessory, climate
+	state.Power.LowVoltage, state.Power.DCDC = 0, 0
+	if state.Vehicle.LowVoltage != nil {
+		state.Power.LowVoltage, state.Power.DCDC = state.Vehicle.LowVoltage.Draw()
+	}
 	interval := state.Interval.Seconds()
-    state.Distance += state.Speed * interval
+	recovered := state.Vehicle.Battery.EnergyRecovered()
+	if state.Options.Integrator == Euler {
+		state.BusVoltage = state.Battery.Operate(state, power)
+		state.Distance += state.Speed * interval
+	} else {
+		//trapezoidal rule, averaging with the end of the tick
+		power = (power + state.endPower(accel, power))/2
+		state.BusVoltage = state.Battery.Operate(state, power)
+		state.Distance += (state.Speed + accel * interval/2) * interval
+	}
+	state.Power.Battery = state.Vehicle.Battery.loss
+	state.account(accel, climate)
+	state.Energy.Regen += state.Vehicle.Battery.EnergyRecovered() - recovered
     state.Speed += accel * interval
     state.Time += state.Interval
 }
@@ -88,12 +165,29 @@ func (state *SimulatorState)FindOperatingPoint(targetAccel float64) (float64, er
 		return targetAccel, nil
 	}
 	
-	// TODO fix for cases where CanOperateAtPoint(0) == false
+	if targetAccel >= 0 {
+		zeroErr := state.CanOperate(0)
+		if asLimit(zeroErr).Kind.battery() {
+			//the pack can't supply holding speed, so slow down until it can
+			return state.slowDown(0, zeroErr)
+		}
+		if zeroErr != nil {
+			//can't even hold speed (mid-shift, past the shaft speed limit), so lose as little as possible
+			return state.coast(zeroErr)
+		}
+	} else if asLimit(err).Kind.battery() {
+		return state.slowDown(targetAccel, err)
+	} else if coast := state.Vehicle.Body.coastAccel(state); coast < targetAccel {
+		//flat out it still slows faster than asked, e.g. too heavy for the hill
+		return state.coast(err)
+	}
+	
 	guess := targetAccel/2
 	step := targetAccel/4
 	lastKnownGood := 0.0
-	var lastErr error
-	for step > 0.001 {
+	lastErr := err
+	//step shares the sign of targetAccel, so this works for braking as well
+	for math.Abs(step) > 0.001 {
 		err := state.CanOperate(guess) 
 		if err != nil {
 			guess -= step
@@ -107,14 +201,106 @@ func (state *SimulatorState)FindOperatingPoint(targetAccel float64) (float64, er
 	return lastKnownGood, lastErr
 }
 
-func (state *SimulatorState)Tick(targetAccel float64) (float64, error) {    
-	accel, limit := state.FindOperatingPoint(targetAccel)
-	state.Operate(accel)
+//coast is the wheelsets flat out when they can't hold speed. If the pack can't supply
+//even that the vehicle slows until it can
+func (state *SimulatorState)coast(limit error) (float64, error) {
+	accel := state.Vehicle.Body.coastAccel(state)
+	err := state.CanOperate(accel)
+	if err == nil {
+		return accel, limit
+	}
+	if asLimit(err).Kind.battery() {
+		return state.slowDown(math.Min(accel, 0), err)
+	}
 	return accel, limit
 }
 
+//slowDown is the highest acceleration below from that the pack can supply, searching as far
+//as maxSlowdown below it. If there is none, e.g. the pack is flat, it returns from and the
+//limit, and Tick won't operate there
+func (state *SimulatorState)slowDown(from float64, limit error) (float64, error) {
+	low := from - maxSlowdown
+	if state.CanOperate(low) != nil {
+		return from, limit
+	}
+	high := from
+	for high - low > 0.001 {
+		mid := (low + high)/2
+		if state.CanOperate(mid) == nil {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low, limit
+}
 
+//fuelUsed is the liquid fuel burned so far in liters, all fuels together
+func (state *SimulatorState)fuelUsed() float64 {
+	total := 0.0
+	for fuel,amount := range state.Resources {
+		if fuelDensity[fuel] != 0 {
+			total += amount
+		}
+	}
+	return total
+}
 
+func (state *SimulatorState)Tick(targetAccel float64) (float64, error) {    
+	state.Vehicle.Body.shift(state)
+	//the governor asks for no more than brings the vehicle to its limit this tick
+	governed := false
+	if limiter := state.Vehicle.SpeedLimiter; limiter > 0 {
+		maxAccel := (limiter - state.Speed) / state.Interval.Seconds()
+		if targetAccel > maxAccel {
+			targetAccel, governed = maxAccel, true
+		}
+	}
+	var accel float64
+	var limit error
+	switch state.Options.Integrator {
+	case RK4:
+		accel, limit = state.rk4(state.Speed, targetAccel, state.Interval.Seconds())
+		if state.CanOperate(accel) != nil {
+			//the average of the step isn't an operating point, so take the one at its start
+			accel, limit = state.FindOperatingPoint(targetAccel)
+		}
+	case Adaptive:
+		accel, limit = state.adaptiveStep(targetAccel)
+	default:
+		accel, limit = state.FindOperatingPoint(targetAccel)
+	}
+	if state.Options.Integrator != Adaptive {
+		if limit != nil && state.CanOperate(accel) != nil {
+			//there's nowhere the vehicle can operate, e.g. the pack is flat, so it doesn't
+			state.lastAccel, state.lastLimit = 0, limit
+			return 0, limit
+		}
+		state.Operate(accel)
+	}
+	if governed && limit == nil {
+		limit = LimitReason{Kind: LimitSpeedLimiter, Headroom: headroom(state.Vehicle.SpeedLimiter, state.Speed)}
+	}
+	state.checkStability(accel)
+	state.lastAccel, state.lastLimit = accel, limit
+	if state.Trace != nil {
+		*state.Trace = append(*state.Trace, state.Sample())
+	}
+	state.notify(targetAccel)
+	if state.stats != nil {
+		state.stats.add(state)
+	}
+	return accel, limit
+}
 
-
-
+//LiftOff ticks with both pedals released, the vehicle slowing on the road load and its
+//lift-off regen, Body.Regen.LiftOff. Tick with a deceleration beyond that presses the brake
+//pedal, regenerating up to Body.Regen.BrakePedal
+func (state *SimulatorState)LiftOff() (float64, error) {
+	state.liftOff = true
+	defer func() { state.liftOff = false }()
+	accel := state.Vehicle.Body.liftOffAccel(state)
+	//don't roll backwards once stopped
+	accel = math.Max(accel, -state.Speed / state.Interval.Seconds())
+	return state.Tick(accel)
+}
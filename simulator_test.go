@@ -0,0 +1,84 @@
+package automotiveSim
+
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTickFlatPackDoesNotAccelerate(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.MinSOC = 0.1
+	sim := testSimulation(t, v)
+	b := sim.Battery
+	b.coulombsUsed = (1 - b.MinSOC) * b.Coulomb
+	sim.Speed = 20
+
+	for i := 0; i < 5000 && sim.Speed > 0; i++ {
+		accel, err := sim.Tick(1)
+		if !errors.Is(err, errDepleted) {
+			t.Fatalf("tick %d: got %v, want the pack depleted", i, err)
+		}
+		if accel > 0 {
+			t.Fatalf("tick %d: accelerated at %.3f m/s^2 on a flat pack", i, accel)
+		}
+		if b.StateOfCharge() < b.MinSOC - 1e-9 {
+			t.Fatalf("tick %d: state of charge %.4f below MinSOC", i, b.StateOfCharge())
+		}
+	}
+}
+
+func TestTickSlowsWithinPackCurrent(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.MaxCurrent, v.Battery.ContinuousCurrent = 150, 150
+	sim := testSimulation(t, v)
+	sim.Speed = 40
+	sim.Grade = 0.05
+
+	accel, err := sim.Tick(0)
+	if asLimit(err).Kind != LimitPackCurrent {
+		t.Fatalf("got %v, want the pack current limit", err)
+	}
+	if accel >= 0 {
+		t.Fatalf("held speed at %.3f m/s^2 the pack can't supply", accel)
+	}
+	if current := sim.Battery.AmpsAtPower(sim.Power.Total() - sim.Power.Battery); current > 150 * 1.001 {
+		t.Fatalf("drew %.0f A from a 150 A pack", current)
+	}
+}
+
+func TestTickAcceleratesWithinPackCurrent(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.MaxCurrent, v.Battery.ContinuousCurrent = 150, 150
+	sim := testSimulation(t, v)
+	sim.Speed = 15
+
+	accel, err := sim.Tick(100)
+	if asLimit(err).Kind != LimitPackCurrent {
+		t.Fatalf("got %v, want the pack current limit", err)
+	}
+	if accel <= 0 {
+		t.Fatalf("got %.3f m/s^2, want as much acceleration as the pack allows", accel)
+	}
+}
+
+func TestSpeedLimiterGovernsTopSpeed(t *testing.T) {
+	v := testVehicle(t)
+	v.SpeedLimiter = 25
+	sim := testSimulation(t, v)
+	sim.Speed = 20
+
+	var err error
+	for i := 0; i < 1000; i++ {
+		_, err = sim.Tick(10)
+		if sim.Speed > 25 + 1e-9 {
+			t.Fatalf("tick %d: %.4f m/s past the 25 m/s limiter", i, sim.Speed)
+		}
+	}
+	if asLimit(err).Kind != LimitSpeedLimiter {
+		t.Fatalf("got %v, want the speed limiter", err)
+	}
+	if sim.Speed < 25 - 1e-6 {
+		t.Fatalf("held %.4f m/s, want the limiter's 25 m/s", sim.Speed)
+	}
+}
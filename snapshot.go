@@ -0,0 +1,218 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"time"
+)
+
+//Snapshot is everything a simulation has built up, with only exported fields so it can
+//be saved as JSON or gob. Restoring it into a simulation of the same vehicle carries on
+//from where it was taken, so a long run can be checkpointed, resumed, or branched into
+//what-ifs. The vehicle itself isn't included, and neither is a Trace. The driver's state
+//is, when it is the default PIDDriver, for a simulation ticked with its driver by hand;
+//Run starts every schedule with a fresh driver
+type Snapshot struct {
+	Time time.Duration
+	Speed float64
+	Distance float64
+	Interval time.Duration
+	Resources map[string]float64
+	BusVoltage float64
+	DragReduction float64
+	Grade float64
+	Headwind float64
+	Crosswind float64
+	LateralAccel float64
+	FrictionBrakeEnergy float64
+	RegenLimitedTime time.Duration
+	Energy EnergyBreakdown
+	Warnings []Warning
+	EngineEvents []EngineEvent
+	LastAccel float64
+
+	Battery BatterySnapshot
+	CabinTemperature float64 //kelvin, zero without a climate model
+	Drives []DriveSnapshot //one per wheelset, zero for those without a drive
+
+	//stability checking, so warnings aren't repeated and an oscillation spanning the
+	//snapshot is still caught
+	SpeedWarned bool
+	OscillationWarned bool
+	StabilityAccel float64
+	StabilityDelta float64
+	Reversals int
+
+	Driver *DriverSnapshot //nil unless the simulation's driver is a PIDDriver
+}
+
+//DriverSnapshot is the state of a PIDDriver
+type DriverSnapshot struct {
+	Integral float64
+	LastError float64
+	LastAccel float64
+	Started bool
+}
+
+//BatterySnapshot is the state of the pack
+type BatterySnapshot struct {
+	CoulombsUsed float64
+	EnergyUsed float64
+	EnergyRecovered float64
+	Temperature float64 //kelvin, zero without a thermal model
+}
+
+//DriveSnapshot is the state of one wheelset's drive
+type DriveSnapshot struct {
+	MotorTemperature float64 //kelvin, zero without a thermal model
+	Gear int
+	Shifting time.Duration //left of a gear change in progress
+	EngineOn bool //hybrid engine running
+}
+
+//Snapshot records the simulation's state. Take it between ticks or runs
+func (sim *SimulatorState)Snapshot() Snapshot {
+	s := Snapshot{
+		Time: sim.Time,
+		Speed: sim.Speed,
+		Distance: sim.Distance,
+		Interval: sim.Interval,
+		Resources: make(map[string]float64, len(sim.Resources)),
+		BusVoltage: sim.BusVoltage,
+		DragReduction: sim.DragReduction,
+		Grade: sim.Grade,
+		Headwind: sim.Headwind,
+		Crosswind: sim.Crosswind,
+		LateralAccel: sim.LateralAccel,
+		FrictionBrakeEnergy: sim.FrictionBrakeEnergy,
+		RegenLimitedTime: sim.RegenLimitedTime,
+		Energy: sim.Energy,
+		Warnings: append([]Warning(nil), sim.Warnings...),
+		EngineEvents: append([]EngineEvent(nil), sim.EngineEvents...),
+		LastAccel: sim.lastAccel,
+		SpeedWarned: sim.stability.speedWarned,
+		OscillationWarned: sim.stability.oscillationWarned,
+		StabilityAccel: sim.stability.lastAccel,
+		StabilityDelta: sim.stability.lastDelta,
+		Reversals: sim.stability.reversals,
+	}
+	if d, ok := sim.Options.Driver.(*PIDDriver); ok {
+		s.Driver = &DriverSnapshot{Integral: d.integral, LastError: d.lastError, LastAccel: d.lastAccel, Started: d.started}
+	}
+	for k,v := range sim.Resources {
+		s.Resources[k] = v
+	}
+
+	v := sim.Vehicle
+	b := &v.Battery
+	s.Battery = BatterySnapshot{CoulombsUsed: b.coulombsUsed, EnergyUsed: b.energyUsed, EnergyRecovered: b.energyRecovered}
+	if b.Thermal != nil {
+		s.Battery.Temperature = b.Thermal.temperature
+	}
+	if v.Climate != nil {
+		s.CabinTemperature = v.Climate.cabinTemperature
+	}
+	s.Drives = make([]DriveSnapshot, len(v.Body.Wheelsets))
+	for i,w := range v.Body.Wheelsets {
+		d := w.Drive
+		if d == nil {
+			continue
+		}
+		if d.Motor.Thermal != nil {
+			s.Drives[i].MotorTemperature = d.Motor.Thermal.temperature
+		}
+		if d.Gearbox != nil {
+			s.Drives[i].Gear, s.Drives[i].Shifting = d.Gearbox.gear, d.Gearbox.shifting
+		}
+		if d.Hybrid != nil {
+			s.Drives[i].EngineOn = d.Hybrid.engineOn
+		}
+	}
+	return s
+}
+
+//Restore puts the simulation back to a snapshot taken of a simulation of the same vehicle.
+//Its options are initialized, which starts its driver afresh before any driver state in
+//the snapshot is restored
+func (sim *SimulatorState)Restore(s Snapshot) error {
+	v := sim.Vehicle
+	if len(s.Drives) != len(v.Body.Wheelsets) {
+		return fmt.Errorf("Snapshot has %d wheelsets, the vehicle has %d", len(s.Drives), len(v.Body.Wheelsets))
+	}
+	if s.Interval <= 0 {
+		return fmt.Errorf("Snapshot interval must be positive")
+	}
+	for i,w := range v.Body.Wheelsets {
+		if w.Drive != nil && w.Drive.Gearbox != nil && (s.Drives[i].Gear < 0 || s.Drives[i].Gear >= len(w.Drive.Gearbox.Ratios)) {
+			return fmt.Errorf("Snapshot gear %d is not one of wheelset %d's", s.Drives[i].Gear, i)
+		}
+	}
+
+	sim.Time, sim.Speed, sim.Distance, sim.Interval = s.Time, s.Speed, s.Distance, s.Interval
+	sim.Resources = make(map[string]float64, len(s.Resources))
+	for k,amount := range s.Resources {
+		sim.Resources[k] = amount
+	}
+	sim.BusVoltage = s.BusVoltage
+	sim.DragReduction, sim.Grade = s.DragReduction, s.Grade
+	sim.Headwind, sim.Crosswind, sim.LateralAccel = s.Headwind, s.Crosswind, s.LateralAccel
+	sim.FrictionBrakeEnergy, sim.RegenLimitedTime = s.FrictionBrakeEnergy, s.RegenLimitedTime
+	sim.Energy = s.Energy
+	sim.Warnings = append([]Warning(nil), s.Warnings...)
+	sim.EngineEvents = append([]EngineEvent(nil), s.EngineEvents...)
+	sim.lastAccel = s.LastAccel
+	sim.stability = stability{
+		lastAccel: s.StabilityAccel,
+		lastDelta: s.StabilityDelta,
+		reversals: s.Reversals,
+		speedWarned: s.SpeedWarned,
+		oscillationWarned: s.OscillationWarned,
+	}
+	//the options' defaults are in place before the driver picks up where it was
+	err := sim.Options.Init()
+	if err != nil {
+		return err
+	}
+	if d, ok := sim.Options.Driver.(*PIDDriver); ok && s.Driver != nil {
+		d.integral, d.lastError, d.lastAccel, d.started = s.Driver.Integral, s.Driver.LastError, s.Driver.LastAccel, s.Driver.Started
+	}
+
+	b := &v.Battery
+	b.coulombsUsed, b.energyUsed, b.energyRecovered = s.Battery.CoulombsUsed, s.Battery.EnergyUsed, s.Battery.EnergyRecovered
+	if b.Thermal != nil {
+		b.Thermal.temperature = s.Battery.Temperature
+	}
+	if v.Climate != nil {
+		v.Climate.cabinTemperature = s.CabinTemperature
+	}
+	for i,w := range v.Body.Wheelsets {
+		d := w.Drive
+		if d == nil {
+			continue
+		}
+		if d.Motor.Thermal != nil {
+			d.Motor.Thermal.temperature = s.Drives[i].MotorTemperature
+		}
+		if d.Gearbox != nil {
+			d.Gearbox.gear, d.Gearbox.shifting = s.Drives[i].Gear, s.Drives[i].Shifting
+		}
+		if d.Hybrid != nil {
+			d.Hybrid.engineOn = s.Drives[i].EngineOn
+		}
+	}
+	return nil
+}
+
+//ResumeSimulation starts a new simulation of vehicle from a snapshot. Each call is
+//independent, so one snapshot can be branched any number of ways
+func ResumeSimulation(vehicle *Vehicle, s Snapshot) (*SimulatorState, error) {
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return nil, err
+	}
+	err = sim.Restore(s)
+	if err != nil {
+		return nil, err
+	}
+	return sim, nil
+}
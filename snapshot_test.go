@@ -0,0 +1,51 @@
+package automotiveSim
+
+
+import (
+	"reflect"
+	"testing"
+)
+
+//driveTicks follows a trace weaving around 20 m/s by hand with the simulation's driver,
+//from tick from up to tick to
+func driveTicks(t *testing.T, sim *SimulatorState, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		traceAccel := 2.0
+		if (i/3) % 2 == 1 {
+			traceAccel = -2
+		}
+		trace := 20 + float64(i % 6)*0.1
+		_, err := sim.Tick(sim.Options.Driver.Accel(sim, trace, traceAccel))
+		if err != nil && asLimit(err).Kind.battery() {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSnapshotResumeMatchesContinuousRun(t *testing.T) {
+	v := testVehicle(t)
+	err := v.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	continuous := testSimulation(t, v)
+	continuous.Speed = 20
+	err = continuous.Options.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	driveTicks(t, continuous, 0, 200)
+	snapshot := continuous.Snapshot()
+	driveTicks(t, continuous, 200, 400)
+
+	resumed, err := ResumeSimulation(v, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	driveTicks(t, resumed, 200, 400)
+
+	if got, want := resumed.Snapshot(), continuous.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("resumed run ended at\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	maxSpeedStep = 1.0 //m/s change in a single tick before the step is considered too coarse
+	oscillationTicks = 6 //consecutive reversals in acceleration that count as an oscillation
+	oscillationThreshold = 0.5 //m/s^2, smaller reversals are ignored
+)
+
+//Warning flags results that may not be trustworthy, most often because the
+//simulation interval is too large for the configured dynamics
+type Warning struct {
+	Time time.Duration
+	Message string
+}
+
+//stability watches successive ticks for signs the interval is too coarse
+type stability struct {
+	lastAccel float64
+	lastDelta float64
+	reversals int
+	speedWarned bool
+	oscillationWarned bool
+}
+
+func (state *SimulatorState)checkStability(accel float64) {
+	s := &state.stability
+	
+	step := math.Abs(accel * state.Interval.Seconds())
+	if step > maxSpeedStep && !s.speedWarned {
+		s.speedWarned = true
+		state.Warnings = append(state.Warnings, Warning{
+			Time: state.Time,
+			Message: fmt.Sprintf("Speed changed %4.2fm/s in one tick, consider a smaller interval", step),
+		})
+	}
+	
+	delta := accel - s.lastAccel
+	if math.Abs(delta) > oscillationThreshold && math.Signbit(delta) != math.Signbit(s.lastDelta) {
+		s.reversals++
+	} else {
+		s.reversals = 0
+	}
+	if s.reversals >= oscillationTicks && !s.oscillationWarned {
+		s.oscillationWarned = true
+		state.Warnings = append(state.Warnings, Warning{
+			Time: state.Time,
+			Message: fmt.Sprintf("Acceleration oscillating at %4.2fm/s, consider a smaller interval", state.Speed),
+		})
+	}
+	s.lastAccel = accel
+	s.lastDelta = delta
+}
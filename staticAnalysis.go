@@ -1,12 +1,23 @@
 package automotiveSim
 
 
+import (
+	"math"
+)
+
 var energy_in_fuel map[string]float64
 
+//kg per liter of each liquid fuel
+var fuelDensity = map[string]float64{
+	"Gasoline": 0.745,
+	"Diesel": 0.832,
+}
+
 func init() {
 	energy_in_fuel = make(map[string]float64)
 	energy_in_fuel["Electricity"] = 1.0
 	energy_in_fuel["Gasoline"] = 32049360 // # of joules in 1L gasoline
+	energy_in_fuel["Diesel"] = 35800000 // # of joules in 1L diesel
 	
 }
 
@@ -31,6 +42,8 @@ func et(amount, efficiency float64) float64 {
 	}
 }
 
-
-
-
+//rounds to the given number of decimal places
+func roundTo(x float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(x * scale) / scale
+}
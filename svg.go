@@ -0,0 +1,169 @@
+package automotiveSim
+
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	svgWidth = 640
+	svgHeight = 400
+	svgMargin = 60 //px around the plot area for ticks and labels
+	svgTicks = 5 //roughly how many ticks each axis gets
+)
+
+var svgColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#17becf"}
+
+//niceStep is a 1, 2 or 5 times a power of ten step giving about svgTicks over span
+func niceStep(span float64) float64 {
+	if span <= 0 {
+		return 1
+	}
+	raw := span / svgTicks
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _,m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			return m*magnitude
+		}
+	}
+	return 10*magnitude
+}
+
+//axisRange rounds the extent of values out to whole steps
+func axisRange(values [][]float64) (low, high, step float64) {
+	low, high = math.Inf(1), math.Inf(-1)
+	for _,series := range values {
+		for _,v := range series {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				low, high = math.Min(low, v), math.Max(high, v)
+			}
+		}
+	}
+	if low > high {
+		return 0, 1, niceStep(1)
+	}
+	if low == high {
+		low, high = low - 1, high + 1
+	}
+	step = niceStep(high - low)
+	return math.Floor(low/step)*step, math.Ceil(high/step)*step, step
+}
+
+func axisTitle(label, unit string) string {
+	if unit == "" {
+		return label
+	}
+	return label + " (" + unit + ")"
+}
+
+//RenderSVG draws every series as a line on shared axes, labelled from the first series,
+//with a legend when there is more than one
+func (p PlotData)RenderSVG(w io.Writer) error {
+	if len(p.Series) == 0 {
+		return fmt.Errorf("Nothing to plot")
+	}
+	xs := make([][]float64, len(p.Series))
+	ys := make([][]float64, len(p.Series))
+	for i,s := range p.Series {
+		if len(s.X) != len(s.Y) {
+			return fmt.Errorf("%s: series needs one Y per X", s.Name)
+		}
+		xs[i], ys[i] = s.X, s.Y
+	}
+	xLow, xHigh, xStep := axisRange(xs)
+	yLow, yHigh, yStep := axisRange(ys)
+	plotW, plotH := float64(svgWidth - 2*svgMargin), float64(svgHeight - 2*svgMargin)
+	px := func(x float64) float64 {
+		return svgMargin + (x - xLow)/(xHigh - xLow)*plotW
+	}
+	py := func(y float64) float64 {
+		return svgMargin + plotH - (y - yLow)/(yHigh - yLow)*plotH
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", svgWidth, svgHeight)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(out, `<text x="%d" y="%d" text-anchor="middle" font-size="16">%s</text>`+"\n", svgWidth/2, svgMargin/2, html.EscapeString(p.Title))
+
+	//grid and ticks
+	for x := xLow; x <= xHigh + xStep/2; x += xStep {
+		fmt.Fprintf(out, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n", px(x), svgMargin, px(x), svgHeight - svgMargin)
+		fmt.Fprintf(out, `<text x="%.1f" y="%d" text-anchor="middle">%g</text>`+"\n", px(x), svgHeight - svgMargin + 16, roundTo(x, 6))
+	}
+	for y := yLow; y <= yHigh + yStep/2; y += yStep {
+		fmt.Fprintf(out, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", svgMargin, py(y), svgWidth - svgMargin, py(y))
+		fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%g</text>`+"\n", svgMargin - 6, py(y), roundTo(y, 6))
+	}
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="black"/>`+"\n", svgMargin, svgMargin, plotW, plotH)
+	first := p.Series[0]
+	fmt.Fprintf(out, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", svgWidth/2, svgHeight - svgMargin/4, html.EscapeString(axisTitle(first.XLabel, first.XUnit)))
+	fmt.Fprintf(out, `<text x="%d" y="%d" text-anchor="middle" transform="rotate(-90 %d %d)">%s</text>`+"\n", svgMargin/4, svgHeight/2, svgMargin/4, svgHeight/2, html.EscapeString(axisTitle(first.YLabel, first.YUnit)))
+
+	for i,s := range p.Series {
+		points := make([]string, 0, len(s.X))
+		for j := range s.X {
+			if math.IsNaN(s.Y[j]) || math.IsInf(s.Y[j], 0) {
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(s.X[j]), py(s.Y[j])))
+		}
+		color := svgColors[i % len(svgColors)]
+		fmt.Fprintf(out, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`+"\n", color, strings.Join(points, " "))
+		if len(p.Series) > 1 {
+			y := svgMargin + 10 + 16*i
+			fmt.Fprintf(out, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"/>`+"\n", svgWidth - svgMargin - 110, y, svgWidth - svgMargin - 90, y, color)
+			fmt.Fprintf(out, `<text x="%d" y="%d" dominant-baseline="middle">%s</text>`+"\n", svgWidth - svgMargin - 85, y, html.EscapeString(s.Name))
+		}
+	}
+	fmt.Fprintln(out, `</svg>`)
+	return out.Flush()
+}
+
+//RenderSVG draws the speed against time
+func (p *AccelProfile)RenderSVG(w io.Writer) error {
+	return p.PlotData().RenderSVG(w)
+}
+
+//PlotData is the state of charge against distance
+func (r RangeResult)PlotData() PlotData {
+	soc := PlotSeries{Name: "SOC", XLabel: "Distance", XUnit: "km", YLabel: "State of charge", YUnit: "%"}
+	for _,p := range r.SOC {
+		soc.X = append(soc.X, p.X)
+		soc.Y = append(soc.Y, p.Y*100)
+	}
+	return PlotData{Title: "State of Charge", Series: []PlotSeries{soc}}
+}
+
+//RenderSVG draws the state of charge against distance
+func (r RangeResult)RenderSVG(w io.Writer) error {
+	return r.PlotData().RenderSVG(w)
+}
+
+//EfficiencyPlotData turns the result of EfficiencyAtSpeeds into one series per cause of
+//consumption against speed
+func EfficiencyPlotData(speeds []float64, efficiency map[string][]float64) PlotData {
+	causes := make([]string, 0, len(efficiency))
+	for cause := range efficiency {
+		causes = append(causes, cause)
+	}
+	sort.Strings(causes)
+	data := PlotData{Title: "Consumption by Speed"}
+	for _,cause := range causes {
+		data.Series = append(data.Series, PlotSeries{
+			Name: cause,
+			XLabel: "Speed",
+			XUnit: "m/s",
+			YLabel: "Consumption",
+			YUnit: "J/m",
+			X: speeds,
+			Y: efficiency[cause],
+		})
+	}
+	return data
+}
@@ -0,0 +1,178 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+//Parameter is one axis of a sweep, Apply sets Value on a private copy of the vehicle
+type Parameter struct {
+	Name string
+	Values []float64
+	Apply func(v *Vehicle, value float64)
+}
+
+//Metric measures a vehicle. It gets its own copy and may run simulations on it freely
+type Metric func(v *Vehicle) (float64, error)
+
+//SweepRow is one point of the grid, Values in the same order as the parameters
+type SweepRow struct {
+	Values []float64
+	Result float64
+	Err error
+}
+
+//SweepTable holds every point of a sweep, the last parameter varying fastest
+type SweepTable struct {
+	Parameters []string
+	Rows []SweepRow
+}
+
+//Steps spreads count values evenly from `from` to `to` inclusive
+func Steps(from, to float64, count int) []float64 {
+	if count < 2 {
+		return []float64{from}
+	}
+	values := make([]float64, count)
+	for i := range values {
+		values[i] = from + (to - from) * float64(i)/float64(count - 1)
+	}
+	return values
+}
+
+//MassParameter sweeps the vehicle's weight (kg)
+func MassParameter(values []float64) Parameter {
+	return Parameter{Name: "Mass", Values: values, Apply: func(v *Vehicle, value float64) {
+		v.Body.Weight = value
+	}}
+}
+
+//DragAreaParameter sweeps the vehicle's CdA (m^2)
+func DragAreaParameter(values []float64) Parameter {
+	return Parameter{Name: "CdA", Values: values, Apply: func(v *Vehicle, value float64) {
+		v.Body.CdA = value
+	}}
+}
+
+//Accel100Metric is the 0-100km/h time in seconds
+func Accel100Metric(v *Vehicle) (float64, error) {
+	profile, err := v.RunAccelerationProfile()
+	return profile.Accel100, err
+}
+
+//CycleEnergyMetric is the battery energy (J) used driving the schedule once
+func CycleEnergyMetric(schedule *Schedule) Metric {
+	return func(v *Vehicle) (float64, error) {
+		sim, err := InitSimulation(v)
+		if err != nil {
+			return 0, err
+		}
+		result, err := sim.Run(schedule)
+		return result.Energy, err
+	}
+}
+
+//RangeMetric is the range (km) driving repeats of the schedule until the battery is depleted
+func RangeMetric(schedule *Schedule) Metric {
+	return func(v *Vehicle) (float64, error) {
+		result, err := v.RangeOnCycle(schedule)
+		return result.Range, err
+	}
+}
+
+//Sweep measures every combination of the parameter values, spread over one goroutine
+//per CPU. The vehicle itself is never modified. An error from the metric is kept on
+//its row, only an unusable sweep returns an error
+func (vehicle *Vehicle)Sweep(params []Parameter, metric Metric) (SweepTable, error) {
+	return vehicle.SweepContext(context.Background(), params, metric)
+}
+
+//SweepContext is Sweep, giving up once ctx is done. Points already being measured run to
+//completion, the rest get ctx's error on their row, which is also returned
+func (vehicle *Vehicle)SweepContext(ctx context.Context, params []Parameter, metric Metric) (SweepTable, error) {
+	table := SweepTable{}
+	count := 1
+	for _,p := range params {
+		if len(p.Values) == 0 {
+			return table, fmt.Errorf("Parameter %s has no values", p.Name)
+		}
+		if p.Apply == nil {
+			return table, fmt.Errorf("Parameter %s has no Apply function", p.Name)
+		}
+		table.Parameters = append(table.Parameters, p.Name)
+		count *= len(p.Values)
+	}
+	if metric == nil {
+		return table, fmt.Errorf("Sweep requires a metric")
+	}
+
+	table.Rows = make([]SweepRow, count)
+	for i := range table.Rows {
+		//decode the row number into one index per parameter, last parameter fastest
+		values := make([]float64, len(params))
+		rest := i
+		for j := len(params) - 1; j >= 0; j-- {
+			values[j] = params[j].Values[rest % len(params[j].Values)]
+			rest /= len(params[j].Values)
+		}
+		table.Rows[i].Values = values
+	}
+
+	parallel(count, func(i int) {
+		row := &table.Rows[i]
+		if ctx.Err() != nil {
+			row.Err = ctx.Err()
+			return
+		}
+		row.Result, row.Err = vehicle.measure(params, row.Values, metric)
+	})
+	return table, ctx.Err()
+}
+
+//parallel calls job for 0..count-1 spread over one goroutine per CPU
+func parallel(count int, job func(i int)) {
+	workerPool(runtime.NumCPU(), count, job)
+}
+
+//workerPool calls job for 0..count-1 spread over workers goroutines, one per CPU if
+//workers is zero
+func workerPool(workers, count int, job func(i int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+//measure runs the metric on a copy of the vehicle with the parameters applied
+func (vehicle *Vehicle)measure(params []Parameter, values []float64, metric Metric) (float64, error) {
+	v, err := vehicle.Clone()
+	if err != nil {
+		return 0, err
+	}
+	for i,p := range params {
+		p.Apply(v, values[i])
+	}
+	err = v.Init()
+	if err != nil {
+		return 0, err
+	}
+	return metric(v)
+}
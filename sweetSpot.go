@@ -0,0 +1,85 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+//SweetSpot is how close to its best one motor ran over a cycle. The energy is the motor's
+//shaft work, driving and regenerating alike
+type SweetSpot struct {
+	Wheelset string
+	Peak float64 //highest efficiency on the motor's map, or its Efficiency without one
+	Within float64 //share of the energy at operating points within the band below Peak
+	Average float64 //efficiency over the cycle, weighted by energy
+}
+
+//peakEfficiency is the best efficiency the motor has anywhere
+func (m *Motor)peakEfficiency() float64 {
+	if len(m.EfficiencyMap.X) == 0 {
+		return m.Efficiency
+	}
+	peak := 0.0
+	for _,row := range m.EfficiencyMap.Z {
+		for _,e := range row {
+			peak = math.Max(peak, e)
+		}
+	}
+	return peak
+}
+
+//SweetSpot runs the cycle on a copy of the vehicle and reports, for each motor driven
+//wheelset, how much of the motor's energy went through operating points within band
+//(efficiency points, e.g. 0.02 for 2%) of its peak efficiency, and its average
+//efficiency. It shows how well the gearing puts the cycle where the motor is best
+func (vehicle *Vehicle)SweetSpot(cycle *Schedule, band float64) ([]SweetSpot, error) {
+	if band < 0 || band >= 1 {
+		return nil, fmt.Errorf("Sweet spot band must be on the range [0,1)")
+	}
+	sim, err := InitSimulation(vehicle)
+	if err != nil {
+		return nil, err
+	}
+	
+	wheelsets := sim.Body.Wheelsets
+	result := make([]SweetSpot, 0, len(wheelsets))
+	index := make([]int, 0, len(wheelsets)) //into Body.Wheelsets, for each result
+	for i,w := range wheelsets {
+		if w.Drive == nil || w.Drive.Engine != nil {
+			continue
+		}
+		result = append(result, SweetSpot{Wheelset: w.Name, Peak: w.Drive.Motor.peakEfficiency()})
+		index = append(index, i)
+	}
+	total := make([]float64, len(result))
+	within := make([]float64, len(result))
+	weighted := make([]float64, len(result))
+	sim.OnTick(func(*TickState) {
+		dt := sim.Interval.Seconds()
+		for r,i := range index {
+			mechanical := sim.Power.Drives[i].Mechanical
+			if mechanical == 0 {
+				continue
+			}
+			efficiency := wheelsets[i].Drive.Motor.efficiency
+			energy := math.Abs(mechanical) * dt
+			total[r] += energy
+			weighted[r] += efficiency * energy
+			if efficiency >= result[r].Peak - band {
+				within[r] += energy
+			}
+		}
+	})
+	_, err = sim.Run(cycle)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", cycle.Name, err)
+	}
+	for r := range result {
+		if total[r] > 0 {
+			result[r].Within = within[r] / total[r]
+			result[r].Average = weighted[r] / total[r]
+		}
+	}
+	return result, nil
+}
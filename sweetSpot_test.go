@@ -0,0 +1,51 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+)
+
+//mappedMotor gives the test vehicle a motor that is only at its best near peak torque
+func mappedMotor(t *testing.T, gearing float64) *Vehicle {
+	v := testVehicle(t)
+	drive := v.Body.Wheelsets[1].Drive
+	drive.Gearing = gearing
+	drive.Motor.EfficiencyMap = Map{X: []float64{0, 100, 400}, Y: []float64{0, 2600}, Z: [][]float64{{0.7, 0.7}, {0.9, 0.9}, {0.96, 0.96}}}
+	return v
+}
+
+func TestSweetSpotFixedEfficiency(t *testing.T) {
+	spots, err := testVehicle(t).SweetSpot(testCycle(), 0.02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spots) != 1 || spots[0].Wheelset != "Rear" {
+		t.Fatalf("got %+v, want the rear motor only", spots)
+	}
+	s := spots[0]
+	if s.Peak != 0.92 || s.Within != 1 || math.Abs(s.Average - 0.92) > 1e-9 {
+		t.Fatalf("a fixed 92%% motor reported %+v", s)
+	}
+}
+
+func TestSweetSpotFollowsGearing(t *testing.T) {
+	short, err := mappedMotor(t, 9.7).SweetSpot(testCycle(), 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tall, err := mappedMotor(t, 3).SweetSpot(testCycle(), 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short[0].Peak != 0.96 {
+		t.Fatalf("peak %.2f, not the map's 0.96", short[0].Peak)
+	}
+	if short[0].Average <= 0.7 || short[0].Average >= 0.96 {
+		t.Fatalf("average %.3f outside the map", short[0].Average)
+	}
+	//the taller gear runs the motor at more torque for the same wheel force
+	if tall[0].Average <= short[0].Average || tall[0].Within <= short[0].Within {
+		t.Fatalf("taller gearing %+v isn't closer to the sweet spot than %+v", tall[0], short[0])
+	}
+}
@@ -0,0 +1,152 @@
+package automotiveSim
+
+
+import (
+	"context"
+	"time"
+)
+
+//TelemetrySample is a snapshot of the simulation at a single instant
+type TelemetrySample struct {
+	Time time.Duration
+	Speed float64
+	Accel float64 //achieved on the last tick
+	Distance float64
+	Regen []float64 //power recovered by each wheelset, in Body.Wheelsets order
+	Power map[string]float64 //flattened PowerBreakdown from the last tick
+	Limit string //why the last tick fell short of its target, empty if it didn't
+}
+
+func (sim *SimulatorState)Sample() TelemetrySample {
+	sample := TelemetrySample{
+		Time: sim.Time,
+		Speed: sim.Speed,
+		Accel: sim.lastAccel,
+		Distance: sim.Distance,
+		Regen: make([]float64, len(sim.Vehicle.Body.Wheelsets)),
+		Power: sim.Power.Flatten(),
+	}
+	if sim.lastLimit != nil {
+		sample.Limit = sim.lastLimit.Error()
+	}
+	for i,w := range sim.Vehicle.Body.Wheelsets {
+		sample.Regen[i] = w.RegenPower()
+	}
+	return sample
+}
+
+//TickState is what an OnTick observer is handed after every tick
+type TickState struct {
+	TelemetrySample
+	TargetAccel float64
+	SOC float64
+	Reason LimitReason //why Accel fell short of TargetAccel, LimitNone if it didn't
+}
+
+//OnTick registers fn to be called after every tick, in the order registered. The
+//TickState is only valid during the call
+func (sim *SimulatorState)OnTick(fn func(*TickState)) {
+	sim.observers = append(sim.observers, fn)
+}
+
+//tickState is the state after the last tick, which asked for targetAccel
+func (sim *SimulatorState)tickState(targetAccel float64) TickState {
+	return TickState{
+		TelemetrySample: sim.Sample(),
+		TargetAccel: targetAccel,
+		SOC: sim.Vehicle.Battery.StateOfCharge(),
+		Reason: asLimit(sim.lastLimit),
+	}
+}
+
+//notify hands the state after a tick to every observer
+func (sim *SimulatorState)notify(targetAccel float64) {
+	if len(sim.observers) == 0 {
+		return
+	}
+	state := sim.tickState(targetAccel)
+	for _,fn := range sim.observers {
+		fn(&state)
+	}
+}
+
+//Step is Tick for callers running the loop themselves, returning the state after the
+//tick. The error is the limit that held it short of targetAccel, as from Tick
+func (sim *SimulatorState)Step(targetAccel float64) (TickState, error) {
+	_, err := sim.Tick(targetAccel)
+	return sim.tickState(targetAccel), err
+}
+
+//Steps runs the schedule as an iterator over the state after every tick, for use with
+//range. Breaking out of the loop stops the run there. If the run fails the last
+//iteration has the error
+func (sim *SimulatorState)Steps(input *Schedule) func(yield func(TickState, error) bool) {
+	return func(yield func(TickState, error) bool) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopped := false
+		observer := len(sim.observers)
+		sim.OnTick(func(state *TickState) {
+			if !stopped && !yield(*state, nil) {
+				stopped = true
+				cancel()
+			}
+		})
+		defer func() {
+			sim.observers = append(sim.observers[:observer], sim.observers[observer+1:]...)
+		}()
+		_, err := sim.RunContext(ctx, input)
+		if err != nil && !stopped {
+			yield(sim.tickState(0), err)
+		}
+	}
+}
+
+//ResampleByDistance interpolates time-ordered samples onto an evenly spaced distance grid,
+//starting at the first sample's distance and spaced step meters apart.
+//Where the vehicle is stopped the first sample to reach a distance wins
+func ResampleByDistance(samples []TelemetrySample, step float64) []TelemetrySample {
+	if len(samples) == 0 || step <= 0 {
+		return nil
+	}
+	
+	start := samples[0].Distance
+	end := samples[len(samples)-1].Distance
+	result := make([]TelemetrySample, 0, int((end - start)/step) + 1)
+	
+	i := 0
+	for n := 0; ; n++ {
+		d := start + float64(n)*step
+		if d > end {
+			break
+		}
+		//find the first pair of samples that bracket this distance
+		for i < len(samples)-1 && samples[i+1].Distance < d {
+			i++
+		}
+		if i == len(samples)-1 || samples[i].Distance >= d {
+			result = append(result, samples[i])
+			result[len(result)-1].Distance = d
+			continue
+		}
+		result = append(result, interpolateAtDistance(samples[i], samples[i+1], d))
+	}
+	return result
+}
+
+//interpolateAtDistance returns the state at distance d, which lies between samples a and b
+func interpolateAtDistance(a, b TelemetrySample, d float64) TelemetrySample {
+	if b.Distance == a.Distance {
+		return b
+	}
+	frac := (d - a.Distance)/(b.Distance - a.Distance)
+	return TelemetrySample{
+		Time: a.Time + time.Duration(frac * float64(b.Time - a.Time)),
+		Speed: a.Speed + frac * (b.Speed - a.Speed),
+		Accel: a.Accel,
+		Distance: d,
+		Regen: a.Regen,
+		Power: a.Power,
+		Limit: a.Limit,
+	}
+}
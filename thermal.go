@@ -0,0 +1,70 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+)
+
+//Thermal is a lumped model of a component's temperature. Its losses heat it up and the
+//cooling system pulls it back toward ambient. Above DerateTemperature the component is
+//limited from its peak toward its continuous rating, reaching it at MaxTemperature
+type Thermal struct {
+	HeatCapacity float64 //J/K
+	Cooling float64 //W/K, heat rejected per kelvin above ambient
+	DerateTemperature float64 //kelvin
+	MaxTemperature float64 //kelvin
+	InitialTemperature float64 //kelvin, defaults to ambient
+
+	//state
+	temperature float64
+}
+
+func (t *Thermal)Init() error {
+	if t.HeatCapacity <= 0 {
+		return fmt.Errorf("Heat capacity must be positive")
+	}
+	if t.Cooling < 0 {
+		return fmt.Errorf("Cooling must not be negative")
+	}
+	if t.DerateTemperature <= 0 {
+		return fmt.Errorf("Derate temperature must be above absolute zero")
+	}
+	if t.MaxTemperature <= t.DerateTemperature {
+		return fmt.Errorf("Max temperature must be above the derate temperature")
+	}
+	if t.InitialTemperature < 0 {
+		return fmt.Errorf("Initial temperature must not be negative")
+	}
+	t.temperature = t.InitialTemperature
+	return nil
+}
+
+//available is how much of the headroom between continuous and peak rating can be used
+//at the current temperature, from 1 (all of it) down to 0 (continuous only)
+func (t *Thermal)available(sim *SimulatorState) float64 {
+	if t.temperature == 0 {
+		t.temperature = sim.Vehicle.Ambient.Temperature
+	}
+	fraction := (t.MaxTemperature - t.temperature)/(t.MaxTemperature - t.DerateTemperature)
+	return math.Max(0, math.Min(1, fraction))
+}
+
+//heat advances the temperature by one interval with the given losses (watts)
+func (t *Thermal)heat(sim *SimulatorState, losses float64) {
+	if t.temperature == 0 {
+		t.temperature = sim.Vehicle.Ambient.Temperature
+	}
+	cooling := t.Cooling * (t.temperature - sim.Vehicle.Ambient.Temperature)
+	t.temperature += (losses - cooling) * sim.Interval.Seconds() / t.HeatCapacity
+}
+
+//Temperature is the component temperature in kelvin
+func (t *Thermal)Temperature() float64 {
+	return t.temperature
+}
+
+//derated scales between a continuous and peak rating
+func derated(continuous, peak, available float64) float64 {
+	return continuous + available * (peak - continuous)
+}
@@ -0,0 +1,145 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+//percentiles are worked out from at most this many samples per quantity. A longer run
+//keeps every other one and halves how often it samples, so memory stays bounded
+const maxStatSamples = 1 << 16
+
+//TickStatistics summarizes one quantity over every tick of a run. All zero if the
+//quantity wasn't modelled, e.g. a temperature without a thermal model
+type TickStatistics struct {
+	Min float64
+	Max float64
+	Mean float64
+	P50 float64
+	P95 float64
+	P99 float64
+}
+
+//RunStatistics summarizes a run tick by tick, so peaks and percentiles don't need a Trace
+type RunStatistics struct {
+	Speed TickStatistics //m/s
+	BatteryPower TickStatistics //W drawn from the cells, negative while charging
+	BatteryCurrent TickStatistics //A, negative while charging
+	BatteryTemperature TickStatistics //kelvin
+	MotorTemperature TickStatistics //kelvin, the hottest motor
+}
+
+//statAccumulator keeps the exact min, max and mean of a quantity and a decimated set of
+//samples for its percentiles
+type statAccumulator struct {
+	count int
+	sum float64
+	min float64
+	max float64
+	samples []float64
+	stride int //ticks between samples
+	skip int //ticks until the next sample
+}
+
+func (a *statAccumulator)add(x float64) {
+	if a.count == 0 || x < a.min {
+		a.min = x
+	}
+	if a.count == 0 || x > a.max {
+		a.max = x
+	}
+	a.count++
+	a.sum += x
+
+	if a.skip > 0 {
+		a.skip--
+		return
+	}
+	if a.stride == 0 {
+		a.stride = 1
+	}
+	if len(a.samples) == maxStatSamples {
+		for i := 0; i < maxStatSamples/2; i++ {
+			a.samples[i] = a.samples[2*i]
+		}
+		a.samples = a.samples[:maxStatSamples/2]
+		a.stride *= 2
+	}
+	a.samples = append(a.samples, x)
+	a.skip = a.stride - 1
+}
+
+func (a *statAccumulator)summary() TickStatistics {
+	if a.count == 0 {
+		return TickStatistics{}
+	}
+	sorted := Statistics{Samples: append([]float64(nil), a.samples...)}
+	sort.Float64s(sorted.Samples)
+	return TickStatistics{
+		Min: a.min,
+		Max: a.max,
+		Mean: a.sum / float64(a.count),
+		P50: sorted.Percentile(50),
+		P95: sorted.Percentile(95),
+		P99: sorted.Percentile(99),
+	}
+}
+
+//runStats collects RunStatistics and the ScheduleResult profile during a Run
+type runStats struct {
+	speed, power, current, batteryTemperature, motorTemperature statAccumulator
+	start time.Duration //of the run
+	profile []ScheduleSample
+	next time.Duration //into the run the next sample is due
+}
+
+//sample adds to the profile if the run has reached its next sample time
+func (r *runStats)sample(sim *SimulatorState) {
+	elapsed := sim.Time - r.start
+	if elapsed < r.next {
+		return
+	}
+	r.next = (elapsed/scheduleProfileInterval + 1) * scheduleProfileInterval
+	power := sim.Power
+	power.Drives = nil
+	if len(r.profile) == 0 {
+		//nothing has been driven yet
+		power = PowerBreakdown{}
+	}
+	r.profile = append(r.profile, ScheduleSample{Time: elapsed, Speed: sim.Speed, Power: power})
+}
+
+func (r *runStats)add(sim *SimulatorState) {
+	r.sample(sim)
+	r.speed.add(sim.Speed)
+	power := sim.Power.Total()
+	r.power.add(power)
+	if sim.BusVoltage > 0 {
+		r.current.add((power - sim.Power.Battery) / sim.BusVoltage)
+	}
+	v := sim.Vehicle
+	if v.Battery.Thermal != nil {
+		r.batteryTemperature.add(v.Battery.temperature(sim))
+	}
+	hottest := math.Inf(-1)
+	for _,w := range v.Body.Wheelsets {
+		if w.Drive != nil && w.Drive.Motor.Thermal != nil {
+			hottest = math.Max(hottest, w.Drive.Motor.Thermal.Temperature())
+		}
+	}
+	if !math.IsInf(hottest, -1) {
+		r.motorTemperature.add(hottest)
+	}
+}
+
+func (r *runStats)summary() RunStatistics {
+	return RunStatistics{
+		Speed: r.speed.summary(),
+		BatteryPower: r.power.summary(),
+		BatteryCurrent: r.current.summary(),
+		BatteryTemperature: r.batteryTemperature.summary(),
+		MotorTemperature: r.motorTemperature.summary(),
+	}
+}
@@ -3,24 +3,138 @@ package automotiveSim
 
 import (
 	"fmt"
+	"math"
+)
+
+const (
+	//fractional increase in rolling resistance per kPa of underinflation,
+	//roughly 1.4% per psi for passenger car tires
+	defaultPressureSensitivity = 0.002
+	defaultPeakSlip = 0.1
+	defaultSlidingGrip = 0.75
 )
 
 type Tire struct {
     Grip float64
     RollingResistance float64
     Radius float64
+	
+	//optional, rolling resistance rising with speed as RollingResistance +
+	//RollingResistanceSpeed*v + RollingResistanceSpeedSquared*v^2, v in m/s
+	RollingResistanceSpeed float64
+	RollingResistanceSpeedSquared float64
+	
+	//optional, rolling resistance against speed (m/s). Overrides RollingResistance and its
+	//speed terms
+	RollingResistanceCurve Curve
+	
+	//optional, rolling resistance is taken as measured at ReferencePressure (kPa).
+	//running at a lower Pressure raises it by PressureSensitivity per kPa (default 0.002)
+	Pressure float64
+	ReferencePressure float64
+	PressureSensitivity float64
+	
+	//optional, Grip falls by LoadSensitivity for every multiple of ReferenceLoad (N on the
+	//wheelset) the tires carry above it, and rises below it. ReferenceLoad defaults to the
+	//static load
+	LoadSensitivity float64
+	ReferenceLoad float64
+	
+	//optional, the slip curve. Grip is reached at PeakSlip (default 0.1) and falls to
+	//SlidingGrip (fraction of Grip, default 0.75) with the wheel spinning or locked.
+	//Only used to derate grip for traction control's TargetSlip, see SlipGrip
+	PeakSlip float64
+	SlidingGrip float64
 }
 
 func (t *Tire)Init() error {
 	if t.Grip <= 0 {
 		return fmt.Errorf("Tire grip must be positive")
 	}
-	if t.RollingResistance < 0 {
+	if t.RollingResistance < 0 || t.RollingResistanceSpeed < 0 || t.RollingResistanceSpeedSquared < 0 {
 		return fmt.Errorf("Tire rolling resistance must not be negative")
 	}
+	err := t.RollingResistanceCurve.Init()
+	if err != nil {
+		return fmt.Errorf("Tire rolling resistance: %v", err)
+	}
+	for _,p := range t.RollingResistanceCurve {
+		if p.Y < 0 {
+			return fmt.Errorf("Tire rolling resistance must not be negative")
+		}
+	}
 	if t.Radius <= 0 {
 		return fmt.Errorf("Tire radius must be positive")
 	}
+	if t.Pressure < 0 || t.ReferencePressure < 0 {
+		return fmt.Errorf("Tire pressure must not be negative")
+	}
+	if t.PressureSensitivity < 0 {
+		return fmt.Errorf("Tire pressure sensitivity must not be negative")
+	}
+	if t.LoadSensitivity < 0 || t.LoadSensitivity >= 1 {
+		return fmt.Errorf("Tire load sensitivity must be on the range [0,1)")
+	}
+	if t.ReferenceLoad < 0 {
+		return fmt.Errorf("Tire reference load must not be negative")
+	}
+	if t.PeakSlip < 0 || t.PeakSlip >= 1 {
+		return fmt.Errorf("Tire peak slip must be on the range [0,1)")
+	}
+	if t.SlidingGrip < 0 || t.SlidingGrip > 1 {
+		return fmt.Errorf("Tire sliding grip must be on the range [0,1]")
+	}
 	
 	return nil
 }
+
+//Crr is the rolling resistance coefficient at speed (m/s) and the current tire pressure
+func (t *Tire)Crr(speed float64) float64 {
+	crr := t.RollingResistance
+	speed = math.Abs(speed)
+	if len(t.RollingResistanceCurve) != 0 {
+		crr = t.RollingResistanceCurve.At(speed)
+	} else {
+		crr += t.RollingResistanceSpeed*speed + t.RollingResistanceSpeedSquared*speed*speed
+	}
+	if t.Pressure == 0 || t.ReferencePressure == 0 {
+		return crr
+	}
+	sensitivity := t.PressureSensitivity
+	if sensitivity == 0 {
+		sensitivity = defaultPressureSensitivity
+	}
+	underinflation := math.Max(0, t.ReferencePressure - t.Pressure)
+	return crr * (1 + sensitivity * underinflation)
+}
+
+//SlipGrip is the fraction of Grip available at a slip ratio, rising linearly to the peak
+//and falling linearly to SlidingGrip at full slip. Wheel speed isn't simulated, so there
+//is no slip state carried from tick to tick: this is a static derate of the grip a tire
+//held at that slip would have, used by the SlipTarget traction control strategy
+func (t *Tire)SlipGrip(slip float64) float64 {
+	peak, sliding := t.PeakSlip, t.SlidingGrip
+	if peak == 0 {
+		peak = defaultPeakSlip
+	}
+	if sliding == 0 {
+		sliding = defaultSlidingGrip
+	}
+	slip = math.Min(math.Abs(slip), 1)
+	if slip <= peak {
+		return slip / peak
+	}
+	return 1 - (1 - sliding) * (slip - peak) / (1 - peak)
+}
+
+//Mu is the friction coefficient carrying load (N), where staticLoad is the load at rest
+func (t *Tire)Mu(load, staticLoad float64) float64 {
+	reference := t.ReferenceLoad
+	if reference == 0 {
+		reference = staticLoad
+	}
+	if t.LoadSensitivity == 0 || reference <= 0 {
+		return t.Grip
+	}
+	return t.Grip * math.Max(0, 1 - t.LoadSensitivity * (load/reference - 1))
+}
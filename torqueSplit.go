@@ -0,0 +1,154 @@
+package automotiveSim
+
+
+import (
+	"math"
+)
+
+const (
+	efficientSplitSteps = 20 //increments the optimizing split hands out
+)
+
+//TorqueSplit shares the net force asked of the wheelsets between them. total is always
+//within the sum of Fmin and the sum of Fmax, and each wheelset's share must stay within
+//its own Fmin and Fmax, which already include its traction limit
+type TorqueSplit interface {
+	Split(sim *SimulatorState, b *Body, total float64, Fmin, Fmax []float64) []float64
+}
+
+//ProportionalSplit drives every wheelset at the same fraction of its range. The default
+type ProportionalSplit struct{}
+
+func (ProportionalSplit)Split(sim *SimulatorState, b *Body, total float64, Fmin, Fmax []float64) []float64 {
+	totalFmin, totalFmax := 0.0, 0.0
+	for i := range Fmin {
+		totalFmin += Fmin[i]
+		totalFmax += Fmax[i]
+	}
+	throttle := 0.0
+	if totalFmax > totalFmin {
+		throttle = (total - totalFmin)/(totalFmax - totalFmin)
+	}
+	forces := sim.tickBuffers(len(Fmin)).forces
+	for i := range forces {
+		forces[i] = (throttle * (Fmax[i] - Fmin[i])) + Fmin[i]
+	}
+	return forces
+}
+
+//FixedSplit gives each wheelset a fixed share of the force, in Body.Wheelsets order.
+//Whatever a wheelset can't take moves to the others in proportion to their shares
+type FixedSplit struct {
+	Shares []float64
+}
+
+func (s FixedSplit)Split(sim *SimulatorState, b *Body, total float64, Fmin, Fmax []float64) []float64 {
+	buffers := sim.tickBuffers(len(Fmin))
+	forces, fixed := buffers.forces, buffers.fixed
+	for i := range fixed {
+		fixed[i] = false
+	}
+	clamp := func(i int, f float64) float64 {
+		return math.Max(Fmin[i], math.Min(Fmax[i], f))
+	}
+	//wheelsets without a share (undriven ones) carry no drive force
+	for i := range forces {
+		if s.share(i) == 0 {
+			forces[i], fixed[i] = clamp(i, 0), true
+		}
+	}
+	//hand out the rest by share, fixing any wheelset that hits its limit and going again
+	for pass := 0; pass < len(forces); pass++ {
+		remaining, shares := total, 0.0
+		for i := range forces {
+			if fixed[i] {
+				remaining -= forces[i]
+			} else {
+				shares += s.share(i)
+			}
+		}
+		if shares == 0 {
+			break
+		}
+		clamped := false
+		for i := range forces {
+			if fixed[i] {
+				continue
+			}
+			want := remaining * s.share(i)/shares
+			forces[i] = clamp(i, want)
+			if forces[i] != want {
+				fixed[i], clamped = true, true
+			}
+		}
+		if !clamped {
+			break
+		}
+	}
+	//if the shares can't make up the total, anything with room takes the rest
+	remaining := total
+	for _,f := range forces {
+		remaining -= f
+	}
+	for i := range forces {
+		f := clamp(i, forces[i] + remaining)
+		remaining -= f - forces[i]
+		forces[i] = f
+	}
+	return forces
+}
+
+func (s FixedSplit)share(i int) float64 {
+	if i >= len(s.Shares) {
+		return 0
+	}
+	return s.Shares[i]
+}
+
+//EfficientSplit starts every wheelset at zero torque, then hands the force out (or takes
+//it back when braking) a step at a time to whichever wheelset makes the least power for
+//it, so a lightly loaded dual motor car runs mostly on its more efficient axle
+type EfficientSplit struct{}
+
+func (EfficientSplit)Split(sim *SimulatorState, b *Body, total float64, Fmin, Fmax []float64) []float64 {
+	buffers := sim.tickBuffers(len(Fmin))
+	forces, power := buffers.forces, buffers.power
+	remaining := total
+	for i,w := range b.Wheelsets {
+		//the tires' own rolling resistance is what the wheelset gives at zero torque
+		forces[i] = math.Max(Fmin[i], math.Min(Fmax[i], -w.RollingDrag(sim)))
+		power[i], _ = w.CanOperate(sim, forces[i])
+		remaining -= forces[i]
+	}
+	sign := 1.0
+	if remaining < 0 {
+		sign = -1
+	}
+	step := math.Abs(remaining) / efficientSplitSteps
+	//each round either hands out a whole step or fills a wheelset, so this ends
+	for math.Abs(remaining) > 1e-9 {
+		best, bestCost, bestPower, bestAdd := -1, math.Inf(1), 0.0, 0.0
+		for i,w := range b.Wheelsets {
+			room := Fmax[i] - forces[i]
+			if sign < 0 {
+				room = forces[i] - Fmin[i]
+			}
+			add := math.Min(math.Min(step, math.Abs(remaining)), room)
+			if add <= 0 {
+				continue
+			}
+			p, _ := w.CanOperate(sim, forces[i] + sign*add)
+			cost := (p - power[i])/add
+			if cost < bestCost {
+				best, bestCost, bestPower, bestAdd = i, cost, p, add
+			}
+		}
+		if best < 0 {
+			break
+		}
+		forces[best] += sign*bestAdd
+		power[best] = bestPower
+		remaining -= sign*bestAdd
+	}
+	return forces
+}
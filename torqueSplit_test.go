@@ -0,0 +1,32 @@
+package automotiveSim
+
+
+import (
+	"testing"
+)
+
+//dualMotor is the test vehicle with a second, less efficient, copy of its motor driving
+//the front wheels
+func dualMotor(t *testing.T, split TorqueSplit) *Vehicle {
+	v := testVehicle(t)
+	front := *v.Body.Wheelsets[1].Drive
+	front.Motor.Efficiency = 0.85
+	v.Body.Wheelsets[0].Drive = &front
+	v.Body.TorqueSplit = split
+	return v
+}
+
+func TestEfficientSplitCutsCycleEnergy(t *testing.T) {
+	run := func(split TorqueSplit) float64 {
+		result, err := testSimulation(t, dualMotor(t, split)).Run(testCycle())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Energy
+	}
+	proportional, efficient := run(ProportionalSplit{}), run(EfficientSplit{})
+	saving := 1 - efficient/proportional
+	if saving < 0.03 || saving > 0.06 {
+		t.Fatalf("efficient split saved %.2f%% of %.0f J, want about 4%%", saving*100, proportional)
+	}
+}
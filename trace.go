@@ -0,0 +1,109 @@
+package automotiveSim
+
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+//Trace is the tick by tick record of a run. Set SimulatorState.Trace (or AccelOptions.Trace)
+//to collect one
+type Trace []TelemetrySample
+
+//traceRecord is one sample as written out, with time in seconds
+type traceRecord struct {
+	Time float64
+	Speed float64
+	Accel float64
+	Distance float64
+	Limit string
+	Regen []float64
+	Power map[string]float64
+}
+
+//powerKeys is every power entry that appears anywhere in the trace, sorted
+func (t Trace)powerKeys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _,s := range t {
+		for key := range s.Power {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//WriteCSV writes one row per sample with a header. Power entries get a column each,
+//empty where a sample doesn't have them
+func (t Trace)WriteCSV(w io.Writer) error {
+	keys := t.powerKeys()
+	regenCount := 0
+	if len(t) != 0 {
+		regenCount = len(t[0].Regen)
+	}
+
+	header := []string{"Time", "Speed", "Accel", "Distance", "Limit"}
+	for i := 0; i < regenCount; i++ {
+		header = append(header, fmt.Sprintf("Regen %d", i))
+	}
+	for _,key := range keys {
+		header = append(header, "Power " + key)
+	}
+
+	out := csv.NewWriter(w)
+	err := out.Write(header)
+	if err != nil {
+		return err
+	}
+	format := func(x float64) string {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	for _,s := range t {
+		row := []string{format(s.Time.Seconds()), format(s.Speed), format(s.Accel), format(s.Distance), s.Limit}
+		for i := 0; i < regenCount; i++ {
+			row = append(row, format(s.Regen[i]))
+		}
+		for _,key := range keys {
+			val, ok := s.Power[key]
+			if ok {
+				row = append(row, format(val))
+			} else {
+				row = append(row, "")
+			}
+		}
+		err = out.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+//WriteNDJSON writes one JSON object per line per sample
+func (t Trace)WriteNDJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _,s := range t {
+		err := encoder.Encode(traceRecord{
+			Time: s.Time.Seconds(),
+			Speed: s.Speed,
+			Accel: s.Accel,
+			Distance: s.Distance,
+			Limit: s.Limit,
+			Regen: s.Regen,
+			Power: s.Power,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	lapStep = 1.0 //meters between points of the speed profile
+	maxLapTime = time.Hour //gives up on a vehicle that never finishes the lap
+)
+
+//TrackSegment is a straight (Radius 0) or a constant radius corner
+type TrackSegment struct {
+	Length float64 //m along the racing line
+	Radius float64 //m, zero for a straight
+}
+
+type Track struct {
+	Name string
+	Segments []TrackSegment
+	StandingStart bool //start from rest, otherwise a flying lap at the speed it finishes with
+}
+
+func (t *Track)Init() error {
+	if len(t.Segments) == 0 {
+		return fmt.Errorf("%s: track has no segments", t.Name)
+	}
+	for i,s := range t.Segments {
+		if s.Length <= 0 {
+			return fmt.Errorf("%s: segment %d must have positive length", t.Name, i)
+		}
+		if s.Radius < 0 {
+			return fmt.Errorf("%s: segment %d must not have a negative radius", t.Name, i)
+		}
+	}
+	return nil
+}
+
+//Length is the lap distance in meters
+func (t *Track)Length() float64 {
+	total := 0.0
+	for _,s := range t.Segments {
+		total += s.Length
+	}
+	return total
+}
+
+//radii is the corner radius at every lapStep along the track, zero on the straights
+func (t *Track)radii() []float64 {
+	points := int(math.Ceil(t.Length()/lapStep)) + 1
+	radii := make([]float64, points)
+	segment, end := 0, t.Segments[0].Length
+	for i := range radii {
+		for float64(i)*lapStep > end && segment + 1 < len(t.Segments) {
+			segment++
+			end += t.Segments[segment].Length
+		}
+		radii[i] = t.Segments[segment].Radius
+	}
+	return radii
+}
+
+type LapResult struct {
+	Time time.Duration
+	Energy float64 //drawn from the battery, joules
+	FrictionEnergy float64 //dissipated in the friction brakes, joules
+	TopSpeed float64
+	BrakingPoints []float64 //distances (m) where the driver starts braking
+	Speed Curve //target speed (m/s) against distance (m)
+}
+
+//lateralGrip is the vehicle's cornering acceleration limit, m/s^2
+func (b *Body)lateralGrip(sim *SimulatorState) float64 {
+	total := 0.0
+	for _,w := range b.Wheelsets {
+		total += w.Grip(sim)
+	}
+	return total / b.Weight
+}
+
+//longitudinalGrip is what the friction circle leaves for accelerating or braking once
+//cornering at speed around radius has used its share
+func longitudinalGrip(grip, speed, radius float64) float64 {
+	if radius == 0 {
+		return grip
+	}
+	lateral := speed * speed / radius
+	return grip * math.Sqrt(math.Max(0, 1 - (lateral/grip)*(lateral/grip)))
+}
+
+//LapTime drives a copy of the vehicle around the track. The speed profile is found
+//quasi-statically: corner speed is capped by lateral grip, then a forward pass accelerates
+//as hard as the powertrain and friction circle allow and a backward pass brakes for
+//what's ahead. The profile is then driven through the simulator for time and energy
+func (vehicle *Vehicle)LapTime(track *Track) (LapResult, error) {
+	err := track.Init()
+	if err != nil {
+		return LapResult{}, err
+	}
+	v, err := vehicle.Clone()
+	if err != nil {
+		return LapResult{}, err
+	}
+	sim, err := initSimulation(v)
+	if err != nil {
+		return LapResult{}, err
+	}
+
+	radii := track.radii()
+	grip := v.Body.lateralGrip(sim)
+	limit := make([]float64, len(radii))
+	for i,r := range radii {
+		limit[i] = math.Inf(1)
+		if r > 0 {
+			limit[i] = math.Sqrt(grip * r)
+		}
+	}
+
+	speeds := make([]float64, len(radii))
+	braking := make([]bool, len(radii))
+	start := 0.0
+	//a flying lap starts at whatever speed the previous lap ended with
+	passes := 2
+	if track.StandingStart {
+		passes = 1
+	}
+	for pass := 0; pass < passes; pass++ {
+		speeds[0] = math.Min(start, limit[0])
+		for i := 1; i < len(speeds); i++ {
+			prev := speeds[i-1]
+			accel, _ := sim.accelAt(prev, 1000)
+			accel = math.Min(accel, longitudinalGrip(grip, prev, radii[i-1]))
+			speeds[i] = math.Min(limit[i], math.Sqrt(math.Max(0, prev*prev + 2*accel*lapStep)))
+		}
+		for i := len(speeds) - 2; i >= 0; i-- {
+			next := speeds[i+1]
+			sim.Speed = next
+			drag := v.Body.AeroDrag(sim) + v.Body.RollingDrag(sim)
+			decel := math.Min(v.Body.MaxBrakeForce(sim)/v.Body.Mass(), longitudinalGrip(grip, next, radii[i+1]))
+			decel += drag / v.Body.Mass()
+			reachable := math.Sqrt(next*next + 2*decel*lapStep)
+			braking[i] = reachable < speeds[i]
+			speeds[i] = math.Min(speeds[i], reachable)
+		}
+		sim.Speed = 0
+		start = speeds[len(speeds)-1]
+	}
+
+	result := LapResult{Speed: make(Curve, len(speeds))}
+	for i,s := range speeds {
+		result.Speed[i] = CurvePoint{X: float64(i)*lapStep, Y: s}
+		if braking[i] && (i == 0 || !braking[i-1]) {
+			result.BrakingPoints = append(result.BrakingPoints, float64(i)*lapStep)
+		}
+	}
+
+	//drive the profile, aiming for the target speed a tick ahead
+	sim.Speed = speeds[0]
+	length := track.Length()
+	battery := &v.Battery
+	interval := sim.Interval.Seconds()
+	for sim.Distance < length {
+		if sim.Time > maxLapTime {
+			return result, fmt.Errorf("%s: vehicle did not finish the lap", track.Name)
+		}
+		if radius := radii[int(math.Min(sim.Distance, length)/lapStep)]; radius > 0 {
+			sim.LateralAccel = sim.Speed * sim.Speed / radius
+		} else {
+			sim.LateralAccel = 0
+		}
+		ahead := sim.Distance + math.Max(sim.Speed, 0.1)*interval
+		target := result.Speed.At(math.Min(ahead, length))
+		sim.Tick((target - sim.Speed)/interval)
+		result.TopSpeed = math.Max(result.TopSpeed, sim.Speed)
+	}
+	result.Time = sim.Time
+	result.Energy = battery.EnergyUsed()
+	result.FrictionEnergy = sim.FrictionBrakeEnergy
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package automotiveSim
+
+
+import (
+	"fmt"
+)
+
+const (
+	defaultTorqueCut = 0.2
+)
+
+//TractionStrategy is how a traction control system stops wheelspin
+type TractionStrategy int
+
+const (
+	SlipTarget TractionStrategy = iota //holds the driven tires at TargetSlip, a fixed derate of grip by the slip curve
+	TorqueCut //lets the tires reach peak grip, then cuts TorqueCut of the grip on any tick the demand exceeds it
+)
+
+//TractionControl limits the force the driven tires are asked for. It is quasi-static:
+//wheel speed and slip aren't simulated, so each tick the strategy decides from that
+//tick's demand and grip alone, with nothing carried over from wheelspin on the last
+type TractionControl struct {
+	Strategy TractionStrategy
+	TargetSlip float64 //SlipTarget, slip ratio held. Defaults to the tires' PeakSlip
+	TorqueCut float64 //TorqueCut, fraction of the grip given up once the tires spin. Defaults to 0.2
+}
+
+func (tc *TractionControl)Init() error {
+	if tc.Strategy < SlipTarget || tc.Strategy > TorqueCut {
+		return fmt.Errorf("Unknown traction control strategy %d", tc.Strategy)
+	}
+	if tc.TargetSlip < 0 || tc.TargetSlip > 1 {
+		return fmt.Errorf("Traction control target slip must be on the range [0,1]")
+	}
+	if tc.TorqueCut < 0 || tc.TorqueCut >= 1 {
+		return fmt.Errorf("Traction control torque cut must be on the range [0,1)")
+	}
+	if tc.TorqueCut == 0 {
+		tc.TorqueCut = defaultTorqueCut
+	}
+	return nil
+}
+
+//limit is the force (N) the tires put down when the drive asks for force, given the peak
+//traction they have, and whether wheelspin limited it. A nil TractionControl holds the
+//tires at peak grip
+func (tc *TractionControl)limit(tires *Tire, traction, force float64) (float64, bool) {
+	if tc == nil {
+		if force > traction {
+			return traction, true
+		}
+		return force, false
+	}
+	switch tc.Strategy {
+	case TorqueCut:
+		if force > traction {
+			return traction * (1 - tc.TorqueCut), true
+		}
+		return force, false
+	default:
+		held := traction
+		if tc.TargetSlip != 0 {
+			held *= tires.SlipGrip(tc.TargetSlip)
+		}
+		if force > held {
+			return held, true
+		}
+		return force, false
+	}
+}
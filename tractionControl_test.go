@@ -0,0 +1,32 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTractionControlStrategies(t *testing.T) {
+	tires := &Tire{Grip: 1, PeakSlip: 0.1, SlidingGrip: 0.75}
+	tests := []struct {
+		name string
+		tc *TractionControl
+		force float64
+		want float64
+		limited bool
+	}{
+		{"none, within grip", nil, 800, 800, false},
+		{"none, spinning", nil, 1500, 1000, true},
+		{"slip target below peak", &TractionControl{Strategy: SlipTarget, TargetSlip: 0.05}, 1500, 500, true},
+		{"slip target past peak", &TractionControl{Strategy: SlipTarget, TargetSlip: 0.55}, 1500, 875, true},
+		{"slip target at peak", &TractionControl{Strategy: SlipTarget}, 1500, 1000, true},
+		{"torque cut, within grip", &TractionControl{Strategy: TorqueCut, TorqueCut: 0.2}, 900, 900, false},
+		{"torque cut, spinning", &TractionControl{Strategy: TorqueCut, TorqueCut: 0.2}, 1500, 800, true},
+	}
+	for _,test := range tests {
+		got, limited := test.tc.limit(tires, 1000, test.force)
+		if math.Abs(got - test.want) > 1e-9 || limited != test.limited {
+			t.Errorf("%s: got %.1f N (limited %v), want %.1f N (limited %v)", test.name, got, limited, test.want, test.limited)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package automotiveSim
+
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+//common conversions to SI
+const (
+	MilesPerHour = 0.44704 //m/s
+	KilometersPerHour = 1 / 3.6 //m/s
+	Mile = 1609.344 //m
+	Foot = 0.3048 //m
+	Inch = 0.0254 //m
+	Pound = 0.45359237 //kg
+	Horsepower = 745.699872 //W, mechanical
+	MetricHorsepower = 735.49875 //W
+	PoundFoot = 1.3558179483 //Nm
+	KilowattHour = 3.6e6 //J
+	GallonEquivalent = 33.705 * KilowattHour //J, the energy EPA counts as one gallon of gasoline
+	AmpHour = 3600 //C
+	PSI = 6894.757293 //Pa
+	RPM = rpmToRadS //rad/s
+)
+
+//dimension is what a unit measures. A value with a unit is only accepted in a field of
+//the same dimension
+type dimension int
+
+const (
+	fraction dimension = iota + 1
+	length
+	speed
+	acceleration
+	mass
+	force
+	torque
+	power
+	energy
+	charge
+	current
+	voltage
+	resistance
+	temperature
+	pressure
+	area
+	angularSpeed
+)
+
+var dimensionNames = map[dimension]string{
+	fraction: "fraction", length: "length", speed: "speed", acceleration: "acceleration",
+	mass: "mass", force: "force", torque: "torque", power: "power", energy: "energy",
+	charge: "charge", current: "current", voltage: "voltage", resistance: "resistance",
+	temperature: "temperature", pressure: "pressure", area: "area", angularSpeed: "angular speed",
+}
+
+//unit scales a quantity to SI, then adds an offset (only temperatures have one)
+type unit struct {
+	scale float64
+	offset float64
+	dimension dimension
+}
+
+var units = map[string]unit{
+	"m": {1, 0, length}, "km": {1000, 0, length}, "cm": {0.01, 0, length}, "mm": {0.001, 0, length},
+	"mi": {Mile, 0, length}, "mile": {Mile, 0, length}, "miles": {Mile, 0, length},
+	"ft": {Foot, 0, length}, "in": {Inch, 0, length},
+	"m/s": {1, 0, speed}, "km/h": {KilometersPerHour, 0, speed}, "kph": {KilometersPerHour, 0, speed}, "mph": {MilesPerHour, 0, speed},
+	"m/s^2": {1, 0, acceleration},
+	"kg": {1, 0, mass}, "t": {1000, 0, mass}, "lb": {Pound, 0, mass}, "lbs": {Pound, 0, mass},
+	"N": {1, 0, force}, "kN": {1000, 0, force}, "lbf": {Pound * gravity, 0, force},
+	"Nm": {1, 0, torque}, "N*m": {1, 0, torque}, "lb-ft": {PoundFoot, 0, torque}, "lbft": {PoundFoot, 0, torque}, "ft-lb": {PoundFoot, 0, torque},
+	"W": {1, 0, power}, "kW": {1000, 0, power}, "hp": {Horsepower, 0, power}, "PS": {MetricHorsepower, 0, power},
+	"J": {1, 0, energy}, "kJ": {1000, 0, energy}, "Wh": {3600, 0, energy}, "kWh": {KilowattHour, 0, energy},
+	"C": {1, 0, charge}, "Ah": {AmpHour, 0, charge},
+	"A": {1, 0, current}, "V": {1, 0, voltage}, "ohm": {1, 0, resistance}, "mohm": {0.001, 0, resistance},
+	"K": {1, 0, temperature}, "degC": {1, 273.15, temperature}, "degF": {5.0/9, 273.15 - 32*5.0/9, temperature},
+	"Pa": {1, 0, pressure}, "kPa": {1000, 0, pressure}, "bar": {100000, 0, pressure}, "psi": {PSI, 0, pressure},
+	"m^2": {1, 0, area}, "ft^2": {Foot * Foot, 0, area},
+	"rad/s": {1, 0, angularSpeed}, "rpm": {RPM, 0, angularSpeed},
+	"%": {0.01, 0, fraction},
+}
+
+//temperatureUnits are the short forms only taken as temperatures in a temperature field.
+//Anywhere else "C" is coulombs
+var temperatureUnits = map[string]string{"C": "degC", "°C": "degC", "F": "degF", "°F": "degF"}
+
+//fieldUnit is what a vehicle description field measures, and the unit it is stored in
+type fieldUnit struct {
+	dimension dimension
+	unit string //empty for SI
+}
+
+//fieldUnits are the fields that take a value with a unit, by name, or by the name of the
+//object holding them and their own name where a name is used for different things
+var fieldUnits = map[string]fieldUnit{
+	"Accessory": {power, ""}, "HeaterPower": {power, ""}, "MaxRegenPower": {power, ""}, "Power": {power, ""},
+	"MaxPower": {power, ""}, "Load": {power, ""}, "Standby": {power, ""},
+	"Torque": {torque, ""},
+	"Weight": {mass, ""}, "Mass": {mass, ""},
+	"Radius": {length, ""}, "Wheelbase": {length, ""}, "CGHeight": {length, ""}, "Altitude": {length, ""},
+	"CdA": {area, ""}, "SolarArea": {area, ""},
+	"SpeedLimiter": {speed, ""},
+	"LiftOff": {acceleration, ""}, "BrakePedal": {acceleration, ""},
+	"MaxShaftSpeed": {angularSpeed, ""}, "BaseSpeedRPM": {angularSpeed, "rpm"}, "IdleRPM": {angularSpeed, "rpm"},
+	"RedlineRPM": {angularSpeed, "rpm"}, "GeneratorRPM": {angularSpeed, "rpm"},
+	"Coulomb": {charge, ""}, "Cell.Capacity": {charge, "Ah"},
+	"MaxCurrent": {current, ""}, "ContinuousCurrent": {current, ""}, "MaxChargeCurrent": {current, ""},
+	"MaxDischargeCurrent": {current, ""}, "ContinuousDischargeCurrent": {current, ""},
+	"NominalVoltage": {voltage, ""}, "SwitchingVoltage": {voltage, ""},
+	"Resistance": {resistance, ""},
+	"Temperature": {temperature, ""}, "InitialTemperature": {temperature, ""}, "MaxTemperature": {temperature, ""},
+	"DerateTemperature": {temperature, ""}, "HeatPumpCutoff": {temperature, ""}, "Setpoint": {temperature, ""},
+	"Ambient.Pressure": {pressure, ""}, "Tires.Pressure": {pressure, "kPa"}, "ReferencePressure": {pressure, "kPa"},
+	"ReferenceLoad": {force, ""},
+	"Efficiency": {fraction, ""}, "Efficiencies": {fraction, ""}, "ChargerEfficency": {fraction, ""},
+	"GeneratorEfficiency": {fraction, ""}, "InitialSOC": {fraction, ""}, "MinSOC": {fraction, ""}, "MaxSOC": {fraction, ""},
+	"WeightDistribution": {fraction, ""}, "BrakeBias": {fraction, ""}, "CalendarFade": {fraction, ""},
+	"CycleFade": {fraction, ""}, "RoadGrip": {fraction, ""}, "TorqueCut": {fraction, ""}, "TargetSlip": {fraction, ""},
+	"PeakSlip": {fraction, ""}, "SlidingGrip": {fraction, ""}, "CarnotFraction": {fraction, ""},
+}
+
+//fieldUnitOf looks up field, held in the object under parent
+func fieldUnitOf(parent, field string) (fieldUnit, bool) {
+	f, ok := fieldUnits[parent + "." + field]
+	if !ok {
+		f, ok = fieldUnits[field]
+	}
+	return f, ok
+}
+
+//ToSI converts value in the named unit, e.g. "mph" or "lb-ft", to SI. Temperatures are
+//"K", "degC" or "degF"
+func ToSI(value float64, name string) (float64, error) {
+	u, ok := units[name]
+	if !ok {
+		return 0, fmt.Errorf("Unknown unit %q", name)
+	}
+	return value*u.scale + u.offset, nil
+}
+
+//FromSI converts an SI value to the named unit
+func FromSI(value float64, name string) (float64, error) {
+	u, ok := units[name]
+	if !ok {
+		return 0, fmt.Errorf("Unknown unit %q", name)
+	}
+	return (value - u.offset)/u.scale, nil
+}
+
+//quantity matches a number followed by a unit, e.g. "300 hp" or "-5degC"
+var quantity = regexp.MustCompile(`^\s*([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)\s*([A-Za-z%°][A-Za-z0-9/^*%°-]*)\s*$`)
+
+//convertUnits rewrites every field given as a number with a unit, like "4000 lb", as the
+//plain number in the unit the field is stored in. The unit must measure what the field
+//does, so "300 hp" is rejected as a torque. Fields that don't take units, names
+//included, are left alone
+func convertUnits(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	var errs ValidationErrors
+	doc = convertValue(doc, "", "", "", &errs)
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return json.Marshal(doc)
+}
+
+//convertValue converts value, found at path in the object under parent as field
+func convertValue(value interface{}, path, parent, field string, errs *ValidationErrors) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertValue(item, joinPath(path, key), field, key, errs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertValue(item, fmt.Sprintf("%s[%d]", path, i), parent, field, errs)
+		}
+	case string:
+		f, ok := fieldUnitOf(parent, field)
+		if !ok {
+			return v
+		}
+		match := quantity.FindStringSubmatch(v)
+		if match == nil {
+			return v
+		}
+		number, err := convertQuantity(match[1], match[2], f)
+		if err != nil {
+			*errs = append(*errs, &ValidationError{Field: path, Reason: err.Error()})
+			return v
+		}
+		return json.Number(strconv.FormatFloat(number, 'g', -1, 64))
+	}
+	return value
+}
+
+//convertQuantity converts number in the named unit to the unit field f is stored in
+func convertQuantity(number, name string, f fieldUnit) (float64, error) {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+	if alias, ok := temperatureUnits[name]; ok && f.dimension == temperature {
+		name = alias
+	}
+	u, ok := units[name]
+	if !ok {
+		return 0, fmt.Errorf("Unknown unit %q", name)
+	}
+	if u.dimension != f.dimension {
+		return 0, fmt.Errorf("%q is a %s, not a %s", name, dimensionNames[u.dimension], dimensionNames[f.dimension])
+	}
+	value = value*u.scale + u.offset
+	if f.unit != "" {
+		return FromSI(value, f.unit)
+	}
+	return value, nil
+}
+
+//joinPath extends a field path, e.g. Body.Wheelsets[1] and Tires
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
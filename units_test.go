@@ -0,0 +1,79 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+//parseWith parses the test vehicle with old replaced by new in its description
+func parseWith(t *testing.T, old, new string) (*Vehicle, error) {
+	t.Helper()
+	if !strings.Contains(testVehicleJSON, old) {
+		t.Fatalf("test vehicle has no %s", old)
+	}
+	return ParseVehicle([]byte(strings.Replace(testVehicleJSON, old, new, 1)))
+}
+
+func TestUnitsConvertPerField(t *testing.T) {
+	v, err := parseWith(t, `"Weight": 1800`, `"Weight": "4000 lb"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(v.Body.Weight - 4000*Pound) > 1e-9 {
+		t.Fatalf("4000 lb read as %.3f kg", v.Body.Weight)
+	}
+
+	v, err = parseWith(t, `"Temperature": 293`, `"Temperature": "22 C"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(v.Ambient.Temperature - 295.15) > 1e-9 {
+		t.Fatalf("22 C read as %.2f K", v.Ambient.Temperature)
+	}
+
+	v, err = parseWith(t, `"Coulomb": 700000`, `"Coulomb": "700000 C"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Battery.Coulomb != 700000 {
+		t.Fatalf("700000 C read as %.0f C", v.Battery.Coulomb)
+	}
+}
+
+func TestUnitsRejectWrongDimension(t *testing.T) {
+	_, err := parseWith(t, `"Torque": 400`, `"Torque": "300 hp"`)
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("got %v, want one validation error", err)
+	}
+	if errs[0].Field != "Body.Wheelsets[1].Drive.Motor.Peak.Torque" {
+		t.Fatalf("error for %q, want the motor's peak torque", errs[0].Field)
+	}
+}
+
+func TestUnitsLeaveNamesAlone(t *testing.T) {
+	v, err := parseWith(t, `"Name": "M"`, `"Name": "300 hp"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := v.Body.Wheelsets[1].Drive.Motor.Name; name != "300 hp" {
+		t.Fatalf("motor name rewritten to %q", name)
+	}
+}
+
+func TestUnitsInFieldUnit(t *testing.T) {
+	f, _ := fieldUnitOf("Tires", "Pressure")
+	kPa, err := convertQuantity("36", "psi", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(kPa - 36*PSI/1000) > 1e-9 {
+		t.Fatalf("36 psi read as %.2f kPa", kPa)
+	}
+	f, _ = fieldUnitOf("Cell", "Capacity")
+	if ah, _ := convertQuantity("180000", "C", f); math.Abs(ah - 50) > 1e-9 {
+		t.Fatalf("180000 C read as %.2f Ah", ah)
+	}
+}
@@ -1,22 +1,40 @@
 package automotiveSim
 
 
+import (
+	"fmt"
+)
 
+//Vehicle is the specification of a vehicle. The simulation state (charge used, gear,
+//temperatures) lives in components too, but every simulation works on its own Clone,
+//so once initialized a Vehicle is only read. It is then safe to share between
+//goroutines running simulations, as long as nothing modifies it meanwhile
 type Vehicle struct {
     Accessory float64
     Battery Battery
 	Body Body
 	Ambient Ambient
+	Climate *Climate //optional, cabin heating/cooling on top of Accessory
+	LowVoltage *LowVoltage //optional, 12V loads fed through a DC-DC converter, on top of Accessory
+	SpeedLimiter float64 //m/s, optional. An electronic governor that won't let the vehicle drive any faster
 }
 
 
 func (v *Vehicle)Init() error {
+	if v.SpeedLimiter < 0 {
+		return fmt.Errorf("Speed limiter must not be negative")
+	}
 	initFuncs := []func() error {
 		v.Battery.Init,
 		v.Body.Init,
 		v.Ambient.Init,
  	}
-	
+	if v.Climate != nil {
+		initFuncs = append(initFuncs, v.Climate.Init)
+	}
+	if v.LowVoltage != nil {
+		initFuncs = append(initFuncs, v.LowVoltage.Init)
+	}
 	
 	for _,function := range initFuncs {
 		err := function()
@@ -27,3 +45,72 @@ func (v *Vehicle)Init() error {
 	
 	return nil
 }
+
+//Clone returns an initialized vehicle with the same specification but fresh simulation
+//state, sharing nothing a simulation writes to with the original. Curves, maps and ratio
+//slices are read-only and shared
+func (v *Vehicle)Clone() (*Vehicle, error) {
+	c := *v
+	c.Body.Wheelsets = make([]Wheelset, len(v.Body.Wheelsets))
+	for i,w := range v.Body.Wheelsets {
+		if w.Drive != nil {
+			drive := *w.Drive
+			if drive.Motor.Thermal != nil {
+				thermal := *drive.Motor.Thermal
+				drive.Motor.Thermal = &thermal
+			}
+			if drive.Motor.Inverter != nil {
+				inverter := *drive.Motor.Inverter
+				drive.Motor.Inverter = &inverter
+			}
+			if drive.Engine != nil {
+				engine := *drive.Engine
+				drive.Engine = &engine
+			}
+			if drive.Gearbox != nil {
+				gearbox := *drive.Gearbox
+				drive.Gearbox = &gearbox
+			}
+			if drive.Hybrid != nil {
+				hybrid := *drive.Hybrid
+				drive.Hybrid = &hybrid
+			}
+			w.Drive = &drive
+		}
+		c.Body.Wheelsets[i] = w
+	}
+	if v.Battery.Thermal != nil {
+		thermal := *v.Battery.Thermal
+		c.Battery.Thermal = &thermal
+	}
+	if v.Battery.Cell != nil {
+		cell := *v.Battery.Cell
+		c.Battery.Cell = &cell
+	}
+	if v.Battery.Aging != nil {
+		aging := *v.Battery.Aging
+		c.Battery.Aging = &aging
+	}
+	if v.Body.Trailer != nil {
+		trailer := *v.Body.Trailer
+		c.Body.Trailer = &trailer
+	}
+	if v.Body.TractionControl != nil {
+		tc := *v.Body.TractionControl
+		c.Body.TractionControl = &tc
+	}
+	if v.Climate != nil {
+		climate := *v.Climate
+		c.Climate = &climate
+	}
+	if v.LowVoltage != nil {
+		lv := *v.LowVoltage
+		c.LowVoltage = &lv
+	}
+	
+	err := c.Init()
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,25 @@
+package automotiveSim
+
+
+import (
+	"testing"
+)
+
+func TestCloneSharesNoState(t *testing.T) {
+	v := testVehicle(t)
+	v.Battery.Aging = &Aging{CalendarFade: 0.02, CycleFade: 0.0001}
+	v.LowVoltage = &LowVoltage{Load: 200, Efficiency: 0.9}
+	v.Body.TractionControl = &TractionControl{}
+
+	c, err := v.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Battery.Aging == v.Battery.Aging || c.LowVoltage == v.LowVoltage || c.Body.TractionControl == v.Body.TractionControl {
+		t.Fatal("clone shares optional models with the original")
+	}
+	//Init fills in defaults on the clone's copies only
+	if v.Battery.Aging.DoDExponent != 0 || v.Body.TractionControl.TorqueCut != 0 {
+		t.Fatal("cloning wrote defaults into the original")
+	}
+}
@@ -2,108 +2,187 @@ package automotiveSim
 
 
 import (
-	"time"
-	"fmt"
 	"math"
 )
 
 func (w *Wheelset)Fmax(sim *SimulatorState) (float64, error) {
 	maxF := 0.0
-	limit := nil
-	if(w.Drive != nil) {
-		shaftRatio := w.Drive.Gearing/w.Drive.Tires.Radius
+	var limit error
+	if(w.Drive != nil && !w.Drive.Shifting()) {
+		shaftRatio := w.Drive.Ratio()/w.Tires.Radius
 		
 		maxTorque := 0.0
 		//careful not to use := here and redefine limit (and why we define maxTorque above)
-		maxTorque, limit = w.Drive.Motor.MaxTorque(sim, sim.Speed * shaftRatio)
-		maxF += maxTorque * w.Drive.Efficiency * shaftRatio
+		maxTorque, limit = w.Drive.maxTorque(sim, sim.Speed * shaftRatio)
+		shaftSpeed := sim.Speed * shaftRatio
+		maxF += (maxTorque - w.Drive.spinTorque(shaftSpeed)) * w.Drive.EfficiencyAt(sim.Speed) * shaftRatio
+	} else if w.Drive != nil {
+		limit = w.limits.of(LimitShifting, w.Name)
 	} else {
-		limit = fmt.Errorf("Freewheel")
+		limit = w.limits.of(LimitFreewheel, w.Name)
 	}
 	
-	forceOnWheel := w.WeightDistribution * sim.Body.Weight * gravity
-	maxF -= forceOnWheel * w.Drive.Tires.RollingResistance
+	maxF -= w.RollingDrag(sim)
 	
-	tireGrip := forceOnWheel * w.Drive.Tires.Grip
+	traction := w.Traction(sim)
 	
-	if(math.Abs(maxF) > tireGrip) {
-		return math.Copysign(tireGrip, maxF), fmt.Errorf("Tire grip")
+	if w.Drive == nil {
+		if(math.Abs(maxF) > traction) {
+			return math.Copysign(traction, maxF), limit
+		}
+		return maxF, limit
+	}
+	force, limited := sim.Vehicle.Body.TractionControl.limit(&w.Tires, traction, math.Abs(maxF))
+	if limited {
+		return math.Copysign(force, maxF), w.limits.of(LimitTraction, w.Name)
 	}
 	return maxF, limit
 }
 
+//Fmin is the strongest braking force the wheelset can put on the vehicle (a negative number)
 func (w *Wheelset)Fmin(sim *SimulatorState) (float64, error) {
+	minF := 0.0
+	var limit error
+	if(w.Drive != nil && w.Drive.Engine != nil) {
+		limit = w.Drive.Engine.limits.of(LimitEngineRegen, w.Drive.Engine.Name)
+	} else if(w.Drive != nil && !w.Drive.Shifting()) {
+		shaftRatio := w.Drive.Ratio()/w.Tires.Radius
+		
+		maxTorque := 0.0
+		maxTorque, limit = w.Drive.Motor.MaxRegenTorque(sim, sim.Speed * shaftRatio)
+		//drive losses help when braking
+		minF -= (maxTorque + w.Drive.spinTorque(sim.Speed * shaftRatio)) * shaftRatio / w.Drive.EfficiencyAt(sim.Speed)
+	} else if w.Drive != nil {
+		limit = w.limits.of(LimitShifting, w.Name)
+	} else {
+		limit = w.limits.of(LimitFreewheel, w.Name)
+	}
+	
+	minF -= w.RollingDrag(sim)
 	
+	traction := w.Traction(sim)
+	
+	if(math.Abs(minF) > traction) {
+		return math.Copysign(traction, minF), w.limits.of(LimitTireGrip, w.Name)
+	}
+	return minF, limit
 }
 
-func (w *Wheelset)CanOperate(sim *SimulatorState, force float64) (float64, error) {
-
-	//tire grip
-	for _,d := range s.drives {
-		grip := d.drive.Tires.Grip * d.drive.WeightDistribution * s.body.Weight * gravity
-		if splitForce > grip {
-			return 0, fmt.Errorf("Tire grip on %s", d.drive.Name)
-		}
-	}
+//shaftLoad converts the net force this wheelset puts on the vehicle into motor shaft
+//speed and torque, along with the power lost in the gear meshes and to spin losses on the way
+func (w *Wheelset)shaftLoad(sim *SimulatorState, force float64) (shaftSpeed, shaftTorque, meshLoss, spinLoss float64) {
+	efficiency := w.Drive.EfficiencyAt(sim.Speed)
 	
-	//motors need to overcome rolling resistance as well
-	totalForce += s.RollingDrag(sim)
+	//the tires need to overcome their own rolling resistance as well
+	wheelForce := force + w.RollingDrag(sim)
 	
-	splitForce = totalForce / float64(len(s.drives))
-	totalPower := 0.0
-	for _,d := range s.drives {
-		//motor calculations
-		shaftTorque := (splitForce * d.drive.Tires.Radius) / (d.drive.Efficiency * d.drive.Gearing)
-		motorPower, err := d.motor.CanOperate(sim, shaftSpeed, shaftTorque, duration)
-		if err != nil {
-			return 0, fmt.Errorf("%s: %s: %v", d.drive.Name, d.drive.Motor.Name, err)	
-		}
-		totalPower += motorPower
+	shaftSpeed = (sim.Speed / w.Tires.Radius) * w.Drive.Ratio()
+	idealTorque := (wheelForce * w.Tires.Radius) / w.Drive.Ratio()
+	shaftTorque = et(idealTorque, efficiency)
+	meshLoss = math.Abs((shaftTorque - idealTorque) * shaftSpeed)
+	//spin losses are the same loaded or not, taken from the motor when driving and out of regen when braking
+	spin := w.Drive.spinTorque(shaftSpeed)
+	shaftTorque += spin
+	spinLoss = spin * shaftSpeed
+	return
+}
+
+func (w *Wheelset)CanOperate(sim *SimulatorState, force float64) (float64, error) {
+	if w.Drive == nil {
+		return 0, nil
 	}
-	return totalPower, nil
+	if w.Drive.Engine != nil {
+		//burns fuel, not electricity
+		return 0, nil
+	}
+	shaftSpeed, shaftTorque, _, _ := w.shaftLoad(sim, force)
+	h := w.Drive.Hybrid
+	if h != nil && h.Mode == Parallel {
+		engineTorque, _ := h.split(sim, &w.Drive.Motor, shaftSpeed, shaftTorque)
+		shaftTorque -= engineTorque
+	}
+	mech, loss := w.Drive.Motor.powerUse(shaftSpeed, shaftTorque)
+	loss += w.Drive.Motor.inverterLoss(sim, mech + loss)
+	if h != nil && h.Mode == Series {
+		generated, _, _ := h.generate(sim, shaftSpeed * shaftTorque)
+		return mech + loss - generated, nil
+	}
+	return mech + loss, nil
 }
 
 func (w *Wheelset)Operate(sim *SimulatorState, force float64) (float64) {
-	totalForce := s.body.Weight * accel
-	totalForce += s.RollingDrag(sim)
-	totalForce += s.AeroDrag(sim)
-	//TODO optimize torque split between drives
-	splitForce := totalForce / float64(len(s.drives))
-	totalPower := 0.0
-	for _,d := range s.drives {
-		//motor calculations
-		shaftSpeed := (sim.Speed / d.drive.Tires.Radius) * d.drive.Gearing
-		shaftTorque := (splitForce * d.drive.Tires.Radius) / (d.drive.Gearing)
-		if shaftSpeed != 0 {
-			shaftTorque = et(shaftTorque*shaftSpeed, d.drive.Efficiency)/shaftSpeed
+	if w.Drive == nil {
+		return 0
+	}
+	shaftSpeed, shaftTorque, meshLoss, spinLoss := w.shaftLoad(sim, force)
+	p := &w.Drive.power
+	*p = DrivePower{GearFriction: meshLoss, SpinLoss: spinLoss}
+	if w.Drive.Engine != nil {
+		p.Engine, p.Fuel = w.Drive.Engine.Operate(sim, shaftSpeed, shaftTorque)
+		return 0
+	}
+	h := w.Drive.Hybrid
+	if h == nil {
+		p.Mechanical, p.MotorLosses, p.InverterLosses = w.Drive.Motor.Operate(sim, shaftSpeed, shaftTorque)
+		return p.Mechanical + p.MotorLosses + p.InverterLosses
+	}
+	if h.Mode == Parallel {
+		engineTorque, on := h.split(sim, &w.Drive.Motor, shaftSpeed, shaftTorque)
+		if on {
+			p.Engine, p.Fuel = h.Engine.Operate(sim, shaftSpeed, engineTorque)
 		}
-		loss := math.Abs(shaftSpeed * shaftTorque) * (1 - d.drive.Efficiency)
-		motorPower := d.motor.Operate(sim, shaftSpeed, shaftTorque, duration)
-		totalPower += motorPower
-		d.power["Gear friction"] = loss
+		h.setEngine(sim, on)
+		p.Mechanical, p.MotorLosses, p.InverterLosses = w.Drive.Motor.Operate(sim, shaftSpeed, shaftTorque - engineTorque)
+		return p.Mechanical + p.MotorLosses + p.InverterLosses
 	}
-	return totalPower
+	generated, engineSpeed, engineTorque := h.generate(sim, shaftSpeed * shaftTorque)
+	if generated > 0 {
+		p.Engine, p.Fuel = h.Engine.Operate(sim, engineSpeed, engineTorque)
+	}
+	h.setEngine(sim, generated > 0)
+	p.Mechanical, p.MotorLosses, p.InverterLosses = w.Drive.Motor.Operate(sim, shaftSpeed, shaftTorque)
+	return p.Mechanical + p.MotorLosses + p.InverterLosses - generated
 }
 
-func (s *bodyState)RollingDrag(sim *SimulatorState) float64 {
-	total := 0.0
-	for _,d := range s.drives {
-		supportedWeight := d.drive.WeightDistribution * s.body.Weight
-		total += supportedWeight * gravity * d.drive.Tires.RollingResistance
-	}
-	
-	//add the freewheeling resistance
-	for _,f := range s.body.Freewheels {
-		supportedWeight := f.WeightDistribution * s.body.Weight
-		total += supportedWeight * gravity * f.Tires.RollingResistance
+//RegenPower is the mechanical power the wheelset's motor recovered on the last tick
+func (w *Wheelset)RegenPower() float64 {
+	if w.Drive == nil {
+		return 0
 	}
-	
-	return total
+	return math.Max(0, -w.Drive.power.Mechanical)
+}
+
+//staticLoad is the wheelset's share of the body's weight on level ground at rest (N)
+func (w *Wheelset)staticLoad(sim *SimulatorState) float64 {
+	return w.WeightDistribution * sim.Vehicle.Body.Weight * gravity
 }
 
-func (s *bodyState)AeroDrag(sim *SimulatorState) float64 {
-    return 0.5 * s.body.CdA * sim.Speed * sim.Speed * airDensity(sim.Vehicle.Ambient.Temperature, sim.Vehicle.Ambient.Pressure) //aero
+//Load is the normal force on the wheelset's tires (N) as the vehicle is driven: its share
+//of the weight across the slope, plus any weight transfer at the acceleration being tried
+func (w *Wheelset)Load(sim *SimulatorState) float64 {
+	b := &sim.Vehicle.Body
+	load := w.staticLoad(sim) * math.Cos(math.Atan(sim.Grade))
+	if w.transfer == 0 {
+		return load
+	}
+	return math.Max(0, load + w.transfer * b.weightTransfer(sim, sim.accel))
 }
 
+//Grip is the largest force the tires can transmit before they slip, in any direction.
+//Load sensitivity is relative to the load at rest, so a loaded wheelset grips less per newton
+func (w *Wheelset)Grip(sim *SimulatorState) float64 {
+	load := w.Load(sim)
+	return w.Tires.Mu(load, w.staticLoad(sim)) * load * sim.Vehicle.Ambient.RoadGrip
+}
 
+//Traction is the longitudinal force the tires can transmit once cornering has taken
+//its share of the friction circle
+func (w *Wheelset)Traction(sim *SimulatorState) float64 {
+	grip := w.Grip(sim)
+	lateral := w.WeightDistribution * sim.Vehicle.Body.Weight * sim.LateralAccel
+	return math.Sqrt(math.Max(0, grip*grip - lateral*lateral))
+}
 
+func (w *Wheelset)RollingDrag(sim *SimulatorState) float64 {
+	return w.Load(sim) * w.Tires.Crr(sim.Speed)
+}
@@ -0,0 +1,38 @@
+package automotiveSim
+
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGripFollowsDynamicLoad(t *testing.T) {
+	sim := tractionLimited(t)
+	rear := &sim.Body.Wheelsets[1]
+	rear.Tires.LoadSensitivity = 0.2
+
+	sim.accel = 0
+	still, stillGrip := rear.Load(sim), rear.Grip(sim)
+	sim.accel = 3
+	loaded, loadedGrip := rear.Load(sim), rear.Grip(sim)
+	if loaded <= still {
+		t.Fatalf("accelerating left the rear load at %.0f N from %.0f N", loaded, still)
+	}
+	if loadedGrip <= stillGrip {
+		t.Fatalf("more load gave less grip, %.0f N from %.0f N", loadedGrip, stillGrip)
+	}
+	//load sensitivity: the extra load grips less per newton
+	if loadedGrip/loaded >= stillGrip/still {
+		t.Fatalf("friction coefficient %.3f loaded, %.3f at rest", loadedGrip/loaded, stillGrip/still)
+	}
+}
+
+func TestLoadAcrossSlope(t *testing.T) {
+	sim := testSimulation(t, testVehicle(t))
+	front := &sim.Body.Wheelsets[0]
+	flat := front.Load(sim)
+	sim.Grade = 0.3
+	if got, want := front.Load(sim), flat/math.Sqrt(1.09); math.Abs(got - want) > 1e-6 {
+		t.Fatalf("got %.1f N on a 30%% grade, want %.1f N", got, want)
+	}
+}